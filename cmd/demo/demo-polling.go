@@ -30,8 +30,8 @@ func main() {
 	}
 
 	// Create SAP client and service
-	sapClient := sap.NewClient(cfg, logger)
-	maintenanceService := services.NewMaintenanceService(sapClient, logger)
+	sapDestinations := sap.NewRouter(cfg, nil, logger)
+	maintenanceService := services.NewMaintenanceService(sapDestinations, logger)
 
 	// Create a test order first
 	fmt.Println("1. Creating a test order...")
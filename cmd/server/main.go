@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"sap-adaptor/internal/config"
 	"sap-adaptor/internal/handlers"
@@ -23,17 +24,25 @@ import (
 // @BasePath /api/v1
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Setup logger
 	logger := logrus.New()
 	logger.SetLevel(logrus.InfoLevel)
 
-	// Initialize SAP client
-	sapClient := sap.NewClient(cfg.SAP, logger)
+	// Initialize SAP clients, one per destination
+	sapDestinations := sap.NewRouter(cfg.SAP, cfg.SAPDestinations, logger)
 
 	// Initialize services
-	maintenanceService := services.NewMaintenanceService(sapClient, logger)
+	maintenanceService := services.NewMaintenanceService(sapDestinations, logger)
+	maintenanceService.EnableAggregation(cfg.Aggregation)
+	maintenanceService.EnableApprovals(cfg.Approval)
+
+	// Compare the fields sent to SAP with its $metadata; the result is shown on /health
+	go maintenanceService.CheckSAPMetadata(context.Background())
 
 	// Initialize handlers
 	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService, logger)
@@ -49,8 +58,32 @@ func main() {
 	v1 := router.Group("/api/v1")
 	{
 		v1.POST("/maintenance-orders", maintenanceHandler.CreateMaintenanceOrder)
+		v1.GET("/maintenance-orders", maintenanceHandler.ListMaintenanceOrders)
+		v1.DELETE("/maintenance-orders", maintenanceHandler.CancelMaintenanceOrderByEvent)
 		v1.GET("/maintenance-orders/:id", maintenanceHandler.GetMaintenanceOrder)
+		v1.PATCH("/maintenance-orders/:id", maintenanceHandler.UpdateMaintenanceOrder)
+		v1.DELETE("/maintenance-orders/:id", maintenanceHandler.CancelMaintenanceOrder)
+		v1.POST("/maintenance-orders/:id/operations", maintenanceHandler.AddOrderOperation)
+		v1.PATCH("/maintenance-orders/:id/operations/:op", maintenanceHandler.UpdateOrderOperation)
+		v1.DELETE("/maintenance-orders/:id/operations/:op", maintenanceHandler.DeleteOrderOperation)
+		v1.POST("/maintenance-orders/:id/operations/:op/confirmations", maintenanceHandler.CreateOperationConfirmation)
+		v1.DELETE("/maintenance-orders/:id/operations/:op/confirmations/:confirmationId", maintenanceHandler.CancelOperationConfirmation)
+		v1.GET("/maintenance-notifications", maintenanceHandler.ListNotifications)
+		v1.GET("/maintenance-notifications/:id", maintenanceHandler.GetNotification)
+		v1.PATCH("/maintenance-notifications/:id", maintenanceHandler.UpdateNotification)
+		v1.GET("/duplicates", maintenanceHandler.ListDuplicateDecisions)
+		v1.GET("/aggregations/:id", maintenanceHandler.GetAggregatedEvent)
+		v1.GET("/approvals", maintenanceHandler.ListApprovals)
+		v1.GET("/approvals/:id", maintenanceHandler.GetApproval)
+		v1.POST("/approvals/:id/approve", maintenanceHandler.ApproveEvent)
+		v1.POST("/approvals/:id/reject", maintenanceHandler.RejectEvent)
 		v1.POST("/maintenance-done", maintenanceHandler.HandleMaintenanceDone)
+		v1.POST("/rules/evaluate", maintenanceHandler.EvaluateRules)
+		v1.GET("/templates", maintenanceHandler.ListTemplates)
+		v1.POST("/templates", maintenanceHandler.CreateTemplate)
+		v1.GET("/templates/:id", maintenanceHandler.GetTemplate)
+		v1.PUT("/templates/:id", maintenanceHandler.UpdateTemplate)
+		v1.DELETE("/templates/:id", maintenanceHandler.DeleteTemplate)
 	}
 
 	// System routes
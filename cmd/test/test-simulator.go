@@ -82,12 +82,12 @@ func main() {
 	fmt.Println("\n   SAP Adaptor Internal: Converting Digital Twin Event to SAP Format")
 	
 	// Convert to SAP notification request
-	sapNotificationReq := sap.ConvertMaintenanceOrderEventToNotificationRequest(digitalTwinEvent)
+	sapNotificationReq := sap.ConvertMaintenanceOrderEventToNotificationRequest(digitalTwinEvent, sapClient.FaultClassCatalogs(), sapClient.FieldMapping())
 	prettyPrintJSON("SAP Adaptor Internal (Converted NotificationRequest)", sapNotificationReq)
 	
 	// Convert to SAP order request (we'll use a placeholder notification ID for now)
 	placeholderNotificationID := "200000000" // This will be replaced with actual notification ID
-	sapOrderReq := sap.ConvertMaintenanceOrderEventToOrderRequest(digitalTwinEvent, placeholderNotificationID)
+	sapOrderReq := sap.ConvertMaintenanceOrderEventToOrderRequest(digitalTwinEvent, placeholderNotificationID, sapClient.FieldMapping())
 	prettyPrintJSON("SAP Adaptor Internal (Converted OrderRequest)", sapOrderReq)
 	
 	// Test notification creation
@@ -1,14 +1,20 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	SAP    SAPConfig    `mapstructure:"sap"`
-	DigitalTwin DigitalTwinConfig `mapstructure:"digitalTwin"`
+	Server          ServerConfig      `mapstructure:"server"`
+	SAP             SAPConfig         `mapstructure:"sap"`
+	SAPDestinations []SAPConfig       `mapstructure:"sapDestinations"` // Additional SAP systems, routed by plant or company code
+	DigitalTwin     DigitalTwinConfig `mapstructure:"digitalTwin"`
+	Aggregation     AggregationConfig `mapstructure:"aggregation"`
+	Approval        ApprovalConfig    `mapstructure:"approval"`
 }
 
 // ServerConfig holds server configuration
@@ -17,16 +23,240 @@ type ServerConfig struct {
 	Host string `mapstructure:"host"`
 }
 
-// SAPConfig holds SAP connection configuration
+// SAPConfig holds SAP connection configuration. The sap block is the default destination;
+// each entry of sapDestinations is another SAP system with its own routing rules.
 type SAPConfig struct {
-	BaseURL      string `mapstructure:"baseUrl"`
-	Username     string `mapstructure:"username"`
-	Password     string `mapstructure:"password"`
-	ClientID     string `mapstructure:"clientId"`
-	ClientSecret string `mapstructure:"clientSecret"`
-	TokenURL     string `mapstructure:"tokenUrl"`
-	Timeout      int    `mapstructure:"timeout"`
-	SimulatorMode bool  `mapstructure:"simulatorMode"`
+	Name                   string                       `mapstructure:"name"`
+	SAPClient              string                       `mapstructure:"sapClient"`    // SAP logon client (sap-client)
+	SAPLanguage            string                       `mapstructure:"sapLanguage"`  // Logon language (sap-language)
+	ServiceRoot            string                       `mapstructure:"serviceRoot"`  // Gateway path of OData services, e.g. /sap/opu/odata/sap
+	ServicePaths           map[string]string            `mapstructure:"servicePaths"` // Full path per service, overriding serviceRoot
+	ODataVersion           string                       `mapstructure:"odataVersion"` // Protocol of the maintenance order service: v2 (default) or v4
+	Headers                map[string]string            `mapstructure:"headers"`      // Static headers sent with every request
+	QueryParams            []QueryParam                 `mapstructure:"queryParams"`  // Static query parameters sent with every request
+	TLS                    TLSConfig                    `mapstructure:"tls"`
+	ProxyURL               string                       `mapstructure:"proxyUrl"` // HTTP CONNECT proxy, e.g. http://proxy.example.com:3128
+	ProxyUsername          string                       `mapstructure:"proxyUsername"`
+	ProxyPassword          string                       `mapstructure:"proxyPassword"`
+	Plants                 []string                     `mapstructure:"plants"`       // Routing: plants or ranges such as 1000-1999
+	CompanyCodes           []string                     `mapstructure:"companyCodes"` // Routing: company codes
+	BaseURL                string                       `mapstructure:"baseUrl"`
+	Username               string                       `mapstructure:"username"`
+	Password               string                       `mapstructure:"password"`
+	ClientID               string                       `mapstructure:"clientId"`
+	ClientSecret           string                       `mapstructure:"clientSecret"`
+	TokenURL               string                       `mapstructure:"tokenUrl"`
+	Timeout                int                          `mapstructure:"timeout"`
+	SimulatorMode          bool                         `mapstructure:"simulatorMode"`
+	FaultClasses           map[string]FaultClassCatalog `mapstructure:"faultClasses"`
+	ExtensionFields        []ExtensionField             `mapstructure:"extensionFields"`        // Event extensions written to SAP custom fields
+	Priority               PriorityConfig               `mapstructure:"priority"`               // Priority from severity and criticality, SLA dates
+	Rules                  DeterminationRules           `mapstructure:"rules"`                  // Order type, notification type and control key determination
+	Duplicates             DuplicateDetection           `mapstructure:"duplicates"`             // Handling of faults repeated on the same equipment
+	Workflow               WorkflowConfig               `mapstructure:"workflow"`               // Notification and/or order per plant
+	Actions                SAPActions                   `mapstructure:"actions"`                // Names of the actions that withdraw orders and notifications
+	MappingFile            string                       `mapstructure:"mappingFile"`            // Field mapping of events to SAP payloads (reloaded on change)
+	MasterDataCacheTTL     int                          `mapstructure:"masterDataCacheTtl"`     // Seconds
+	ReferenceDataFile      string                       `mapstructure:"referenceDataFile"`      // Simulator master data (work centers, functional locations)
+	SkipMetadataValidation bool                         `mapstructure:"skipMetadataValidation"` // Do not check payloads against SAP $metadata
+}
+
+// QueryParam is a static query parameter. It is a name/value pair rather than a map entry
+// because viper lowercases map keys and SAP query parameter names are case-sensitive.
+type QueryParam struct {
+	Name  string `mapstructure:"name"`
+	Value string `mapstructure:"value"`
+}
+
+// TLSConfig holds the TLS settings of an SAP connection. Certificate files are reloaded
+// when they change on disk.
+type TLSConfig struct {
+	CertFile   string `mapstructure:"certFile"`   // Client certificate (PEM) for mutual TLS
+	KeyFile    string `mapstructure:"keyFile"`    // Client private key (PEM)
+	CAFile     string `mapstructure:"caFile"`     // CA bundle (PEM) trusted instead of the system roots
+	MinVersion string `mapstructure:"minVersion"` // 1.2 (default) or 1.3
+	ServerName string `mapstructure:"serverName"` // Overrides the server name used for SNI and verification
+}
+
+// FaultClassCatalog maps a Digital Twin fault class to SAP catalog code groups.
+// Codes are optional and used when the event does not specify one.
+type FaultClassCatalog struct {
+	ObjectPartCodeGroup string `mapstructure:"objectPartCodeGroup"`
+	ObjectPartCode      string `mapstructure:"objectPartCode"`
+	DamageCodeGroup     string `mapstructure:"damageCodeGroup"`
+	DamageCode          string `mapstructure:"damageCode"`
+	CauseCodeGroup      string `mapstructure:"causeCodeGroup"`
+	CauseCode           string `mapstructure:"causeCode"`
+	ActivityCodeGroup   string `mapstructure:"activityCodeGroup"`
+	ActivityCode        string `mapstructure:"activityCode"`
+}
+
+// ExtensionField maps an entry of the event extensions map to custom fields (YY1_, ZZ1_)
+// of the notification and/or the order. Either field may be empty.
+type ExtensionField struct {
+	Extension         string `mapstructure:"extension"`         // Key in MaintenanceOrderEvent.extensions
+	NotificationField string `mapstructure:"notificationField"` // e.g. YY1_AnomalyScore_NOT
+	OrderField        string `mapstructure:"orderField"`        // e.g. YY1_DigitalTwinRef_ORD
+	Type              string `mapstructure:"type"`              // string (default), decimal or boolean
+}
+
+// PriorityConfig derives the SAP priority from the Digital Twin severity (or risk score) and
+// the ABC criticality of the equipment, and the required dates from SLAs per priority
+type PriorityConfig struct {
+	RiskScoreLevels []RiskScoreLevel `mapstructure:"riskScoreLevels"` // Severity for a risk score; the highest matching minimum wins
+	Matrices        []PriorityMatrix `mapstructure:"matrices"`
+	SLAs            []PrioritySLA    `mapstructure:"slas"`
+}
+
+// RiskScoreLevel maps risk scores from MinScore upwards to a severity
+type RiskScoreLevel struct {
+	MinScore float64 `mapstructure:"minScore"`
+	Severity string  `mapstructure:"severity"`
+}
+
+// PriorityMatrix gives the priority per severity and ABC indicator. Keys are case-insensitive;
+// the indicator "default" applies to equipment without an indicator or one that is not listed.
+type PriorityMatrix struct {
+	Plants []string                     `mapstructure:"plants"` // Plants or ranges; a matrix without plants applies to all other plants
+	Matrix map[string]map[string]string `mapstructure:"matrix"` // severity -> ABC indicator -> priority
+}
+
+// PrioritySLA sets the required start and end of work, in hours from the event, for a priority
+type PrioritySLA struct {
+	Priority         string `mapstructure:"priority"`
+	StartWithinHours int    `mapstructure:"startWithinHours"`
+	EndWithinHours   int    `mapstructure:"endWithinHours"`
+}
+
+// DeterminationRules pick the order type, notification type and operation control key of events
+// that do not specify them. The first matching rule of each list wins.
+type DeterminationRules struct {
+	OrderType        []DeterminationRule `mapstructure:"orderType"`
+	NotificationType []DeterminationRule `mapstructure:"notificationType"`
+	ControlKey       []DeterminationRule `mapstructure:"controlKey"`
+}
+
+// DeterminationRule sets a value when all its conditions match. Conditions left empty match
+// everything; a condition may list alternatives ("1000|2000") and use wildcards ("EXT-*").
+type DeterminationRule struct {
+	Category       string `mapstructure:"category"`
+	Plant          string `mapstructure:"plant"`
+	EquipmentClass string `mapstructure:"equipmentClass"`
+	WorkCenter     string `mapstructure:"workCenter"` // Operation work center, or the main work center for order and notification type
+	Value          string `mapstructure:"value"`
+}
+
+// DuplicateDetection looks for open notifications with the same damage code on the equipment
+// before a notification is created, so a repeated fault does not create another order
+type DuplicateDetection struct {
+	WindowHours int    `mapstructure:"windowHours"` // Notifications created within this window are checked; 0 disables the check
+	Policy      string `mapstructure:"policy"`      // attach (default), note or escalate
+}
+
+// WorkflowConfig selects the SAP objects an event creates: notification and order (full),
+// only a notification from which SAP creates the order, or only an order
+type WorkflowConfig struct {
+	Mode             string          `mapstructure:"mode"`             // full (default), notification-only or order-only
+	Plants           []PlantWorkflow `mapstructure:"plants"`           // Mode per plant; the first match wins
+	OrderWaitMinutes int             `mapstructure:"orderWaitMinutes"` // notification-only: how long to wait for SAP to attach the order (default 60)
+	PollSeconds      int             `mapstructure:"pollSeconds"`      // notification-only: how often the notification is read (default 30)
+}
+
+// SAPActions names the function imports (OData v2) or bound actions (v4) used to withdraw an
+// order and complete its notification. They differ between releases and custom gateway services;
+// unset names fall back to the defaults of the SAP client.
+type SAPActions struct {
+	OrderDeletionFlag    string `mapstructure:"orderDeletionFlag"`    // Sets the deletion flag of a created order
+	OrderLock            string `mapstructure:"orderLock"`            // Locks a released order
+	NotificationComplete string `mapstructure:"notificationComplete"` // Completes a notification
+}
+
+// PlantWorkflow sets the workflow mode of plants
+type PlantWorkflow struct {
+	Plants []string `mapstructure:"plants"` // Plants or ranges such as 1000-1999
+	Mode   string   `mapstructure:"mode"`
+}
+
+// WithDefaults returns the destination with unset settings taken from base
+func (c SAPConfig) WithDefaults(base SAPConfig) SAPConfig {
+	if c.Timeout == 0 {
+		c.Timeout = base.Timeout
+	}
+	if c.SAPClient == "" {
+		c.SAPClient = base.SAPClient
+	}
+	if c.SAPLanguage == "" {
+		c.SAPLanguage = base.SAPLanguage
+	}
+	if c.ServiceRoot == "" {
+		c.ServiceRoot = base.ServiceRoot
+	}
+	if c.ServicePaths == nil {
+		c.ServicePaths = base.ServicePaths
+	}
+	if c.ODataVersion == "" {
+		c.ODataVersion = base.ODataVersion
+	}
+	if c.Headers == nil {
+		c.Headers = base.Headers
+	}
+	if c.QueryParams == nil {
+		c.QueryParams = base.QueryParams
+	}
+	if c.TLS == (TLSConfig{}) {
+		c.TLS = base.TLS
+	}
+	if c.ProxyURL == "" {
+		c.ProxyURL = base.ProxyURL
+		c.ProxyUsername = base.ProxyUsername
+		c.ProxyPassword = base.ProxyPassword
+	}
+	if c.FaultClasses == nil {
+		c.FaultClasses = base.FaultClasses
+	}
+	if c.ExtensionFields == nil {
+		c.ExtensionFields = base.ExtensionFields
+	}
+	if c.Priority.RiskScoreLevels == nil && c.Priority.Matrices == nil && c.Priority.SLAs == nil {
+		c.Priority = base.Priority
+	}
+	if c.Rules.OrderType == nil && c.Rules.NotificationType == nil && c.Rules.ControlKey == nil {
+		c.Rules = base.Rules
+	}
+	if c.Duplicates == (DuplicateDetection{}) {
+		c.Duplicates = base.Duplicates
+	}
+	if c.Workflow.Mode == "" && c.Workflow.Plants == nil && c.Workflow.OrderWaitMinutes == 0 && c.Workflow.PollSeconds == 0 {
+		c.Workflow = base.Workflow
+	}
+	if c.Actions == (SAPActions{}) {
+		c.Actions = base.Actions
+	}
+	if c.MappingFile == "" {
+		c.MappingFile = base.MappingFile
+	}
+	if c.MasterDataCacheTTL == 0 {
+		c.MasterDataCacheTTL = base.MasterDataCacheTTL
+	}
+	if c.ReferenceDataFile == "" {
+		c.ReferenceDataFile = base.ReferenceDataFile
+	}
+	return c
+}
+
+// AggregationConfig merges bursts of events for the same equipment into one order
+type AggregationConfig struct {
+	WindowSeconds     int  `mapstructure:"windowSeconds"`     // Events within this time after the first are merged; 0 disables aggregation
+	SplitByWorkCenter bool `mapstructure:"splitByWorkCenter"` // Create one order per operation work center
+}
+
+// ApprovalConfig parks events for review by a reliability engineer before they are sent to SAP.
+// An event needs approval if any of the rules matches; without rules the gate is off.
+type ApprovalConfig struct {
+	Plants           []string `mapstructure:"plants"`           // Plants or plant ranges whose events need approval
+	Priorities       []string `mapstructure:"priorities"`       // Priorities that need approval, e.g. "1"
+	ShutdownRequired bool     `mapstructure:"shutdownRequired"` // Events that require an equipment shutdown need approval
+	TimeoutHours     int      `mapstructure:"timeoutHours"`     // Pending approvals expire after this time; default 24
 }
 
 // DigitalTwinConfig holds Digital Twin system configuration
@@ -36,14 +266,29 @@ type DigitalTwinConfig struct {
 	Timeout int    `mapstructure:"timeout"`
 }
 
-// Load loads configuration from environment variables and config files
-func Load() *Config {
+// Load loads configuration from config.yaml in the working directory, if present, and
+// environment variables, which take precedence. A config.yaml that cannot be read or parsed is
+// returned as an error rather than ignored.
+func Load() (*Config, error) {
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.host", "0.0.0.0")
+	viper.SetDefault("sap.name", "default")
 	viper.SetDefault("sap.timeout", 30)
 	viper.SetDefault("sap.simulatorMode", true)
+	viper.SetDefault("sap.masterDataCacheTtl", 300)
 	viper.SetDefault("digitalTwin.timeout", 30)
 
+	// Read config.yaml from the working directory if present
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(".")
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("failed to read configuration file: %w", err)
+		}
+	}
+
 	// Set environment variable prefix
 	viper.SetEnvPrefix("SAP_ADAPTOR")
 	viper.AutomaticEnv()
@@ -52,6 +297,18 @@ func Load() *Config {
 	viper.BindEnv("server.port", "SAP_ADAPTOR_SERVER_PORT")
 	viper.BindEnv("server.host", "SAP_ADAPTOR_SERVER_HOST")
 	viper.BindEnv("sap.baseUrl", "SAP_ADAPTOR_SAP_BASE_URL")
+	viper.BindEnv("sap.sapClient", "SAP_ADAPTOR_SAP_CLIENT")
+	viper.BindEnv("sap.sapLanguage", "SAP_ADAPTOR_SAP_LANGUAGE")
+	viper.BindEnv("sap.serviceRoot", "SAP_ADAPTOR_SAP_SERVICE_ROOT")
+	viper.BindEnv("sap.odataVersion", "SAP_ADAPTOR_SAP_ODATA_VERSION")
+	viper.BindEnv("sap.tls.certFile", "SAP_ADAPTOR_SAP_TLS_CERT_FILE")
+	viper.BindEnv("sap.tls.keyFile", "SAP_ADAPTOR_SAP_TLS_KEY_FILE")
+	viper.BindEnv("sap.tls.caFile", "SAP_ADAPTOR_SAP_TLS_CA_FILE")
+	viper.BindEnv("sap.tls.minVersion", "SAP_ADAPTOR_SAP_TLS_MIN_VERSION")
+	viper.BindEnv("sap.tls.serverName", "SAP_ADAPTOR_SAP_TLS_SERVER_NAME")
+	viper.BindEnv("sap.proxyUrl", "SAP_ADAPTOR_SAP_PROXY_URL")
+	viper.BindEnv("sap.proxyUsername", "SAP_ADAPTOR_SAP_PROXY_USERNAME")
+	viper.BindEnv("sap.proxyPassword", "SAP_ADAPTOR_SAP_PROXY_PASSWORD")
 	viper.BindEnv("sap.username", "SAP_ADAPTOR_SAP_USERNAME")
 	viper.BindEnv("sap.password", "SAP_ADAPTOR_SAP_PASSWORD")
 	viper.BindEnv("sap.clientId", "SAP_ADAPTOR_SAP_CLIENT_ID")
@@ -59,14 +316,25 @@ func Load() *Config {
 	viper.BindEnv("sap.tokenUrl", "SAP_ADAPTOR_SAP_TOKEN_URL")
 	viper.BindEnv("sap.timeout", "SAP_ADAPTOR_SAP_TIMEOUT")
 	viper.BindEnv("sap.simulatorMode", "SAP_ADAPTOR_SAP_SIMULATOR_MODE")
+	viper.BindEnv("sap.masterDataCacheTtl", "SAP_ADAPTOR_SAP_MASTER_DATA_CACHE_TTL")
+	viper.BindEnv("sap.referenceDataFile", "SAP_ADAPTOR_SAP_REFERENCE_DATA_FILE")
+	viper.BindEnv("sap.mappingFile", "SAP_ADAPTOR_SAP_MAPPING_FILE")
+	viper.BindEnv("sap.duplicates.windowHours", "SAP_ADAPTOR_SAP_DUPLICATE_WINDOW_HOURS")
+	viper.BindEnv("sap.duplicates.policy", "SAP_ADAPTOR_SAP_DUPLICATE_POLICY")
+	viper.BindEnv("sap.workflow.mode", "SAP_ADAPTOR_SAP_WORKFLOW_MODE")
+	viper.BindEnv("sap.skipMetadataValidation", "SAP_ADAPTOR_SAP_SKIP_METADATA_VALIDATION")
+	viper.BindEnv("aggregation.windowSeconds", "SAP_ADAPTOR_AGGREGATION_WINDOW_SECONDS")
+	viper.BindEnv("aggregation.splitByWorkCenter", "SAP_ADAPTOR_AGGREGATION_SPLIT_BY_WORK_CENTER")
+	viper.BindEnv("approval.shutdownRequired", "SAP_ADAPTOR_APPROVAL_SHUTDOWN_REQUIRED")
+	viper.BindEnv("approval.timeoutHours", "SAP_ADAPTOR_APPROVAL_TIMEOUT_HOURS")
 	viper.BindEnv("digitalTwin.baseUrl", "SAP_ADAPTOR_DIGITAL_TWIN_BASE_URL")
 	viper.BindEnv("digitalTwin.apiKey", "SAP_ADAPTOR_DIGITAL_TWIN_API_KEY")
 	viper.BindEnv("digitalTwin.timeout", "SAP_ADAPTOR_DIGITAL_TWIN_TIMEOUT")
 
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
-		panic("Failed to load configuration: " + err.Error())
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	return &config
+	return &config, nil
 }
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAggregatedEvent handles GET /aggregations/:id
+// @Summary Get Aggregated Event
+// @Description Returns an event buffered for aggregation and, once the buffer is flushed, the order it went into. Outcomes are kept in memory for the last 1000 flushed events.
+// @Tags Aggregations
+// @Produce json
+// @Param id path string true "Aggregation ID"
+// @Success 200 {object} models.AggregatedEvent
+// @Failure 404 {object} models.ErrorResponse
+// @Router /aggregations/{id} [get]
+func (h *MaintenanceHandler) GetAggregatedEvent(c *gin.Context) {
+	aggregated, err := h.maintenanceService.GetAggregatedEvent(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrAggregatedEventNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Aggregated event not found",
+				Code:  "AGGREGATION_NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to read aggregated event",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, aggregated)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListApprovals handles GET /approvals
+// @Summary List Approvals
+// @Description Lists events parked for review before they are sent to SAP, newest first. Pending approvals past their timeout are listed as EXPIRED.
+// @Tags Approvals
+// @Produce json
+// @Param status query string false "PENDING, APPROVED, REJECTED, EXPIRED, FAILED or WITHDRAWN"
+// @Success 200 {array} models.Approval
+// @Router /approvals [get]
+func (h *MaintenanceHandler) ListApprovals(c *gin.Context) {
+	c.JSON(http.StatusOK, h.maintenanceService.ListApprovals(c.Query("status")))
+}
+
+// GetApproval handles GET /approvals/:id
+// @Summary Get Approval
+// @Description Returns a parked event, its decision and the order created after approval
+// @Tags Approvals
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Success 200 {object} models.Approval
+// @Failure 404 {object} models.ErrorResponse
+// @Router /approvals/{id} [get]
+func (h *MaintenanceHandler) GetApproval(c *gin.Context) {
+	approval, err := h.maintenanceService.GetApproval(c.Param("id"))
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// ApproveEvent handles POST /approvals/:id/approve
+// @Summary Approve Event
+// @Description Approves a parked event and sends it to SAP. The approval is returned with the created order; if processing fails it is marked FAILED and the error is returned.
+// @Tags Approvals
+// @Accept json
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Param request body models.ApprovalDecision false "Comment and reviewer"
+// @Success 200 {object} models.Approval
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /approvals/{id}/approve [post]
+func (h *MaintenanceHandler) ApproveEvent(c *gin.Context) {
+	id := c.Param("id")
+	decision, ok := h.bindApprovalDecision(c)
+	if !ok {
+		return
+	}
+
+	approval, err := h.maintenanceService.ApproveEvent(c.Request.Context(), id, decision)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"approvalId": id,
+			"error":      err,
+		}).Error("Failed to approve maintenance order event")
+		if approval != nil {
+			h.respondEventError(c, err)
+			return
+		}
+		h.respondApprovalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// RejectEvent handles POST /approvals/:id/reject
+// @Summary Reject Event
+// @Description Rejects a parked event with a comment; nothing is sent to SAP
+// @Tags Approvals
+// @Accept json
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Param request body models.ApprovalDecision true "Comment and reviewer"
+// @Success 200 {object} models.Approval
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /approvals/{id}/reject [post]
+func (h *MaintenanceHandler) RejectEvent(c *gin.Context) {
+	id := c.Param("id")
+	decision, ok := h.bindApprovalDecision(c)
+	if !ok {
+		return
+	}
+	if decision.Comment == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "A comment is required to reject an event",
+			Code:  "MISSING_COMMENT",
+		})
+		return
+	}
+
+	approval, err := h.maintenanceService.RejectEvent(id, decision)
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// bindApprovalDecision reads the optional decision body
+func (h *MaintenanceHandler) bindApprovalDecision(c *gin.Context) (*models.ApprovalDecision, bool) {
+	var decision models.ApprovalDecision
+	if c.Request.ContentLength == 0 {
+		return &decision, true
+	}
+	if err := c.ShouldBindJSON(&decision); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return nil, false
+	}
+	return &decision, true
+}
+
+// respondApprovalError maps approval queue errors to HTTP responses
+func (h *MaintenanceHandler) respondApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Approval not found",
+			Code:  "APPROVAL_NOT_FOUND",
+		})
+	case errors.Is(err, services.ErrApprovalDecided):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Approval was already decided or has expired",
+			Code:    "APPROVAL_DECIDED",
+			Details: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to process approval",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+	}
+}
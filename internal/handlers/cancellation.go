@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+	"sap-adaptor/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CancelMaintenanceOrder handles DELETE /maintenance-orders/:id
+// @Summary Cancel Maintenance Order
+// @Description Withdraws an order the Digital Twin no longer needs, e.g. after a false positive. An order that is still created (CRTD) gets the deletion flag, a released order without confirmed work is locked. The notification is completed with a no-action reason, monitoring stops and subscribers are notified. Orders with confirmed work or completed orders are refused with 409.
+// @Tags Maintenance Orders
+// @Produce json
+// @Param id path string true "Maintenance Order ID"
+// @Param reason query string false "Reason recorded on the notification"
+// @Success 200 {object} models.OrderCancellation
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders/{id} [delete]
+func (h *MaintenanceHandler) CancelMaintenanceOrder(c *gin.Context) {
+	orderID := c.Param("id")
+
+	cancellation, err := h.maintenanceService.CancelMaintenanceOrder(c.Request.Context(), orderID, c.Query("reason"))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"orderId": orderID,
+			"error":   err,
+		}).Error("Failed to cancel maintenance order")
+		h.respondCancellationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancellation)
+}
+
+// CancelMaintenanceOrderByEvent handles DELETE /maintenance-orders?eventId=
+// @Summary Cancel Maintenance Order by Event
+// @Description Withdraws the order created for a Digital Twin event, like DELETE /maintenance-orders/{id}. An event without an order yet is withdrawn where it waits: out of the aggregation buffer, out of the approval queue, or, in notification-only mode, by completing its notification. Unknown events, and events already rejected, expired or being processed, are answered with 404 EVENT_NOT_FOUND.
+// @Tags Maintenance Orders
+// @Produce json
+// @Param eventId query string true "Digital Twin event ID"
+// @Param reason query string false "Reason recorded on the notification"
+// @Success 200 {object} models.OrderCancellation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders [delete]
+func (h *MaintenanceHandler) CancelMaintenanceOrderByEvent(c *gin.Context) {
+	eventID := c.Query("eventId")
+	if eventID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Event ID is required",
+			Code:  "MISSING_EVENT_ID",
+		})
+		return
+	}
+
+	cancellation, err := h.maintenanceService.CancelMaintenanceOrderByEvent(c.Request.Context(), eventID, c.Query("reason"))
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"eventId": eventID,
+			"error":   err,
+		}).Error("Failed to cancel maintenance order of event")
+		h.respondCancellationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancellation)
+}
+
+// respondCancellationError maps cancellation errors to HTTP responses
+func (h *MaintenanceHandler) respondCancellationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrderNotCancellable):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Maintenance order can no longer be cancelled, work has started or is complete",
+			Code:    "ORDER_NOT_CANCELLABLE",
+			Details: err.Error(),
+		})
+	case errors.Is(err, services.ErrEventNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "Event has no maintenance order and is not buffered, pending approval or awaiting an order",
+			Code:    "EVENT_NOT_FOUND",
+			Details: err.Error(),
+		})
+	case sap.IsNotFound(err):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Maintenance order not found",
+			Code:  "ORDER_NOT_FOUND",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to cancel maintenance order",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDuplicateDecisions handles GET /duplicates
+// @Summary List Duplicate Decisions
+// @Description Returns how events that repeated the fault of an open notification were handled (attached, noted or escalated), newest first
+// @Tags Maintenance Orders
+// @Produce json
+// @Param equipmentId query string false "Equipment ID"
+// @Success 200 {array} models.DuplicateDecision
+// @Router /duplicates [get]
+func (h *MaintenanceHandler) ListDuplicateDecisions(c *gin.Context) {
+	c.JSON(http.StatusOK, h.maintenanceService.ListDuplicateDecisions(c.Query("equipmentId")))
+}
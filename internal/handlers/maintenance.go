@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
 	"sap-adaptor/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -35,7 +37,10 @@ func NewMaintenanceHandler(maintenanceService *services.MaintenanceService, logg
 // @Produce json
 // @Param request body models.MaintenanceOrderEvent true "Maintenance Order Event"
 // @Success 201 {object} models.MaintenanceOrderResponse
+// @Success 200 {object} models.MaintenanceOrderResponse "Fault already reported in an open notification"
+// @Success 202 {object} models.MaintenanceOrderResponse "Notification created, SAP creates the order (notification-only mode), event waiting for approval, or event buffered for aggregation (see /aggregations/{id})"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /maintenance-orders [post]
 func (h *MaintenanceHandler) CreateMaintenanceOrder(c *gin.Context) {
@@ -63,27 +68,171 @@ func (h *MaintenanceHandler) CreateMaintenanceOrder(c *gin.Context) {
 		return
 	}
 
-	// Process the maintenance order event
-	response, err := h.maintenanceService.ProcessMaintenanceOrderEvent(c.Request.Context(), &event)
-	if err != nil {
-		h.logger.WithError(err).Error("Failed to process maintenance order event")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Failed to create maintenance order",
-			Code:    "PROCESSING_ERROR",
+	// Components must reference operations of the same event
+	if err := event.ValidateComponentAssignments(); err != nil {
+		h.logger.WithError(err).Error("Request validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
 			Details: err.Error(),
 		})
 		return
 	}
 
+	// Process the maintenance order event, merged with a burst of events for the equipment if enabled
+	response, err := h.maintenanceService.SubmitMaintenanceOrderEvent(c.Request.Context(), &event)
+	if err != nil {
+		h.respondEventError(c, err)
+		return
+	}
+
 	h.logger.WithFields(logrus.Fields{
 		"orderId":        response.OrderID,
 		"notificationId": response.NotificationID,
 		"status":         response.Status,
 	}).Info("Maintenance order created successfully")
 
+	// A repeated fault was handled on the open notification, nothing was created
+	if response.Duplicate != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Notification-only: SAP creates the order later; parked events wait for approval and
+	// buffered events for the aggregation window
+	if response.Status == services.StatusAwaitingOrder || response.Status == services.StatusPendingApproval || response.Status == services.StatusBuffered {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
 	c.JSON(http.StatusCreated, response)
 }
 
+// respondEventError maps errors of processing a maintenance order event to HTTP responses
+func (h *MaintenanceHandler) respondEventError(c *gin.Context, err error) {
+	var masterDataErr *services.MasterDataError
+	if errors.As(err, &masterDataErr) {
+		h.logger.WithError(err).Warn("Maintenance order event rejected by master data validation")
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "Master data validation failed",
+			Code:    "MASTER_DATA_INVALID",
+			Details: masterDataErr.Errors,
+		})
+		return
+	}
+
+	if errors.Is(err, services.ErrTemplateNotFound) {
+		h.logger.WithError(err).Warn("Maintenance order event references an unknown template")
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "Task list template not found",
+			Code:    "TEMPLATE_NOT_FOUND",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var extensionErr *sap.ExtensionError
+	if errors.As(err, &extensionErr) {
+		h.logger.WithError(err).Warn("Maintenance order event has invalid extensions")
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "Extension values do not match the configured SAP field types",
+			Code:    "EXTENSION_INVALID",
+			Details: extensionErr.Errors,
+		})
+		return
+	}
+
+	var payloadErr *sap.PayloadError
+	if errors.As(err, &payloadErr) {
+		h.logger.WithError(err).Warn("Maintenance order event does not match SAP metadata")
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "Payload does not match SAP metadata",
+			Code:    "SAP_PAYLOAD_INVALID",
+			Details: payloadErr.Errors,
+		})
+		return
+	}
+
+	h.logger.WithError(err).Error("Failed to process maintenance order event")
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:   "Failed to create maintenance order",
+		Code:    "PROCESSING_ERROR",
+		Details: err.Error(),
+	})
+}
+
+// ListMaintenanceOrders handles GET /maintenance-orders
+// @Summary Search Maintenance Orders
+// @Description Lists maintenance orders filtered by equipment, location, plant, status, type, priority and planned dates.
+// @Description Results are paged with an opaque cursor; source=local answers from the adaptor's tracking store.
+// @Tags Maintenance Orders
+// @Produce json
+// @Param equipmentId query string false "Equipment ID"
+// @Param functionalLocation query string false "Functional location"
+// @Param plant query string false "Plant"
+// @Param status query string false "Order status (CRTD, REL, TECO, CLSD)"
+// @Param orderType query string false "Order type (e.g. PM01)"
+// @Param priority query string false "Priority"
+// @Param plannedStartFrom query string false "Planned start lower bound (RFC3339)"
+// @Param plannedStartTo query string false "Planned start upper bound (RFC3339)"
+// @Param plannedEndFrom query string false "Planned end lower bound (RFC3339)"
+// @Param plannedEndTo query string false "Planned end upper bound (RFC3339)"
+// @Param sortBy query string false "Sort key (orderId, plannedStart, plannedEnd, priority, status)"
+// @Param sortOrder query string false "asc or desc"
+// @Param limit query int false "Page size (1-200, default 50)"
+// @Param cursor query string false "Cursor from a previous page"
+// @Param source query string false "sap (default) or local"
+// @Success 200 {object} models.MaintenanceOrderList
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders [get]
+func (h *MaintenanceHandler) ListMaintenanceOrders(c *gin.Context) {
+	var query models.MaintenanceOrderQuery
+
+	// Bind and validate query parameters
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.WithError(err).Error("Failed to bind query parameters")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid query parameters",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.validator.Struct(&query); err != nil {
+		h.logger.WithError(err).Error("Query validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if _, err := query.Offset(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid cursor",
+			Code:    "INVALID_CURSOR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	list, err := h.maintenanceService.ListMaintenanceOrders(c.Request.Context(), &query)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search maintenance orders")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to search maintenance orders",
+			Code:    "RETRIEVAL_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
 // GetMaintenanceOrder handles GET /maintenance-orders/:id
 // @Summary Get Maintenance Order Status
 // @Description Retrieves the current status and details of a maintenance order
@@ -114,7 +263,7 @@ func (h *MaintenanceHandler) GetMaintenanceOrder(c *gin.Context) {
 		}).Error("Failed to get maintenance order status")
 
 		// Check if it's a not found error
-		if err.Error() == "SAP API returned status 404" {
+		if sap.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{
 				Error: "Maintenance order not found",
 				Code:  "ORDER_NOT_FOUND",
@@ -135,6 +284,9 @@ func (h *MaintenanceHandler) GetMaintenanceOrder(c *gin.Context) {
 		"status":  status.Status,
 	}).Info("Maintenance order status retrieved successfully")
 
+	if status.ETag != "" {
+		c.Header("ETag", status.ETag)
+	}
 	c.JSON(http.StatusOK, status)
 }
 
@@ -197,18 +349,281 @@ func (h *MaintenanceHandler) HandleMaintenanceDone(c *gin.Context) {
 	})
 }
 
+// UpdateMaintenanceOrder handles PATCH /maintenance-orders/:id
+// @Summary Update Maintenance Order
+// @Description Applies changed event fields (description, priority, planned window, operations) to an existing SAP order.
+// @Description Send the order ETag in If-Match and the ETag of every changed operation in its etag field; a stale ETag is answered with 409 and the current order, a missing one with 428.
+// @Tags Maintenance Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Maintenance Order ID"
+// @Param If-Match header string true "Order ETag from a previous read or update"
+// @Param request body models.MaintenanceOrderEvent true "Changed Maintenance Order Event fields"
+// @Success 200 {object} models.MaintenanceOrderUpdateResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 428 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders/{id} [patch]
+func (h *MaintenanceHandler) UpdateMaintenanceOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	etag, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var event models.MaintenanceOrderEvent
+
+	// Bind request; only the fields present are applied, so no struct validation here
+	if err := c.ShouldBindJSON(&event); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for _, op := range event.Operations {
+		if op.OperationID == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Validation failed",
+				Code:    "VALIDATION_ERROR",
+				Details: "operationId is required for every operation in an update",
+			})
+			return
+		}
+		if op.ETag == "" {
+			c.JSON(http.StatusPreconditionRequired, models.ErrorResponse{
+				Error:   "The ETag of every changed operation is required",
+				Code:    "PRECONDITION_REQUIRED",
+				Details: "operation " + op.OperationID + " has no etag",
+			})
+			return
+		}
+	}
+
+	response, err := h.maintenanceService.UpdateMaintenanceOrder(c.Request.Context(), orderID, &event, etag)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"orderId": orderID,
+			"error":   err,
+		}).Error("Failed to update maintenance order")
+
+		var conflictErr *sap.ConflictError
+		if errors.As(err, &conflictErr) {
+			h.respondConflict(c, conflictErr)
+			return
+		}
+
+		if sap.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Maintenance order not found",
+				Code:  "ORDER_NOT_FOUND",
+			})
+			return
+		}
+
+		var payloadErr *sap.PayloadError
+		if errors.As(err, &payloadErr) {
+			c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+				Error:   "Payload does not match SAP metadata",
+				Code:    "SAP_PAYLOAD_INVALID",
+				Details: payloadErr.Errors,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to update maintenance order",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", response.ETag)
+	c.JSON(http.StatusOK, response)
+}
+
+// ifMatch returns the If-Match header of a change. Without it the change could overwrite a
+// concurrent SAP edit, so 428 is answered and false returned.
+func (h *MaintenanceHandler) ifMatch(c *gin.Context) (string, bool) {
+	etag := c.GetHeader("If-Match")
+	if etag == "" {
+		c.JSON(http.StatusPreconditionRequired, models.ErrorResponse{
+			Error: "If-Match header with the ETag of the version being changed is required",
+			Code:  "PRECONDITION_REQUIRED",
+		})
+		return "", false
+	}
+	return etag, true
+}
+
+// respondConflict writes a 409 carrying the current SAP version of the order
+func (h *MaintenanceHandler) respondConflict(c *gin.Context, conflictErr *sap.ConflictError) {
+	conflict := models.MaintenanceOrderConflict{CurrentETag: conflictErr.CurrentETag}
+	if conflictErr.Current != nil {
+		conflict.CurrentOrder = sap.ConvertSAPOrderResponseToStatus(conflictErr.Current)
+	}
+	c.Header("ETag", conflictErr.CurrentETag)
+	c.JSON(http.StatusConflict, models.ErrorResponse{
+		Error:   "Maintenance order was modified in SAP",
+		Code:    "CONCURRENT_MODIFICATION",
+		Details: conflict,
+	})
+}
+
+// CreateOperationConfirmation handles POST /maintenance-orders/:id/operations/:op/confirmations
+// @Summary Confirm Operation Work
+// @Description Posts a time confirmation (actual work) for an order operation to SAP
+// @Tags Maintenance Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Maintenance Order ID"
+// @Param op path string true "Operation number (e.g. 0010)"
+// @Param request body models.OperationConfirmationRequest true "Operation Confirmation"
+// @Success 201 {object} models.OperationConfirmationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders/{id}/operations/{op}/confirmations [post]
+func (h *MaintenanceHandler) CreateOperationConfirmation(c *gin.Context) {
+	orderID := c.Param("id")
+	operationID := c.Param("op")
+
+	var req models.OperationConfirmationRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate the request
+	if err := h.validator.Struct(&req); err != nil {
+		h.logger.WithError(err).Error("Request validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	confirmation, err := h.maintenanceService.CreateOperationConfirmation(c.Request.Context(), orderID, operationID, &req)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"orderId":     orderID,
+			"operationId": operationID,
+			"error":       err,
+		}).Error("Failed to create operation confirmation")
+
+		if sap.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Maintenance order operation not found",
+				Code:  "OPERATION_NOT_FOUND",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to create operation confirmation",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"orderId":        confirmation.OrderID,
+		"operationId":    confirmation.OperationID,
+		"confirmationId": confirmation.ConfirmationID,
+	}).Info("Operation confirmation created successfully")
+
+	c.JSON(http.StatusCreated, confirmation)
+}
+
+// CancelOperationConfirmation handles DELETE /maintenance-orders/:id/operations/:op/confirmations/:confirmationId
+// @Summary Cancel Operation Confirmation
+// @Description Reverses a time confirmation previously posted for an order operation. Confirmations of another order or operation are answered with 404.
+// @Tags Maintenance Orders
+// @Produce json
+// @Param id path string true "Maintenance Order ID"
+// @Param op path string true "Operation number (e.g. 0010)"
+// @Param confirmationId path string true "SAP confirmation number"
+// @Param counter query string false "SAP confirmation counter (default 00000001)"
+// @Success 200 {object} models.OperationConfirmationResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders/{id}/operations/{op}/confirmations/{confirmationId} [delete]
+func (h *MaintenanceHandler) CancelOperationConfirmation(c *gin.Context) {
+	orderID := c.Param("id")
+	operationID := c.Param("op")
+	confirmationID := c.Param("confirmationId")
+	counter := c.DefaultQuery("counter", "00000001")
+
+	confirmation, err := h.maintenanceService.CancelOperationConfirmation(c.Request.Context(), orderID, operationID, confirmationID, counter)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"orderId":        orderID,
+			"confirmationId": confirmationID,
+			"error":          err,
+		}).Error("Failed to cancel operation confirmation")
+
+		if errors.Is(err, services.ErrConfirmationNotFound) || sap.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Confirmation not found",
+				Code:  "CONFIRMATION_NOT_FOUND",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to cancel operation confirmation",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"orderId":        orderID,
+		"confirmationId": confirmationID,
+	}).Info("Operation confirmation cancelled successfully")
+
+	c.JSON(http.StatusOK, confirmation)
+}
+
 // HealthCheck handles GET /health
 // @Summary Health Check
-// @Description Check if the service is running
+// @Description Check if the service is running and whether the SAP $metadata matches the fields the adaptor sends
 // @Tags System
 // @Produce json
-// @Success 200 {object} models.SuccessResponse
+// @Success 200 {object} models.HealthResponse
 // @Router /health [get]
 func (h *MaintenanceHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, models.SuccessResponse{
-		Success: true,
-		Message: "SAP Adaptor is running",
-	})
+	response := models.HealthResponse{
+		Success:     true,
+		Message:     "SAP Adaptor is running",
+		SAPMetadata: h.maintenanceService.SAPMetadataStatus(),
+	}
+	for _, status := range response.SAPMetadata {
+		if status.Status == "drift" {
+			response.Message = "SAP Adaptor is running, SAP metadata drift detected"
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetMetrics handles GET /metrics (placeholder for future metrics implementation)
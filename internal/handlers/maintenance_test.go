@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+	"sap-adaptor/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUpdateMaintenanceOrderRequiresCurrentETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := services.NewMaintenanceService(sap.NewRouter(config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60}, nil, logger), logger)
+	handler := NewMaintenanceHandler(service, logger)
+	router := gin.New()
+	router.GET("/maintenance-orders/:id", handler.GetMaintenanceOrder)
+	router.PATCH("/maintenance-orders/:id", handler.UpdateMaintenanceOrder)
+
+	patch := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/maintenance-orders/400000123", strings.NewReader(`{"description":"Replace bearing"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A blind change is refused
+	if rec := patch(""); rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("Expected 428 without If-Match, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/maintenance-orders/400000123", nil))
+	read := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || read == "" {
+		t.Fatalf("Expected the order with an ETag, got %d %q", rec.Code, read)
+	}
+
+	if rec := patch(read); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the change to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The ETag read before the change is now stale
+	rec = patch(read)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 for a stale ETag, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Code    string                          `json:"code"`
+		Details models.MaintenanceOrderConflict `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode the conflict: %v", err)
+	}
+	if response.Code != "CONCURRENT_MODIFICATION" || response.Details.CurrentETag == read || response.Details.CurrentOrder == nil || response.Details.CurrentOrder.OrderID != "400000123" {
+		t.Errorf("Expected the current order with its new ETag, got %+v", response)
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"net/http"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListNotifications handles GET /maintenance-notifications
+// @Summary List Maintenance Notifications
+// @Description Lists the SAP maintenance notifications of an equipment with their status and linked order
+// @Tags Maintenance Notifications
+// @Produce json
+// @Param equipmentId query string true "Equipment ID"
+// @Param plant query string false "Plant"
+// @Param status query string false "OUTSTANDING, POSTPONED, IN_PROCESS, COMPLETED or REJECTED"
+// @Param limit query int false "Page size (1-200, default 50)"
+// @Param cursor query string false "Cursor from a previous page"
+// @Success 200 {object} models.MaintenanceNotificationList
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-notifications [get]
+func (h *MaintenanceHandler) ListNotifications(c *gin.Context) {
+	var query models.MaintenanceNotificationQuery
+
+	// Bind and validate query parameters
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.logger.WithError(err).Error("Failed to bind query parameters")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid query parameters",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.validator.Struct(&query); err != nil {
+		h.logger.WithError(err).Error("Query validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if _, err := query.Offset(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid cursor",
+			Code:    "INVALID_CURSOR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	list, err := h.maintenanceService.ListNotifications(c.Request.Context(), &query)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list maintenance notifications")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to list maintenance notifications",
+			Code:    "RETRIEVAL_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetNotification handles GET /maintenance-notifications/:id
+// @Summary Get Maintenance Notification Status
+// @Description Retrieves a maintenance notification, including whether it was postponed, rejected or converted into an order
+// @Tags Maintenance Notifications
+// @Produce json
+// @Param id path string true "Maintenance Notification ID"
+// @Success 200 {object} models.MaintenanceNotificationStatus
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-notifications/{id} [get]
+func (h *MaintenanceHandler) GetNotification(c *gin.Context) {
+	notificationID := c.Param("id")
+
+	status, err := h.maintenanceService.GetNotificationStatus(c.Request.Context(), notificationID)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"notificationId": notificationID,
+			"error":          err,
+		}).Error("Failed to get maintenance notification")
+
+		if sap.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Maintenance notification not found",
+				Code:  "NOTIFICATION_NOT_FOUND",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve maintenance notification",
+			Code:    "RETRIEVAL_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if status.ETag != "" {
+		c.Header("ETag", status.ETag)
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// UpdateNotification handles PATCH /maintenance-notifications/:id
+// @Summary Update Maintenance Notification
+// @Description Changes description, priority or required dates of a notification. Send the ETag in If-Match.
+// @Tags Maintenance Notifications
+// @Accept json
+// @Produce json
+// @Param id path string true "Maintenance Notification ID"
+// @Param If-Match header string true "Notification ETag"
+// @Param request body models.MaintenanceNotificationUpdate true "Changed notification fields"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 428 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-notifications/{id} [patch]
+func (h *MaintenanceHandler) UpdateNotification(c *gin.Context) {
+	notificationID := c.Param("id")
+	ifMatch, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var update models.MaintenanceNotificationUpdate
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&update); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.validator.Struct(&update); err != nil {
+		h.logger.WithError(err).Error("Request validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	etag, err := h.maintenanceService.UpdateNotification(c.Request.Context(), notificationID, &update, ifMatch)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"notificationId": notificationID,
+			"error":          err,
+		}).Error("Failed to update maintenance notification")
+
+		switch {
+		case sap.IsPreconditionFailed(err):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: "Maintenance notification was modified in SAP",
+				Code:  "CONCURRENT_MODIFICATION",
+			})
+		case sap.IsNotFound(err):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Maintenance notification not found",
+				Code:  "NOTIFICATION_NOT_FOUND",
+			})
+		case sap.IsPayloadInvalid(err):
+			c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+				Error:   "Payload does not match SAP metadata",
+				Code:    "SAP_PAYLOAD_INVALID",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to update maintenance notification",
+				Code:    "PROCESSING_ERROR",
+				Details: err.Error(),
+			})
+		}
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Maintenance notification updated successfully",
+	})
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+	"sap-adaptor/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AddOrderOperation handles POST /maintenance-orders/:id/operations
+// @Summary Add Operation to Maintenance Order
+// @Description Adds an operation to an existing SAP order. Without operationId the next free number (step 10) is used.
+// @Tags Maintenance Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Maintenance Order ID"
+// @Param request body models.MaintenanceOperation true "Maintenance Operation"
+// @Success 201 {object} models.OperationStatus
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders/{id}/operations [post]
+func (h *MaintenanceHandler) AddOrderOperation(c *gin.Context) {
+	orderID := c.Param("id")
+
+	var op models.MaintenanceOperation
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&op); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate the request
+	if err := h.validator.Struct(&op); err != nil {
+		h.logger.WithError(err).Error("Request validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	operation, err := h.maintenanceService.AddOrderOperation(c.Request.Context(), orderID, &op)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"orderId": orderID,
+			"error":   err,
+		}).Error("Failed to add order operation")
+
+		if errors.Is(err, services.ErrOperationExists) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "Operation number already used on this order",
+				Code:    "OPERATION_EXISTS",
+				Details: err.Error(),
+			})
+			return
+		}
+		h.respondOperationError(c, err, "Failed to add order operation")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"orderId":     orderID,
+		"operationId": operation.OperationID,
+	}).Info("Order operation added successfully")
+
+	c.JSON(http.StatusCreated, operation)
+}
+
+// UpdateOrderOperation handles PATCH /maintenance-orders/:id/operations/:op
+// @Summary Update Maintenance Order Operation
+// @Description Changes text, work center or duration of an operation. Send the operation ETag in If-Match.
+// @Tags Maintenance Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Maintenance Order ID"
+// @Param op path string true "Operation number (e.g. 0010)"
+// @Param If-Match header string true "Operation ETag"
+// @Param request body models.MaintenanceOperation true "Changed operation fields"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 428 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders/{id}/operations/{op} [patch]
+func (h *MaintenanceHandler) UpdateOrderOperation(c *gin.Context) {
+	orderID := c.Param("id")
+	operationID := c.Param("op")
+	ifMatch, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var op models.MaintenanceOperation
+
+	// Bind request; only the fields present are applied, so no struct validation here
+	if err := c.ShouldBindJSON(&op); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	etag, err := h.maintenanceService.UpdateOrderOperation(c.Request.Context(), orderID, operationID, &op, ifMatch)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"orderId":     orderID,
+			"operationId": operationID,
+			"error":       err,
+		}).Error("Failed to update order operation")
+		h.respondOperationError(c, err, "Failed to update order operation")
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Operation " + models.NormalizeOperationID(operationID) + " updated successfully",
+	})
+}
+
+// DeleteOrderOperation handles DELETE /maintenance-orders/:id/operations/:op
+// @Summary Delete Maintenance Order Operation
+// @Description Removes an operation from an existing SAP order. Send the operation ETag in If-Match.
+// @Tags Maintenance Orders
+// @Produce json
+// @Param id path string true "Maintenance Order ID"
+// @Param op path string true "Operation number (e.g. 0010)"
+// @Param If-Match header string true "Operation ETag"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 428 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /maintenance-orders/{id}/operations/{op} [delete]
+func (h *MaintenanceHandler) DeleteOrderOperation(c *gin.Context) {
+	orderID := c.Param("id")
+	operationID := c.Param("op")
+	etag, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.maintenanceService.DeleteOrderOperation(c.Request.Context(), orderID, operationID, etag); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"orderId":     orderID,
+			"operationId": operationID,
+			"error":       err,
+		}).Error("Failed to delete order operation")
+		h.respondOperationError(c, err, "Failed to delete order operation")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Operation " + models.NormalizeOperationID(operationID) + " deleted successfully",
+	})
+}
+
+// respondOperationError maps SAP errors of operation endpoints to HTTP responses
+func (h *MaintenanceHandler) respondOperationError(c *gin.Context, err error, message string) {
+	var conflictErr *sap.ConflictError
+	if errors.As(err, &conflictErr) {
+		h.respondConflict(c, conflictErr)
+		return
+	}
+
+	if sap.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Maintenance order operation not found",
+			Code:  "OPERATION_NOT_FOUND",
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:   message,
+		Code:    "PROCESSING_ERROR",
+		Details: err.Error(),
+	})
+}
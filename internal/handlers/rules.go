@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EvaluateRules handles POST /rules/evaluate
+// @Summary Evaluate Determination Rules
+// @Description Dry run: shows the order type, notification type and operation control keys the configured rules determine for an event, after master data enrichment. Nothing is written to SAP.
+// @Tags Rules
+// @Accept json
+// @Produce json
+// @Param request body models.MaintenanceOrderEvent true "Maintenance Order Event"
+// @Success 200 {object} models.RuleEvaluation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /rules/evaluate [post]
+func (h *MaintenanceHandler) EvaluateRules(c *gin.Context) {
+	var event models.MaintenanceOrderEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.validator.Struct(&event); err != nil {
+		h.logger.WithError(err).Error("Request validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	evaluation, err := h.maintenanceService.EvaluateRules(c.Request.Context(), &event)
+	if err != nil {
+		var masterDataErr *services.MasterDataError
+		if errors.As(err, &masterDataErr) {
+			c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+				Error:   "Master data validation failed",
+				Code:    "MASTER_DATA_INVALID",
+				Details: masterDataErr.Errors,
+			})
+			return
+		}
+
+		h.logger.WithError(err).Error("Failed to evaluate determination rules")
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to evaluate determination rules",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTemplates handles GET /templates
+// @Summary List Task List Templates
+// @Description Returns the task list templates applied to events by failure mode and equipment class
+// @Tags Templates
+// @Produce json
+// @Success 200 {array} models.OrderTemplate
+// @Router /templates [get]
+func (h *MaintenanceHandler) ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, h.maintenanceService.ListTemplates())
+}
+
+// GetTemplate handles GET /templates/:id
+// @Summary Get Task List Template
+// @Description Returns a task list template
+// @Tags Templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} models.OrderTemplate
+// @Failure 404 {object} models.ErrorResponse
+// @Router /templates/{id} [get]
+func (h *MaintenanceHandler) GetTemplate(c *gin.Context) {
+	template, err := h.maintenanceService.GetTemplate(c.Param("id"))
+	if err != nil {
+		h.respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// CreateTemplate handles POST /templates
+// @Summary Create Task List Template
+// @Description Adds a template for a failure mode and optionally an equipment class. It holds either operations and components or a reference to an SAP general task list.
+// @Tags Templates
+// @Accept json
+// @Produce json
+// @Param request body models.OrderTemplate true "Task List Template"
+// @Success 201 {object} models.OrderTemplate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /templates [post]
+func (h *MaintenanceHandler) CreateTemplate(c *gin.Context) {
+	template, ok := h.bindTemplate(c)
+	if !ok {
+		return
+	}
+
+	created, err := h.maintenanceService.CreateTemplate(template)
+	if err != nil {
+		h.respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateTemplate handles PUT /templates/:id
+// @Summary Replace Task List Template
+// @Description Replaces a task list template
+// @Tags Templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param request body models.OrderTemplate true "Task List Template"
+// @Success 200 {object} models.OrderTemplate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /templates/{id} [put]
+func (h *MaintenanceHandler) UpdateTemplate(c *gin.Context) {
+	template, ok := h.bindTemplate(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.maintenanceService.UpdateTemplate(c.Param("id"), template)
+	if err != nil {
+		h.respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteTemplate handles DELETE /templates/:id
+// @Summary Delete Task List Template
+// @Description Removes a task list template
+// @Tags Templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /templates/{id} [delete]
+func (h *MaintenanceHandler) DeleteTemplate(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.maintenanceService.DeleteTemplate(id); err != nil {
+		h.respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Template " + id + " deleted successfully",
+	})
+}
+
+// bindTemplate reads and validates a template from the request body
+func (h *MaintenanceHandler) bindTemplate(c *gin.Context) (*models.OrderTemplate, bool) {
+	var template models.OrderTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		h.logger.WithError(err).Error("Failed to bind JSON request")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request format",
+			Code:    "INVALID_REQUEST",
+			Details: err.Error(),
+		})
+		return nil, false
+	}
+
+	err := h.validator.Struct(&template)
+	if err == nil {
+		err = template.Validate()
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Template validation failed")
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
+		return nil, false
+	}
+	return &template, true
+}
+
+// respondTemplateError maps template registry errors to HTTP responses
+func (h *MaintenanceHandler) respondTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Task list template not found",
+			Code:  "TEMPLATE_NOT_FOUND",
+		})
+	case errors.Is(err, services.ErrTemplateExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:   "Task list template already exists",
+			Code:    "TEMPLATE_EXISTS",
+			Details: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to process template",
+			Code:    "PROCESSING_ERROR",
+			Details: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// extensionFieldPrefixes are the prefixes of custom fields created with SAP key user extensibility
+var extensionFieldPrefixes = []string{"YY1_", "ZZ1_"}
+
+// IsExtensionField reports whether an SAP property is a custom field
+func IsExtensionField(name string) bool {
+	for _, prefix := range extensionFieldPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtensionValue is the value of a custom field: a string, a number or a boolean
+type ExtensionValue struct {
+	value interface{}
+}
+
+// NewExtensionValue wraps a string, json.Number, float64 or bool
+func NewExtensionValue(value interface{}) ExtensionValue {
+	if f, ok := value.(float64); ok {
+		value = json.Number(fmt.Sprint(f))
+	}
+	return ExtensionValue{value: value}
+}
+
+// Value returns the string, json.Number or bool held by the extension value
+func (v ExtensionValue) Value() interface{} {
+	return v.value
+}
+
+// String formats the value for SAP string fields
+func (v ExtensionValue) String() string {
+	if v.value == nil {
+		return ""
+	}
+	return fmt.Sprint(v.value)
+}
+
+// MarshalJSON implements json.Marshaler
+func (v ExtensionValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.value)
+}
+
+// UnmarshalJSON accepts strings, numbers and booleans only
+func (v *ExtensionValue) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	switch value.(type) {
+	case string, json.Number, bool:
+		v.value = value
+		return nil
+	default:
+		return fmt.Errorf("extension values must be strings, numbers or booleans, got %s", data)
+	}
+}
+
+// marshalWithExtensions marshals v and adds the custom fields to the JSON object
+func marshalWithExtensions(v interface{}, extensions map[string]interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extensions) == 0 {
+		return data, err
+	}
+	extra, err := json.Marshal(extensions)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(data, []byte("{}")) {
+		return extra, nil
+	}
+	// Join {"a":1} and {"YY1_b":2} into {"a":1,"YY1_b":2}
+	return append(append(data[:len(data)-1], ','), extra[1:]...), nil
+}
+
+// unmarshalExtensions returns the custom fields of a JSON object, or nil if there are none
+func unmarshalExtensions(data []byte) (map[string]interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	var extensions map[string]interface{}
+	for name, raw := range fields {
+		if !IsExtensionField(name) {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if extensions == nil {
+			extensions = make(map[string]interface{})
+		}
+		extensions[name] = value
+	}
+	return extensions, nil
+}
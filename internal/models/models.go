@@ -1,51 +1,348 @@
 package models
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // MaintenanceOrderEvent represents the input from Digital Twin
 type MaintenanceOrderEvent struct {
-	EquipmentID          string                 `json:"equipmentId" validate:"required"`
-	FunctionalLocation   string                 `json:"functionalLocation,omitempty"`
-	Plant                string                 `json:"plant" validate:"required"`
-	Description          string                 `json:"description" validate:"required"`
-	Priority             string                 `json:"priority,omitempty"`
-	MaintenanceOrderType string                 `json:"maintenanceOrderType,omitempty"`
-	PlannedStartTime     *time.Time             `json:"plannedStartTime,omitempty"`
-	PlannedEndTime       *time.Time             `json:"plannedEndTime,omitempty"`
-	Operations           []MaintenanceOperation `json:"operations,omitempty"`
+	EventID              string                    `json:"eventId,omitempty"` // Digital Twin event ID; the order can be withdrawn by it
+	EquipmentID          string                    `json:"equipmentId" validate:"required"`
+	FunctionalLocation   string                    `json:"functionalLocation,omitempty"`
+	Plant                string                    `json:"plant" validate:"required"`
+	CompanyCode          string                    `json:"companyCode,omitempty"` // Used to route the event to an SAP destination
+	Description          string                    `json:"description" validate:"required"`
+	Priority             string                    `json:"priority,omitempty"` // Derived from severity or riskScore when either is set
+	Severity             string                    `json:"severity,omitempty"` // Digital Twin severity, e.g. low, medium, high, critical
+	RiskScore            *float64                  `json:"riskScore,omitempty" validate:"omitempty,gte=0,lte=100"`
+	FailureMode          string                    `json:"failureMode,omitempty"`                                                               // Selects a task list template together with the equipment class
+	TemplateID           string                    `json:"templateId,omitempty"`                                                                // Applies this task list template
+	TaskList             *TaskListReference        `json:"taskList,omitempty"`                                                                  // SAP task list copied into the order instead of operations
+	WorkflowMode         string                    `json:"workflowMode,omitempty" validate:"omitempty,oneof=full notification-only order-only"` // Overrides the mode of the plant
+	ShutdownRequired     bool                      `json:"shutdownRequired,omitempty"`                                                          // The work needs an equipment shutdown; may require approval
+	Category             string                    `json:"category,omitempty"`                                                                  // corrective, preventive, predictive, ...; input to determination rules
+	MaintenanceOrderType string                    `json:"maintenanceOrderType,omitempty"`                                                      // Determined by rules when empty
+	NotificationType     string                    `json:"notificationType,omitempty"`                                                          // Determined by rules when empty
+	PlannedStartTime     *time.Time                `json:"plannedStartTime,omitempty"`
+	PlannedEndTime       *time.Time                `json:"plannedEndTime,omitempty"`
+	Operations           []MaintenanceOperation    `json:"operations,omitempty"`
+	Components           []MaintenanceComponent    `json:"components,omitempty" validate:"omitempty,dive"`
+	PlanningPlant        string                    `json:"planningPlant,omitempty"`  // Filled from equipment master data
+	MainWorkCenter       string                    `json:"mainWorkCenter,omitempty"` // Filled from equipment master data
+	Criticality          string                    `json:"criticality,omitempty"`    // ABC indicator, filled from equipment master data
+	EquipmentClass       string                    `json:"equipmentClass,omitempty"` // Technical object type, filled from equipment master data
+	FaultClass           string                    `json:"faultClass,omitempty"`
+	LongText             string                    `json:"longText,omitempty"`
+	Diagnostics          *FaultDiagnostics         `json:"diagnostics,omitempty"`
+	Items                []NotificationItem        `json:"items,omitempty" validate:"omitempty,dive"`
+	Extensions           map[string]ExtensionValue `json:"extensions,omitempty"` // Written to SAP custom fields per sap.extensionFields
+	MergedEventIDs       []string                  `json:"-"`                    // IDs of the other events aggregated into this one
+}
+
+// FaultDiagnostics carries the Digital Twin analysis behind an event.
+// It is written to the notification long text.
+type FaultDiagnostics struct {
+	AnomalyExplanation string          `json:"anomalyExplanation,omitempty"`
+	SensorReadings     []SensorReading `json:"sensorReadings,omitempty" validate:"omitempty,dive"`
+	SuggestedRootCause string          `json:"suggestedRootCause,omitempty"`
+}
+
+// SensorReading is a sensor value observed when the fault was detected
+type SensorReading struct {
+	Sensor    string     `json:"sensor" validate:"required"`
+	Value     float64    `json:"value"`
+	Unit      string     `json:"unit,omitempty"`
+	Threshold *float64   `json:"threshold,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// NotificationItem is a notification item with catalog codes for the damaged object part.
+// Code groups that are left empty are taken from the fault class mapping.
+type NotificationItem struct {
+	Text                string                     `json:"text,omitempty" validate:"max=40"`
+	FaultClass          string                     `json:"faultClass,omitempty"` // Defaults to the event fault class
+	ObjectPartCodeGroup string                     `json:"objectPartCodeGroup,omitempty"`
+	ObjectPartCode      string                     `json:"objectPartCode,omitempty"`
+	DamageCodeGroup     string                     `json:"damageCodeGroup,omitempty"`
+	DamageCode          string                     `json:"damageCode,omitempty"`
+	Causes              []NotificationCatalogEntry `json:"causes,omitempty" validate:"omitempty,dive"`
+	Activities          []NotificationCatalogEntry `json:"activities,omitempty" validate:"omitempty,dive"`
+}
+
+// NotificationCatalogEntry is a cause or activity of a notification item
+type NotificationCatalogEntry struct {
+	Text      string `json:"text,omitempty" validate:"max=40"`
+	CodeGroup string `json:"codeGroup,omitempty"`
+	Code      string `json:"code,omitempty"`
 }
 
 // MaintenanceOperation represents a single operation within a maintenance order
 type MaintenanceOperation struct {
+	OperationID  string  `json:"operationId,omitempty"`
 	Text         string  `json:"text" validate:"required"`
 	WorkCenter   string  `json:"workCenter,omitempty"`
+	ControlKey   string  `json:"controlKey,omitempty"` // Determined by rules when empty
 	Duration     float64 `json:"duration,omitempty"`
 	DurationUnit string  `json:"durationUnit,omitempty"`
+	ETag         string  `json:"etag,omitempty"` // Operation ETag, required when an existing operation is changed
+}
+
+// MaintenanceComponent represents a spare part required by a maintenance order.
+// Components are reserved in SAP when the order is created.
+type MaintenanceComponent struct {
+	Material        string  `json:"material" validate:"required"`
+	Quantity        float64 `json:"quantity" validate:"gt=0"`
+	Unit            string  `json:"unit,omitempty"`
+	StorageLocation string  `json:"storageLocation,omitempty"`
+	OperationID     string  `json:"operationId,omitempty"` // Defaults to the first operation
+}
+
+// OrderTemplate is a task list template for a failure mode and equipment class. Its operations
+// and components are used for events without operations; instead of operations it can
+// reference an SAP general task list.
+type OrderTemplate struct {
+	ID                   string                 `json:"id"`
+	FailureMode          string                 `json:"failureMode" validate:"required"`
+	EquipmentClass       string                 `json:"equipmentClass,omitempty"` // Empty matches every equipment class
+	Description          string                 `json:"description,omitempty"`
+	MaintenanceOrderType string                 `json:"maintenanceOrderType,omitempty"`
+	Operations           []MaintenanceOperation `json:"operations,omitempty" validate:"omitempty,dive"`
+	Components           []MaintenanceComponent `json:"components,omitempty" validate:"omitempty,dive"`
+	TaskList             *TaskListReference     `json:"taskList,omitempty"`
+	CreatedAt            time.Time              `json:"createdAt"`
+	UpdatedAt            time.Time              `json:"updatedAt"`
+}
+
+// TaskListReference identifies an SAP task list whose operations SAP copies into the order
+type TaskListReference struct {
+	TaskListType         string `json:"taskListType" validate:"required"` // A = general task list
+	TaskListGroup        string `json:"taskListGroup" validate:"required"`
+	TaskListGroupCounter string `json:"taskListGroupCounter" validate:"required"`
+}
+
+// Validate checks that a template has either operations or a task list
+func (t *OrderTemplate) Validate() error {
+	if len(t.Operations) == 0 && t.TaskList == nil {
+		return fmt.Errorf("template needs operations or a task list")
+	}
+	if len(t.Operations) > 0 && t.TaskList != nil {
+		return fmt.Errorf("template cannot have both operations and a task list")
+	}
+	event := MaintenanceOrderEvent{Operations: t.Operations, Components: t.Components}
+	return event.ValidateComponentAssignments()
 }
 
 // MaintenanceOrderResponse represents the response after creating an order
 type MaintenanceOrderResponse struct {
-	OrderID        string    `json:"orderId"`
-	NotificationID string    `json:"notificationId"`
-	Status         string    `json:"status"`
-	Message        string    `json:"message"`
-	CreatedAt      time.Time `json:"createdAt"`
+	OrderID          string             `json:"orderId"`
+	NotificationID   string             `json:"notificationId"`
+	Destination      string             `json:"destination"`
+	Status           string             `json:"status"`
+	Message          string             `json:"message"`
+	CreatedAt        time.Time          `json:"createdAt"`
+	Duplicate        *DuplicateDecision `json:"duplicate,omitempty"`        // Set when the event repeated the fault of an open notification
+	AggregatedEvents int                `json:"aggregatedEvents,omitempty"` // Number of events merged with this one
+	RelatedOrders    []string           `json:"relatedOrders,omitempty"`    // Other orders created from the same burst of events
+	WorkflowMode     string             `json:"workflowMode,omitempty"`     // full, notification-only or order-only
+	ApprovalID       string             `json:"approvalId,omitempty"`       // Set when the event is waiting for approval
+	AggregationID    string             `json:"aggregationId,omitempty"`    // Set when the event is buffered for aggregation
+}
+
+// DuplicateDecision records how an event was handled that repeats the fault of an open
+// notification on the same equipment, instead of creating a new notification and order
+type DuplicateDecision struct {
+	EquipmentID      string    `json:"equipmentId"`
+	FaultCode        string    `json:"faultCode"` // Damage code group and code, e.g. PM-DMG/WEAR
+	Destination      string    `json:"destination"`
+	NotificationID   string    `json:"notificationId"`
+	OrderID          string    `json:"orderId,omitempty"`
+	Policy           string    `json:"policy"` // attach, note or escalate
+	Action           string    `json:"action"` // attached, noted or escalated
+	PreviousPriority string    `json:"previousPriority,omitempty"`
+	Priority         string    `json:"priority,omitempty"`
+	Description      string    `json:"description"` // Description of the repeated event
+	DecidedAt        time.Time `json:"decidedAt"`
+}
+
+// AggregatedEvent is an event buffered for aggregation and, once the buffer is flushed, the order
+// it went into
+type AggregatedEvent struct {
+	ID          string                    `json:"id"`
+	Status      string                    `json:"status"` // BUFFERED, PROCESSED, FAILED or WITHDRAWN
+	EventID     string                    `json:"eventId,omitempty"`
+	EquipmentID string                    `json:"equipmentId"`
+	Plant       string                    `json:"plant"`
+	BufferedAt  time.Time                 `json:"bufferedAt"`
+	FlushAt     time.Time                 `json:"flushAt"`
+	Result      *MaintenanceOrderResponse `json:"result,omitempty"` // Order the event went into
+	Error       string                    `json:"error,omitempty"`  // Why the merged event could not be processed
+}
+
+// Approval is an event parked for review by a reliability engineer before it is sent to SAP
+type Approval struct {
+	ID          string                    `json:"id"`
+	Status      string                    `json:"status"`  // PENDING, APPROVED, REJECTED, EXPIRED, FAILED or WITHDRAWN
+	Reasons     []string                  `json:"reasons"` // Rules that matched the event
+	Event       MaintenanceOrderEvent     `json:"event"`
+	RequestedAt time.Time                 `json:"requestedAt"`
+	ExpiresAt   time.Time                 `json:"expiresAt"`
+	DecidedAt   *time.Time                `json:"decidedAt,omitempty"`
+	DecidedBy   string                    `json:"decidedBy,omitempty"`
+	Comment     string                    `json:"comment,omitempty"`
+	Result      *MaintenanceOrderResponse `json:"result,omitempty"` // Order created after approval
+	Error       string                    `json:"error,omitempty"`  // Why the approved event could not be processed
+}
+
+// ApprovalDecision approves or rejects a parked event
+type ApprovalDecision struct {
+	Comment   string `json:"comment"` // Required to reject
+	DecidedBy string `json:"decidedBy,omitempty"`
+}
+
+// OrderCancellation is the result of withdrawing an order the Digital Twin no longer needs
+type OrderCancellation struct {
+	OrderID        string    `json:"orderId,omitempty"` // Empty when the event was withdrawn before an order existed
+	NotificationID string    `json:"notificationId,omitempty"`
+	EventIDs       []string  `json:"eventIds,omitempty"`
+	AggregationID  string    `json:"aggregationId,omitempty"` // Set for an event withdrawn from the aggregation buffer
+	ApprovalID     string    `json:"approvalId,omitempty"`    // Set for an event withdrawn while waiting for approval
+	Destination    string    `json:"destination,omitempty"`
+	PreviousStatus string    `json:"previousStatus"`
+	Action         string    `json:"action"` // deletion-flag, locked, notification-completed or withdrawn
+	Status         string    `json:"status"` // DLFL, LKD, NOCO or WITHDRAWN
+	Reason         string    `json:"reason"`
+	CancelledAt    time.Time `json:"cancelledAt"`
+}
+
+// MaintenanceOrderUpdateResponse represents the response after updating an order
+type MaintenanceOrderUpdateResponse struct {
+	OrderID           string            `json:"orderId"`
+	ETag              string            `json:"etag"`
+	UpdatedFields     []string          `json:"updatedFields,omitempty"`
+	UpdatedOperations []string          `json:"updatedOperations,omitempty"`
+	OperationETags    map[string]string `json:"operationEtags,omitempty"` // New ETag per changed operation
+	Message           string            `json:"message"`
+	UpdatedAt         time.Time         `json:"updatedAt"`
+}
+
+// MaintenanceOrderConflict is returned when the order was changed in SAP concurrently
+type MaintenanceOrderConflict struct {
+	CurrentETag  string                  `json:"currentEtag"`
+	CurrentOrder *MaintenanceOrderStatus `json:"currentOrder,omitempty"`
+}
+
+// MaintenanceOrderQuery holds the filters, sorting and paging of an order search
+type MaintenanceOrderQuery struct {
+	EquipmentID        string     `form:"equipmentId" json:"equipmentId,omitempty"`
+	FunctionalLocation string     `form:"functionalLocation" json:"functionalLocation,omitempty"`
+	Plant              string     `form:"plant" json:"plant,omitempty"`
+	Status             string     `form:"status" json:"status,omitempty"`
+	OrderType          string     `form:"orderType" json:"orderType,omitempty"`
+	Priority           string     `form:"priority" json:"priority,omitempty"`
+	PlannedStartFrom   *time.Time `form:"plannedStartFrom" time_format:"2006-01-02T15:04:05Z07:00" json:"plannedStartFrom,omitempty"`
+	PlannedStartTo     *time.Time `form:"plannedStartTo" time_format:"2006-01-02T15:04:05Z07:00" json:"plannedStartTo,omitempty"`
+	PlannedEndFrom     *time.Time `form:"plannedEndFrom" time_format:"2006-01-02T15:04:05Z07:00" json:"plannedEndFrom,omitempty"`
+	PlannedEndTo       *time.Time `form:"plannedEndTo" time_format:"2006-01-02T15:04:05Z07:00" json:"plannedEndTo,omitempty"`
+	SortBy             string     `form:"sortBy" json:"sortBy,omitempty" validate:"omitempty,oneof=orderId plannedStart plannedEnd priority status"`
+	SortOrder          string     `form:"sortOrder" json:"sortOrder,omitempty" validate:"omitempty,oneof=asc desc"`
+	Limit              int        `form:"limit" json:"limit,omitempty" validate:"omitempty,min=1,max=200"`
+	Cursor             string     `form:"cursor" json:"cursor,omitempty"`
+	Source             string     `form:"source" json:"source,omitempty" validate:"omitempty,oneof=sap local"`
+}
+
+// MaintenanceOrderList represents one page of an order search
+type MaintenanceOrderList struct {
+	Orders     []MaintenanceOrderStatus `json:"orders"`
+	Count      int                      `json:"count"`
+	NextCursor string                   `json:"nextCursor,omitempty"`
+	Source     string                   `json:"source"`
+}
+
+// TrackedOrder is an order created through the adaptor, kept in the local tracking store
+type TrackedOrder struct {
+	OrderID            string     `json:"orderId"`
+	NotificationID     string     `json:"notificationId"`
+	EventIDs           []string   `json:"eventIds,omitempty"` // Digital Twin events the order was created for
+	Destination        string     `json:"destination"`        // SAP destination the order was created in
+	EquipmentID        string     `json:"equipmentId"`
+	FunctionalLocation string     `json:"functionalLocation,omitempty"`
+	Plant              string     `json:"plant"`
+	Description        string     `json:"description"`
+	Priority           string     `json:"priority,omitempty"`
+	OrderType          string     `json:"orderType,omitempty"`
+	Status             string     `json:"status"`
+	PlannedStartTime   *time.Time `json:"plannedStartTime,omitempty"`
+	PlannedEndTime     *time.Time `json:"plannedEndTime,omitempty"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
 }
 
 // MaintenanceOrderStatus represents the current status of a maintenance order
 type MaintenanceOrderStatus struct {
-	OrderID         string            `json:"orderId"`
-	Status          string            `json:"status"`
-	Description     string            `json:"description"`
-	EquipmentID     string            `json:"equipmentId"`
-	Plant           string            `json:"plant"`
-	NotificationID  string            `json:"notificationId"`
-	ActualStartTime *time.Time        `json:"actualStartTime,omitempty"`
-	ActualEndTime   *time.Time        `json:"actualEndTime,omitempty"`
-	Operations      []OperationStatus `json:"operations,omitempty"`
+	OrderID            string                    `json:"orderId"`
+	Status             string                    `json:"status"`
+	Description        string                    `json:"description"`
+	EquipmentID        string                    `json:"equipmentId"`
+	FunctionalLocation string                    `json:"functionalLocation,omitempty"`
+	Plant              string                    `json:"plant"`
+	Priority           string                    `json:"priority,omitempty"`
+	NotificationID     string                    `json:"notificationId"`
+	Destination        string                    `json:"destination,omitempty"`
+	ActualStartTime    *time.Time                `json:"actualStartTime,omitempty"`
+	ActualEndTime      *time.Time                `json:"actualEndTime,omitempty"`
+	Operations         []OperationStatus         `json:"operations,omitempty"`
+	Components         []ComponentStatus         `json:"components,omitempty"`
+	Extensions         map[string]ExtensionValue `json:"extensions,omitempty"` // SAP custom fields of the order
+	ETag               string                    `json:"etag,omitempty"`       // Send as If-Match to change the order
+}
+
+// MaintenanceNotificationStatus represents the current state of a maintenance notification,
+// including whether a planner postponed, rejected or converted it into an order
+type MaintenanceNotificationStatus struct {
+	NotificationID     string     `json:"notificationId"`
+	NotificationType   string     `json:"notificationType"`
+	Status             string     `json:"status"` // OUTSTANDING, POSTPONED, IN_PROCESS, COMPLETED, REJECTED
+	Description        string     `json:"description"`
+	EquipmentID        string     `json:"equipmentId"`
+	FunctionalLocation string     `json:"functionalLocation,omitempty"`
+	Plant              string     `json:"plant"`
+	Priority           string     `json:"priority,omitempty"`
+	OrderID            string     `json:"orderId,omitempty"`
+	Destination        string     `json:"destination,omitempty"`
+	RequiredStartTime  *time.Time `json:"requiredStartTime,omitempty"`
+	RequiredEndTime    *time.Time `json:"requiredEndTime,omitempty"`
+	CreatedAt          *time.Time `json:"createdAt,omitempty"`
+	CompletedAt        *time.Time `json:"completedAt,omitempty"`
+	ETag               string     `json:"etag,omitempty"` // Send as If-Match to change the notification
+}
+
+// MaintenanceNotificationUpdate holds the notification fields that can be changed
+type MaintenanceNotificationUpdate struct {
+	Description       string     `json:"description,omitempty" validate:"omitempty,max=40"`
+	Priority          string     `json:"priority,omitempty"`
+	RequiredStartTime *time.Time `json:"requiredStartTime,omitempty"`
+	RequiredEndTime   *time.Time `json:"requiredEndTime,omitempty"`
+}
+
+// MaintenanceNotificationQuery holds the filters and paging of a notification listing
+type MaintenanceNotificationQuery struct {
+	EquipmentID string `form:"equipmentId" json:"equipmentId" validate:"required"`
+	Plant       string `form:"plant" json:"plant,omitempty"`
+	Status      string `form:"status" json:"status,omitempty" validate:"omitempty,oneof=OUTSTANDING POSTPONED IN_PROCESS COMPLETED REJECTED"`
+	Limit       int    `form:"limit" json:"limit,omitempty" validate:"omitempty,min=1,max=200"`
+	Cursor      string `form:"cursor" json:"cursor,omitempty"`
+}
+
+// MaintenanceNotificationList represents one page of a notification listing
+type MaintenanceNotificationList struct {
+	Notifications []MaintenanceNotificationStatus `json:"notifications"`
+	Count         int                             `json:"count"`
+	NextCursor    string                          `json:"nextCursor,omitempty"`
 }
 
 // OperationStatus represents the status of a specific operation
@@ -53,8 +350,24 @@ type OperationStatus struct {
 	OperationID        string  `json:"operationId"`
 	Text               string  `json:"text"`
 	Status             string  `json:"status"`
+	WorkCenter         string  `json:"workCenter,omitempty"`
+	ControlKey         string  `json:"controlKey,omitempty"`
 	ActualWorkQuantity float64 `json:"actualWorkQuantity,omitempty"`
 	WorkQuantityUnit   string  `json:"workQuantityUnit,omitempty"`
+	ETag               string  `json:"etag,omitempty"` // Send as If-Match to change the operation
+}
+
+// ComponentStatus represents the reservation and withdrawal status of an order component
+type ComponentStatus struct {
+	ComponentID       string  `json:"componentId"`
+	OperationID       string  `json:"operationId"`
+	Material          string  `json:"material"`
+	RequiredQuantity  float64 `json:"requiredQuantity"`
+	WithdrawnQuantity float64 `json:"withdrawnQuantity"`
+	Unit              string  `json:"unit,omitempty"`
+	StorageLocation   string  `json:"storageLocation,omitempty"`
+	Reservation       string  `json:"reservation,omitempty"`
+	ReservationItem   string  `json:"reservationItem,omitempty"`
 }
 
 // MaintenanceDoneEvent represents completion notification from SAP
@@ -66,14 +379,130 @@ type MaintenanceDoneEvent struct {
 	Notes           string     `json:"notes,omitempty"`
 }
 
+// OperationConfirmationRequest represents a time confirmation recorded by a technician for an order operation
+type OperationConfirmationRequest struct {
+	ActualWork        float64    `json:"actualWork" validate:"gt=0"`
+	ActualWorkUnit    string     `json:"actualWorkUnit,omitempty"`
+	FinalConfirmation bool       `json:"finalConfirmation"`
+	PersonnelNumber   string     `json:"personnelNumber,omitempty"`
+	ConfirmationText  string     `json:"confirmationText,omitempty"`
+	WorkStartTime     *time.Time `json:"workStartTime,omitempty"`
+	WorkEndTime       *time.Time `json:"workEndTime,omitempty"`
+	PostingDate       *time.Time `json:"postingDate,omitempty"`
+}
+
+// OperationConfirmationResponse represents a confirmation stored in SAP for an order operation
+type OperationConfirmationResponse struct {
+	ConfirmationID      string     `json:"confirmationId"`
+	ConfirmationCounter string     `json:"confirmationCounter"`
+	OrderID             string     `json:"orderId"`
+	OperationID         string     `json:"operationId"`
+	ActualWork          float64    `json:"actualWork"`
+	ActualWorkUnit      string     `json:"actualWorkUnit,omitempty"`
+	FinalConfirmation   bool       `json:"finalConfirmation"`
+	PersonnelNumber     string     `json:"personnelNumber,omitempty"`
+	Cancelled           bool       `json:"cancelled"`
+	CreatedAt           *time.Time `json:"createdAt,omitempty"` // SAP posting date
+}
+
 // SAP Notification Request
 type SAPNotificationRequest struct {
-	NotificationType   string `json:"NotificationType"`
-	Description        string `json:"Description"`
-	Equipment          string `json:"Equipment"`
-	FunctionalLocation string `json:"FunctionalLocation,omitempty"`
-	Plant              string `json:"Plant"`
-	Priority           string `json:"Priority,omitempty"`
+	NotificationType   string                 `json:"NotificationType"`
+	Description        string                 `json:"Description"`
+	Equipment          string                 `json:"Equipment"`
+	FunctionalLocation string                 `json:"FunctionalLocation,omitempty"`
+	Plant              string                 `json:"Plant"`
+	Priority           string                 `json:"Priority,omitempty"`
+	NotificationText   string                 `json:"NotificationText,omitempty"`
+	RequiredStartDate  string                 `json:"RequiredStartDate,omitempty"`
+	RequiredEndDate    string                 `json:"RequiredEndDate,omitempty"`
+	ToItem             []SAPNotificationItem  `json:"to_Item,omitempty"`
+	Extensions         map[string]interface{} `json:"-"` // Custom fields such as YY1_AnomalyScore_NOT
+}
+
+// MarshalJSON writes the custom fields next to the standard properties
+func (r SAPNotificationRequest) MarshalJSON() ([]byte, error) {
+	type plain SAPNotificationRequest
+	return marshalWithExtensions(plain(r), r.Extensions)
+}
+
+// SAP Notification Item (A_MaintenanceNotificationItem)
+type SAPNotificationItem struct {
+	MaintNotificationItem       string                        `json:"MaintNotificationItem,omitempty"`
+	MaintNotifItemText          string                        `json:"MaintNotifItemText,omitempty"`
+	MaintNotifObjPrtCodeGroup   string                        `json:"MaintNotifObjPrtCodeGroup,omitempty"`
+	MaintNotifObjPrtCode        string                        `json:"MaintNotifObjPrtCode,omitempty"`
+	MaintNotifDamageCodeGroup   string                        `json:"MaintNotifDamageCodeGroup,omitempty"`
+	MaintNotificationDamageCode string                        `json:"MaintNotificationDamageCode,omitempty"`
+	ToItemCause                 []SAPNotificationItemCause    `json:"to_ItemCause,omitempty"`
+	ToItemActivity              []SAPNotificationItemActivity `json:"to_ItemActivity,omitempty"`
+}
+
+// SAP Notification Item Cause (A_MaintNotifItemCause)
+type SAPNotificationItemCause struct {
+	MaintNotificationCause     string `json:"MaintNotificationCause,omitempty"`
+	MaintNotifCauseText        string `json:"MaintNotifCauseText,omitempty"`
+	MaintNotifCauseCodeGroup   string `json:"MaintNotifCauseCodeGroup,omitempty"`
+	MaintNotificationCauseCode string `json:"MaintNotificationCauseCode,omitempty"`
+}
+
+// SAP Notification Item Activity (A_MaintNotifItemActivity)
+type SAPNotificationItemActivity struct {
+	MaintNotificationActivity     string `json:"MaintNotificationActivity,omitempty"`
+	MaintNotifActivityText        string `json:"MaintNotifActivityText,omitempty"`
+	MaintNotifActivityCodeGroup   string `json:"MaintNotifActivityCodeGroup,omitempty"`
+	MaintNotificationActivityCode string `json:"MaintNotificationActivityCode,omitempty"`
+}
+
+// SAP Equipment entity (API_EQUIPMENT)
+type SAPEquipment struct {
+	Equipment                    string `json:"Equipment"`
+	EquipmentName                string `json:"EquipmentName"`
+	FunctionalLocation           string `json:"FunctionalLocation"`
+	MaintenancePlant             string `json:"MaintenancePlant"`
+	MaintenancePlanningPlant     string `json:"MaintenancePlanningPlant"`
+	MainWorkCenter               string `json:"MainWorkCenter"`
+	MainWorkCenterPlant          string `json:"MainWorkCenterPlant"`
+	ABCIndicator                 string `json:"ABCIndicator"`
+	TechnicalObjectType          string `json:"TechnicalObjectType"`
+	EquipmentIsMarkedForDeletion bool   `json:"EquipmentIsMarkedForDeletion"`
+	EquipmentIsInactive          bool   `json:"EquipmentIsInactive"`
+}
+
+// SAP Equipment List Response
+type SAPEquipmentListResponse struct {
+	D struct {
+		Results []SAPEquipment `json:"results"`
+	} `json:"d"`
+}
+
+// SAP Work Center entity (API_WORK_CENTERS)
+type SAPWorkCenter struct {
+	WorkCenter             string `json:"WorkCenter" yaml:"workCenter"`
+	Plant                  string `json:"Plant" yaml:"plant"`
+	WorkCenterDesc         string `json:"WorkCenterDesc" yaml:"description"`
+	WorkCenterCategoryCode string `json:"WorkCenterCategoryCode" yaml:"category"`
+	WorkCenterIsLocked     bool   `json:"WorkCenterIsLocked" yaml:"locked"`
+}
+
+// SAP Work Center List Response
+type SAPWorkCenterListResponse struct {
+	D struct {
+		Results []SAPWorkCenter `json:"results"`
+	} `json:"d"`
+}
+
+// SAP Functional Location entity (API_FUNCTIONALLOCATION)
+type SAPFunctionalLocation struct {
+	FunctionalLocation                    string `json:"FunctionalLocation" yaml:"functionalLocation"`
+	FunctionalLocationName                string `json:"FunctionalLocationName" yaml:"description"`
+	MaintenancePlant                      string `json:"MaintenancePlant" yaml:"plant"`
+	FunctionalLocationIsMarkedForDeletion bool   `json:"FunctionalLocationIsMarkedForDeletion" yaml:"deleted"`
+}
+
+// SAP Functional Location Detail Response
+type SAPFunctionalLocationResponse struct {
+	D SAPFunctionalLocation `json:"d"`
 }
 
 // SAP Notification Response
@@ -85,73 +514,384 @@ type SAPNotificationResponse struct {
 	} `json:"d"`
 }
 
+// SAP Notification entity (A_MaintenanceNotification)
+type SAPNotification struct {
+	Notification              string                   `json:"Notification"`
+	NotificationType          string                   `json:"NotificationType"`
+	Description               string                   `json:"Description"`
+	Equipment                 string                   `json:"Equipment"`
+	FunctionalLocation        string                   `json:"FunctionalLocation"`
+	Plant                     string                   `json:"Plant"`
+	Priority                  string                   `json:"Priority"`
+	MaintNotifProcessingPhase string                   `json:"MaintNotifProcessingPhase"`
+	MaintenanceOrder          string                   `json:"MaintenanceOrder"`
+	RequiredStartDate         string                   `json:"RequiredStartDate"`
+	RequiredEndDate           string                   `json:"RequiredEndDate"`
+	CreationDate              string                   `json:"CreationDate"`
+	CompletionDate            string                   `json:"CompletionDate"`
+	ToItem                    *SAPNotificationItemList `json:"to_Item,omitempty"` // Only read with $expand=to_Item
+	Metadata                  struct {
+		ETag string `json:"etag,omitempty"`
+	} `json:"__metadata"`
+}
+
+// SAP Notification Item List
+type SAPNotificationItemList struct {
+	Results []SAPNotificationItem `json:"results"`
+}
+
+// SAP Notification Detail Response
+type SAPNotificationDetailResponse struct {
+	D SAPNotification `json:"d"`
+}
+
+// SAP Notification List Response
+type SAPNotificationListResponse struct {
+	D struct {
+		Results []SAPNotification `json:"results"`
+		Next    string            `json:"__next,omitempty"`
+	} `json:"d"`
+}
+
+// SAP Notification Update Request (PATCH, only changed fields are sent)
+type SAPNotificationUpdateRequest struct {
+	Description       string `json:"Description,omitempty"`
+	Priority          string `json:"Priority,omitempty"`
+	RequiredStartDate string `json:"RequiredStartDate,omitempty"`
+	RequiredEndDate   string `json:"RequiredEndDate,omitempty"`
+	NotificationText  string `json:"NotificationText,omitempty"` // Added to the long text
+}
+
 // SAP Order Request
 type SAPOrderRequest struct {
-	MaintenanceOrderType        string              `json:"MaintenanceOrderType"`
-	Description                 string              `json:"Description"`
-	Equipment                   string              `json:"Equipment"`
-	FunctionalLocation          string              `json:"FunctionalLocation,omitempty"`
-	Plant                       string              `json:"Plant"`
-	MaintenancePlanningPlant    string              `json:"MaintenancePlanningPlant,omitempty"`
-	Priority                    string              `json:"Priority,omitempty"`
-	MaintOrdBasicStartDateTime  string              `json:"MaintOrdBasicStartDateTime,omitempty"`
-	MaintOrdBasicEndDateTime    string              `json:"MaintOrdBasicEndDateTime,omitempty"`
-	MaintenanceNotification     string              `json:"MaintenanceNotification,omitempty"`
-	ToMaintenanceOrderOperation []SAPOrderOperation `json:"to_MaintenanceOrderOperation,omitempty"`
+	MaintenanceOrderType        string                 `json:"MaintenanceOrderType"`
+	Description                 string                 `json:"Description"`
+	Equipment                   string                 `json:"Equipment"`
+	FunctionalLocation          string                 `json:"FunctionalLocation,omitempty"`
+	Plant                       string                 `json:"Plant"`
+	MaintenancePlanningPlant    string                 `json:"MaintenancePlanningPlant,omitempty"`
+	MainWorkCenter              string                 `json:"MainWorkCenter,omitempty"`
+	Priority                    string                 `json:"Priority,omitempty"`
+	MaintOrdBasicStartDateTime  string                 `json:"MaintOrdBasicStartDateTime,omitempty"`
+	MaintOrdBasicEndDateTime    string                 `json:"MaintOrdBasicEndDateTime,omitempty"`
+	MaintenanceNotification     string                 `json:"MaintenanceNotification,omitempty"`
+	TaskListType                string                 `json:"TaskListType,omitempty" metadata:"optional"` // General task list copied into the order
+	TaskListGroup               string                 `json:"TaskListGroup,omitempty" metadata:"optional"`
+	TaskListGroupCounter        string                 `json:"TaskListGroupCounter,omitempty" metadata:"optional"`
+	ToMaintenanceOrderOperation []SAPOrderOperation    `json:"to_MaintenanceOrderOperation,omitempty"`
+	Extensions                  map[string]interface{} `json:"-"` // Custom fields such as YY1_DigitalTwinRef_ORD
+}
+
+// MarshalJSON writes the custom fields next to the standard properties
+func (r SAPOrderRequest) MarshalJSON() ([]byte, error) {
+	type plain SAPOrderRequest
+	return marshalWithExtensions(plain(r), r.Extensions)
+}
+
+// SAP Order Update Request (PATCH, only changed fields are sent)
+type SAPOrderUpdateRequest struct {
+	Description                string `json:"Description,omitempty"`
+	FunctionalLocation         string `json:"FunctionalLocation,omitempty"`
+	Priority                   string `json:"Priority,omitempty"`
+	MaintOrdBasicStartDateTime string `json:"MaintOrdBasicStartDateTime,omitempty"`
+	MaintOrdBasicEndDateTime   string `json:"MaintOrdBasicEndDateTime,omitempty"`
+}
+
+// SAP Order Operation Update Request (PATCH, only changed fields are sent)
+type SAPOrderOperationUpdateRequest struct {
+	OperationText             string `json:"OperationText,omitempty"`
+	WorkCenter                string `json:"WorkCenter,omitempty"`
+	OperationStandardDuration string `json:"OperationStandardDuration,omitempty"`
+	OperationDurationUnit     string `json:"OperationDurationUnit,omitempty"`
 }
 
 // SAP Order Operation
 type SAPOrderOperation struct {
+	MaintenanceOrder          string `json:"MaintenanceOrder,omitempty"`
+	MaintenanceOrderOperation string `json:"MaintenanceOrderOperation,omitempty"`
 	OperationText             string `json:"OperationText"`
 	WorkCenter                string `json:"WorkCenter,omitempty"`
 	Plant                     string `json:"Plant,omitempty"`
 	OperationControlKey       string `json:"OperationControlKey,omitempty"`
 	OperationStandardDuration string `json:"OperationStandardDuration,omitempty"`
 	OperationDurationUnit     string `json:"OperationDurationUnit,omitempty"`
+
+	ToMaintenanceOrderComponent []SAPOrderComponent `json:"to_MaintenanceOrderComponent,omitempty"`
+}
+
+// SAP Order Component (material reservation item)
+type SAPOrderComponent struct {
+	Material                      string `json:"Material"`
+	RequirementQuantityInBaseUnit string `json:"RequirementQuantityInBaseUnit"`
+	BaseUnit                      string `json:"BaseUnit,omitempty"`
+	Plant                         string `json:"Plant,omitempty"`
+	StorageLocation               string `json:"StorageLocation,omitempty"`
+}
+
+// SAP Order Component Response
+type SAPOrderComponentResponse struct {
+	MaintenanceOrder              string `json:"MaintenanceOrder"`
+	MaintenanceOrderOperation     string `json:"MaintenanceOrderOperation"`
+	MaintenanceOrderComponent     string `json:"MaintenanceOrderComponent"`
+	Material                      string `json:"Material"`
+	RequirementQuantityInBaseUnit string `json:"RequirementQuantityInBaseUnit"`
+	WithdrawnQuantity             string `json:"WithdrawnQuantity,omitempty"`
+	BaseUnit                      string `json:"BaseUnit"`
+	StorageLocation               string `json:"StorageLocation,omitempty"`
+	Reservation                   string `json:"Reservation,omitempty"`
+	ReservationItem               string `json:"ReservationItem,omitempty"`
+}
+
+// SAP Order Component List (expanded navigation property)
+type SAPOrderComponentList struct {
+	Results []SAPOrderComponentResponse `json:"results"`
 }
 
 // SAP Order Response
 type SAPOrderResponse struct {
+	D SAPOrder `json:"d"`
+}
+
+// SAP Order entity (A_MaintenanceOrder)
+type SAPOrder struct {
+	MaintenanceOrder           string `json:"MaintenanceOrder"`
+	MaintenanceOrderType       string `json:"MaintenanceOrderType"`
+	Description                string `json:"Description"`
+	Equipment                  string `json:"Equipment"`
+	FunctionalLocation         string `json:"FunctionalLocation"`
+	Plant                      string `json:"Plant"`
+	Priority                   string `json:"Priority"`
+	OrderStatus                string `json:"OrderStatus"`
+	MaintOrdBasicStartDateTime string `json:"MaintOrdBasicStartDateTime"`
+	MaintOrdBasicEndDateTime   string `json:"MaintOrdBasicEndDateTime"`
+	MaintenanceNotification    string `json:"MaintenanceNotification"`
+	Metadata                   struct {
+		ID   string `json:"id"`
+		URI  string `json:"uri"`
+		Type string `json:"type"`
+		ETag string `json:"etag,omitempty"`
+	} `json:"__metadata"`
+	ToMaintenanceOrderOperation struct {
+		Results []SAPOrderOperationResponse `json:"results"`
+	} `json:"to_MaintenanceOrderOperation"`
+	Extensions map[string]interface{} `json:"-"` // Custom fields (YY1_, ZZ1_) returned by SAP
+}
+
+// UnmarshalJSON collects the custom fields of the order into Extensions
+func (o *SAPOrder) UnmarshalJSON(data []byte) error {
+	type plain SAPOrder
+	if err := json.Unmarshal(data, (*plain)(o)); err != nil {
+		return err
+	}
+	extensions, err := unmarshalExtensions(data)
+	o.Extensions = extensions
+	return err
+}
+
+// SAP Order List Response (collection with optional server-driven paging link)
+type SAPOrderListResponse struct {
 	D struct {
-		MaintenanceOrder           string `json:"MaintenanceOrder"`
-		MaintenanceOrderType       string `json:"MaintenanceOrderType"`
-		Description                string `json:"Description"`
-		Equipment                  string `json:"Equipment"`
-		Plant                      string `json:"Plant"`
-		OrderStatus                string `json:"OrderStatus"`
-		MaintOrdBasicStartDateTime string `json:"MaintOrdBasicStartDateTime"`
-		MaintOrdBasicEndDateTime   string `json:"MaintOrdBasicEndDateTime"`
-		MaintenanceNotification    string `json:"MaintenanceNotification"`
-		Metadata                   struct {
-			ID   string `json:"id"`
-			URI  string `json:"uri"`
-			Type string `json:"type"`
-		} `json:"__metadata"`
-		ToMaintenanceOrderOperation struct {
-			Results []SAPOrderOperationResponse `json:"results"`
-		} `json:"to_MaintenanceOrderOperation"`
+		Results []SAPOrder `json:"results"`
+		Next    string     `json:"__next,omitempty"`
 	} `json:"d"`
 }
 
 // SAP Order Operation Response
 type SAPOrderOperationResponse struct {
-	MaintenanceOrder          string `json:"MaintenanceOrder"`
-	MaintenanceOrderOperation string `json:"MaintenanceOrderOperation"`
-	OperationText             string `json:"OperationText"`
-	WorkCenter                string `json:"WorkCenter"`
-	OperationControlKey       string `json:"OperationControlKey"`
-	OperationStandardDuration string `json:"OperationStandardDuration"`
-	OperationDurationUnit     string `json:"OperationDurationUnit"`
-	OperationStatus           string `json:"OperationStatus,omitempty"`
-	ActualWorkQuantity        string `json:"ActualWorkQuantity,omitempty"`
-	WorkQuantityUnit          string `json:"WorkQuantityUnit,omitempty"`
-	Metadata                  struct {
+	MaintenanceOrder            string                `json:"MaintenanceOrder"`
+	MaintenanceOrderOperation   string                `json:"MaintenanceOrderOperation"`
+	OperationText               string                `json:"OperationText"`
+	WorkCenter                  string                `json:"WorkCenter"`
+	OperationControlKey         string                `json:"OperationControlKey"`
+	OperationStandardDuration   string                `json:"OperationStandardDuration"`
+	OperationDurationUnit       string                `json:"OperationDurationUnit"`
+	OperationStatus             string                `json:"OperationStatus,omitempty"`
+	ActualWorkQuantity          string                `json:"ActualWorkQuantity,omitempty"`
+	WorkQuantityUnit            string                `json:"WorkQuantityUnit,omitempty"`
+	ToMaintenanceOrderComponent SAPOrderComponentList `json:"to_MaintenanceOrderComponent"`
+	Metadata                    struct {
 		ID   string `json:"id"`
 		URI  string `json:"uri"`
 		Type string `json:"type"`
+		ETag string `json:"etag,omitempty"`
 	} `json:"__metadata"`
 }
 
+// SAP Confirmation Request (API_MAINTORDERCONFIRMATION)
+type SAPConfirmationRequest struct {
+	MaintenanceOrder            string `json:"MaintenanceOrder"`
+	MaintenanceOrderOperation   string `json:"MaintenanceOrderOperation"`
+	ActualWorkQuantity          string `json:"ActualWorkQuantity"`
+	ActualWorkQuantityUnit      string `json:"ActualWorkQuantityUnit,omitempty"`
+	IsFinalConfirmation         bool   `json:"IsFinalConfirmation"`
+	PersonnelNumber             string `json:"PersonnelNumber,omitempty"`
+	ConfirmationText            string `json:"ConfirmationText,omitempty"`
+	PostingDate                 string `json:"PostingDate,omitempty"`
+	ConfirmedExecutionStartDate string `json:"ConfirmedExecutionStartDate,omitempty"`
+	ConfirmedExecutionEndDate   string `json:"ConfirmedExecutionEndDate,omitempty"`
+}
+
+// SAP Confirmation entity as returned by API_MAINTORDERCONFIRMATION
+type SAPConfirmation struct {
+	MaintOrderConf            string `json:"MaintOrderConf"`
+	MaintOrderConfCntrValue   string `json:"MaintOrderConfCntrValue"`
+	MaintenanceOrder          string `json:"MaintenanceOrder"`
+	MaintenanceOrderOperation string `json:"MaintenanceOrderOperation"`
+	ActualWorkQuantity        string `json:"ActualWorkQuantity"`
+	ActualWorkQuantityUnit    string `json:"ActualWorkQuantityUnit"`
+	IsFinalConfirmation       bool   `json:"IsFinalConfirmation"`
+	IsReversed                bool   `json:"IsReversed"`
+	PersonnelNumber           string `json:"PersonnelNumber,omitempty"`
+	PostingDate               string `json:"PostingDate,omitempty"`
+}
+
+// SAP Confirmation Response
+type SAPConfirmationResponse struct {
+	D SAPConfirmation `json:"d"`
+}
+
+// SAP Confirmation List Response
+type SAPConfirmationListResponse struct {
+	D struct {
+		Results []SAPConfirmation `json:"results"`
+	} `json:"d"`
+}
+
+// SAP Order Operation Create Response
+type SAPOrderOperationCreateResponse struct {
+	D SAPOrderOperationResponse `json:"d"`
+}
+
+// SAP OData v4 Order entity (MaintenanceOrder). v4 payloads have no d wrapper, carry the
+// ETag as @odata.etag and use JSON numbers for quantities.
+type SAPV4Order struct {
+	ETag                         string                 `json:"@odata.etag,omitempty"`
+	MaintenanceOrder             string                 `json:"MaintenanceOrder,omitempty"`
+	MaintenanceOrderType         string                 `json:"MaintenanceOrderType,omitempty"`
+	MaintenanceOrderDesc         string                 `json:"MaintenanceOrderDesc,omitempty"`
+	Equipment                    string                 `json:"Equipment,omitempty"`
+	FunctionalLocation           string                 `json:"FunctionalLocation,omitempty"`
+	MaintenancePlant             string                 `json:"MaintenancePlant,omitempty"`
+	MaintenancePlanningPlant     string                 `json:"MaintenancePlanningPlant,omitempty"`
+	MainWorkCenter               string                 `json:"MainWorkCenter,omitempty"`
+	MaintPriority                string                 `json:"MaintPriority,omitempty"`
+	MaintenanceOrderSystemStatus string                 `json:"MaintenanceOrderSystemStatus,omitempty"`
+	MaintOrdBasicStartDateTime   string                 `json:"MaintOrdBasicStartDateTime,omitempty"`
+	MaintOrdBasicEndDateTime     string                 `json:"MaintOrdBasicEndDateTime,omitempty"`
+	MaintenanceNotification      string                 `json:"MaintenanceNotification,omitempty"`
+	TaskListType                 string                 `json:"TaskListType,omitempty" metadata:"optional"`
+	TaskListGroup                string                 `json:"TaskListGroup,omitempty" metadata:"optional"`
+	TaskListGroupCounter         string                 `json:"TaskListGroupCounter,omitempty" metadata:"optional"`
+	Operations                   []SAPV4OrderOperation  `json:"_MaintenanceOrderOperation,omitempty"`
+	Extensions                   map[string]interface{} `json:"-"` // Custom fields (YY1_, ZZ1_)
+}
+
+// MarshalJSON writes the custom fields next to the standard properties
+func (o SAPV4Order) MarshalJSON() ([]byte, error) {
+	type plain SAPV4Order
+	return marshalWithExtensions(plain(o), o.Extensions)
+}
+
+// UnmarshalJSON collects the custom fields of the order into Extensions
+func (o *SAPV4Order) UnmarshalJSON(data []byte) error {
+	type plain SAPV4Order
+	if err := json.Unmarshal(data, (*plain)(o)); err != nil {
+		return err
+	}
+	extensions, err := unmarshalExtensions(data)
+	o.Extensions = extensions
+	return err
+}
+
+// SAP OData v4 Order Operation entity (MaintenanceOrderOperation)
+type SAPV4OrderOperation struct {
+	ETag                      string                `json:"@odata.etag,omitempty"`
+	MaintenanceOrder          string                `json:"MaintenanceOrder,omitempty"`
+	MaintenanceOrderOperation string                `json:"MaintenanceOrderOperation,omitempty"`
+	OperationDescription      string                `json:"OperationDescription,omitempty"`
+	WorkCenter                string                `json:"WorkCenter,omitempty"`
+	MaintenancePlant          string                `json:"MaintenancePlant,omitempty"`
+	OperationControlKey       string                `json:"OperationControlKey,omitempty"`
+	OperationStandardDuration json.Number           `json:"OperationStandardDuration,omitempty"`
+	OperationDurationUnit     string                `json:"OperationDurationUnit,omitempty"`
+	OperationSystemStatus     string                `json:"OperationSystemStatus,omitempty"`
+	ActualWorkQuantity        json.Number           `json:"ActualWorkQuantity,omitempty"`
+	WorkQuantityUnit          string                `json:"WorkQuantityUnit,omitempty"`
+	Components                []SAPV4OrderComponent `json:"_MaintenanceOrderComponent,omitempty"`
+}
+
+// SAP OData v4 Order Component entity (MaintenanceOrderComponent)
+type SAPV4OrderComponent struct {
+	MaintenanceOrderComponent string      `json:"MaintenanceOrderComponent,omitempty"`
+	Material                  string      `json:"Material"`
+	RequiredQuantity          json.Number `json:"RequiredQuantity"`
+	WithdrawnQuantity         json.Number `json:"WithdrawnQuantity,omitempty"`
+	BaseUnit                  string      `json:"BaseUnit,omitempty"`
+	MaintenancePlant          string      `json:"MaintenancePlant,omitempty"`
+	StorageLocation           string      `json:"StorageLocation,omitempty"`
+	Reservation               string      `json:"Reservation,omitempty"`
+	ReservationItem           string      `json:"ReservationItem,omitempty"`
+}
+
+// SAP OData v4 Order collection (server-driven paging via @odata.nextLink)
+type SAPV4OrderListResponse struct {
+	Value    []SAPV4Order `json:"value"`
+	NextLink string       `json:"@odata.nextLink,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// RuleDecision is the value determined for a field and where it came from
+type RuleDecision struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`         // event (sent by the caller), rule or none
+	Rule   int    `json:"rule,omitempty"` // Position of the matching rule, starting at 1
+}
+
+// OperationRuleDecision is the control key determined for an operation
+type OperationRuleDecision struct {
+	OperationID string       `json:"operationId"`
+	WorkCenter  string       `json:"workCenter,omitempty"`
+	ControlKey  RuleDecision `json:"controlKey"`
+}
+
+// RuleEvaluation is the result of the determination rules for an event
+type RuleEvaluation struct {
+	Destination      string                  `json:"destination,omitempty"`
+	OrderType        RuleDecision            `json:"orderType"`
+	NotificationType RuleDecision            `json:"notificationType"`
+	Operations       []OperationRuleDecision `json:"operations,omitempty"`
+}
+
+// MetadataDrift is a field the adaptor sends that does not match the SAP $metadata
+type MetadataDrift struct {
+	Service   string `json:"service"`
+	EntitySet string `json:"entitySet"`
+	Field     string `json:"field,omitempty"`
+	Message   string `json:"message"`
+}
+
+// SAPMetadataStatus is the result of the $metadata check of one SAP destination
+type SAPMetadataStatus struct {
+	Destination string          `json:"destination"`
+	Status      string          `json:"status"` // pending, ok, drift, unavailable or skipped
+	CheckedAt   *time.Time      `json:"checkedAt,omitempty"`
+	Drift       []MetadataDrift `json:"drift,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// HealthResponse represents the health check response
+type HealthResponse struct {
+	Success     bool                `json:"success"`
+	Message     string              `json:"message"`
+	SAPMetadata []SAPMetadataStatus `json:"sapMetadata,omitempty"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string      `json:"error"`
@@ -165,51 +905,74 @@ type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
-// ConvertMaintenanceOrderEventToNotificationRequest converts a MaintenanceOrderEvent to SAP notification request
-func ConvertMaintenanceOrderEventToNotificationRequest(event *MaintenanceOrderEvent) *SAPNotificationRequest {
-	return &SAPNotificationRequest{
-		NotificationType:   "M1", // Default notification type
-		Description:        event.Description,
-		Equipment:          event.EquipmentID,
-		FunctionalLocation: event.FunctionalLocation,
-		Plant:              event.Plant,
-		Priority:           event.Priority,
+// ValidateComponentAssignments checks that every component references an operation of the event
+func (e *MaintenanceOrderEvent) ValidateComponentAssignments() error {
+	for _, comp := range e.Components {
+		if comp.OperationID == "" {
+			continue
+		}
+		found := false
+		for i, op := range e.Operations {
+			if OperationNumberForIndex(op.OperationID, i) == NormalizeOperationID(comp.OperationID) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("component %s references unknown operation %s", comp.Material, comp.OperationID)
+		}
 	}
+	return nil
 }
 
-// ConvertMaintenanceOrderEventToOrderRequest converts a MaintenanceOrderEvent to SAP order request
-func ConvertMaintenanceOrderEventToOrderRequest(event *MaintenanceOrderEvent, notificationID string) *SAPOrderRequest {
-	req := &SAPOrderRequest{
-		MaintenanceOrderType:     event.MaintenanceOrderType,
-		Description:              event.Description,
-		Equipment:                event.EquipmentID,
-		FunctionalLocation:       event.FunctionalLocation,
-		Plant:                    event.Plant,
-		MaintenancePlanningPlant: event.Plant, // Default to same plant
-		Priority:                 event.Priority,
-		MaintenanceNotification:  notificationID,
-	}
+// OperationNumberStep is the increment SAP uses between operation numbers (0010, 0020, ...)
+const OperationNumberStep = 10
 
-	// Add time fields if provided
-	if event.PlannedStartTime != nil {
-		req.MaintOrdBasicStartDateTime = event.PlannedStartTime.Format(time.RFC3339)
+// OperationNumberForIndex returns the SAP operation number for the i-th operation of a new order.
+// An explicitly provided operation ID is kept (normalized to 4 digits).
+func OperationNumberForIndex(operationID string, i int) string {
+	if operationID != "" {
+		return NormalizeOperationID(operationID)
 	}
-	if event.PlannedEndTime != nil {
-		req.MaintOrdBasicEndDateTime = event.PlannedEndTime.Format(time.RFC3339)
+	return fmt.Sprintf("%04d", (i+1)*OperationNumberStep)
+}
+
+// NormalizeOperationID pads an operation number to the 4-digit SAP format (10 -> 0010)
+func NormalizeOperationID(operationID string) string {
+	operationID = strings.TrimSpace(operationID)
+	if len(operationID) >= 4 {
+		return operationID
 	}
+	return strings.Repeat("0", 4-len(operationID)) + operationID
+}
 
-	// Convert operations
-	for _, op := range event.Operations {
-		sapOp := SAPOrderOperation{
-			OperationText:             op.Text,
-			WorkCenter:                op.WorkCenter,
-			Plant:                     event.Plant,
-			OperationControlKey:       event.MaintenanceOrderType,
-			OperationStandardDuration: strconv.FormatFloat(op.Duration, 'f', -1, 64),
-			OperationDurationUnit:     op.DurationUnit,
-		}
-		req.ToMaintenanceOrderOperation = append(req.ToMaintenanceOrderOperation, sapOp)
+// Offset decodes the paging cursor of the query into a result offset
+func (q *MaintenanceOrderQuery) Offset() (int, error) {
+	return decodeCursor(q.Cursor)
+}
+
+// Offset decodes the paging cursor of the query into a result offset
+func (q *MaintenanceNotificationQuery) Offset() (int, error) {
+	return decodeCursor(q.Cursor)
+}
+
+// decodeCursor decodes an opaque paging cursor into a result offset
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor: %w", err)
 	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), "offset:"))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return offset, nil
+}
 
-	return req
+// EncodeOrderCursor builds the opaque paging cursor pointing at the given result offset
+func EncodeOrderCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
 }
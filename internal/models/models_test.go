@@ -5,46 +5,6 @@ import (
 	"time"
 )
 
-func TestMaintenanceOrderEventValidation(t *testing.T) {
-	// Test valid maintenance order event
-	event := &MaintenanceOrderEvent{
-		EquipmentID:          "10000045",
-		FunctionalLocation:   "FL100-200-300",
-		Plant:                "1000",
-		Description:          "Test maintenance order",
-		Priority:             "3",
-		MaintenanceOrderType: "PM01",
-		PlannedStartTime:     &[]time.Time{time.Now()}[0],
-		PlannedEndTime:       &[]time.Time{time.Now().Add(8 * time.Hour)}[0],
-		Operations: []MaintenanceOperation{
-			{
-				Text:         "Test operation",
-				WorkCenter:   "TEST-WC01",
-				Duration:     4.0,
-				DurationUnit: "H",
-			},
-		},
-	}
-
-	// Test conversion to SAP notification request
-	notificationReq := ConvertMaintenanceOrderEventToNotificationRequest(event)
-	if notificationReq.Equipment != event.EquipmentID {
-		t.Errorf("Expected equipment %s, got %s", event.EquipmentID, notificationReq.Equipment)
-	}
-	if notificationReq.Description != event.Description {
-		t.Errorf("Expected description %s, got %s", event.Description, notificationReq.Description)
-	}
-
-	// Test conversion to SAP order request
-	orderReq := ConvertMaintenanceOrderEventToOrderRequest(event, "200000123")
-	if orderReq.Equipment != event.EquipmentID {
-		t.Errorf("Expected equipment %s, got %s", event.EquipmentID, orderReq.Equipment)
-	}
-	if orderReq.MaintenanceNotification != "200000123" {
-		t.Errorf("Expected notification ID 200000123, got %s", orderReq.MaintenanceNotification)
-	}
-}
-
 func TestMaintenanceDoneEventValidation(t *testing.T) {
 	// Test valid maintenance done event
 	event := &MaintenanceDoneEvent{
@@ -62,3 +22,34 @@ func TestMaintenanceDoneEventValidation(t *testing.T) {
 		t.Error("Status should not be empty")
 	}
 }
+
+func TestValidateComponentAssignments(t *testing.T) {
+	event := &MaintenanceOrderEvent{
+		Operations: []MaintenanceOperation{
+			{Text: "Disassemble pump"},
+			{Text: "Replace seal"},
+		},
+		Components: []MaintenanceComponent{
+			{Material: "SEAL-KIT-100", Quantity: 1, OperationID: "0020"},
+			{Material: "GREASE-5", Quantity: 0.5, Unit: "KG"},
+		},
+	}
+
+	if err := event.ValidateComponentAssignments(); err != nil {
+		t.Fatalf("Expected valid component assignments, got %v", err)
+	}
+
+	event.Components[0].OperationID = "0090"
+	if err := event.ValidateComponentAssignments(); err == nil {
+		t.Error("Expected error for component assigned to unknown operation")
+	}
+}
+
+func TestNormalizeOperationID(t *testing.T) {
+	cases := map[string]string{"10": "0010", "0020": "0020", " 30 ": "0030"}
+	for in, want := range cases {
+		if got := NormalizeOperationID(in); got != want {
+			t.Errorf("NormalizeOperationID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
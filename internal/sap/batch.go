@@ -0,0 +1,441 @@
+package sap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BatchRequest is a single request inside an OData $batch
+type BatchRequest struct {
+	Method  string
+	Path    string // Service path as used by the client, e.g. /API_MAINTENANCE_ORDER/A_MaintenanceOrder('4711')
+	Headers map[string]string
+	Body    interface{}
+}
+
+// BatchPart is either a single read request or a changeset of write requests that SAP
+// applies atomically
+type BatchPart struct {
+	Request   *BatchRequest
+	Changeset []BatchRequest
+}
+
+// BatchResponse is the response to one request of a $batch
+type BatchResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Decode unmarshals the JSON body of the response
+func (r *BatchResponse) Decode(out interface{}) error {
+	if len(r.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Body, out); err != nil {
+		return fmt.Errorf("failed to parse batch response: %w", err)
+	}
+	return nil
+}
+
+// BatchResult is the outcome of one part of a $batch. A changeset that SAP rolled back has
+// a single error response and Err set.
+type BatchResult struct {
+	Responses []BatchResponse
+	Err       error
+}
+
+// Batch sends the parts to the $batch endpoint of an OData service (e.g. /API_MAINTENANCE_ORDER)
+// and returns one result per part
+func (c *Client) Batch(ctx context.Context, service string, parts []BatchPart) ([]BatchResult, error) {
+	c.logger.WithFields(logrus.Fields{
+		"service":       service,
+		"parts":         len(parts),
+		"simulatorMode": c.simulatorMode,
+	}).Info("Sending SAP $batch request")
+
+	// If in simulator mode, execute the parts against the simulator state
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - executing batch against mock state")
+		results := make([]BatchResult, 0, len(parts))
+		for _, part := range parts {
+			results = append(results, c.executeMockBatchPart(part))
+		}
+		return results, nil
+	}
+
+	for _, part := range parts {
+		for i := range part.Changeset {
+			req := &part.Changeset[i]
+			if err := c.validatePayload(ctx, req.Path, req.Body, req.Method == http.MethodPost); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	body, contentType, err := buildBatchBody(service, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := c.newRequest(ctx, http.MethodPost, service+"/$batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("Accept", "multipart/mixed")
+	if service == orderServiceV4Path {
+		httpReq.Header.Set("OData-Version", "4.0")
+		httpReq.Header.Set("OData-MaxVersion", "4.0")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	results, err := parseBatchResponse(resp.Header.Get("Content-Type"), resp.Body, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.WithField("parts", len(results)).Info("SAP $batch request completed")
+	return results, nil
+}
+
+// buildBatchBody encodes the parts as a multipart/mixed $batch body. Every request of a
+// changeset gets a Content-ID that is unique within the batch: OData v4 requires it, v2
+// accepts it.
+func buildBatchBody(service string, parts []BatchPart) ([]byte, string, error) {
+	var buf bytes.Buffer
+	batch := multipart.NewWriter(&buf)
+
+	contentID := 0
+	for _, part := range parts {
+		if part.Request != nil {
+			if err := writeBatchRequest(batch, service, part.Request, ""); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		var changesetBuf bytes.Buffer
+		changeset := multipart.NewWriter(&changesetBuf)
+		for i := range part.Changeset {
+			contentID++
+			if err := writeBatchRequest(changeset, service, &part.Changeset[i], strconv.Itoa(contentID)); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := changeset.Close(); err != nil {
+			return nil, "", err
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", "multipart/mixed; boundary="+changeset.Boundary())
+		w, err := batch.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := w.Write(changesetBuf.Bytes()); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := batch.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/mixed; boundary=" + batch.Boundary(), nil
+}
+
+// writeBatchRequest writes one request as an application/http part. contentID is set on
+// requests inside a changeset.
+func writeBatchRequest(w *multipart.Writer, service string, req *BatchRequest, contentID string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "application/http")
+	header.Set("Content-Transfer-Encoding", "binary")
+	if contentID != "" {
+		header.Set("Content-ID", contentID)
+	}
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	// Paths inside a batch are relative to the service root
+	path := strings.TrimPrefix(strings.TrimPrefix(req.Path, service), "/")
+	fmt.Fprintf(part, "%s %s HTTP/1.1\r\n", req.Method, path)
+	fmt.Fprintf(part, "Accept: application/json\r\n")
+	for name, value := range req.Headers {
+		fmt.Fprintf(part, "%s: %s\r\n", name, value)
+	}
+
+	if req.Body == nil {
+		_, err = fmt.Fprint(part, "\r\n")
+		return err
+	}
+	payload, err := json.Marshal(req.Body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch request: %w", err)
+	}
+	fmt.Fprintf(part, "Content-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(payload))
+	_, err = part.Write(payload)
+	return err
+}
+
+// parseBatchResponse maps the multipart $batch response back to the requested parts
+func parseBatchResponse(contentType string, body io.Reader, parts []BatchPart) ([]BatchResult, error) {
+	boundary, err := multipartBoundary(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := multipart.NewReader(body, boundary)
+	results := make([]BatchResult, 0, len(parts))
+	for i := range parts {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, fmt.Errorf("batch response has %d parts, expected %d: %w", i, len(parts), err)
+		}
+
+		var result BatchResult
+		if nested, err := multipartBoundary(part.Header.Get("Content-Type")); err == nil {
+			// Successful changeset: one response per request
+			changeset := multipart.NewReader(part, nested)
+			for {
+				inner, err := changeset.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to read changeset response: %w", err)
+				}
+				resp, err := readBatchResponse(inner)
+				if err != nil {
+					return nil, err
+				}
+				result.Responses = append(result.Responses, *resp)
+			}
+		} else {
+			// Single read response, or the error of a rolled back changeset
+			resp, err := readBatchResponse(part)
+			if err != nil {
+				return nil, err
+			}
+			result.Responses = []BatchResponse{*resp}
+		}
+
+		for _, resp := range result.Responses {
+			if resp.StatusCode >= http.StatusMultipleChoices {
+				result.Err = &APIError{StatusCode: resp.StatusCode, Body: string(resp.Body)}
+				break
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// readBatchResponse parses an application/http part
+func readBatchResponse(part io.Reader) (*BatchResponse, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse batch response part: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch response part: %w", err)
+	}
+	return &BatchResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       bytes.TrimSpace(body),
+	}, nil
+}
+
+// multipartBoundary returns the boundary of a multipart/mixed content type
+func multipartBoundary(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return "", fmt.Errorf("not a multipart content type: %q", contentType)
+	}
+	return params["boundary"], nil
+}
+
+// executeMockBatchPart applies a batch part to the simulator state. A changeset is only
+// applied when all of its ETag preconditions hold.
+func (c *Client) executeMockBatchPart(part BatchPart) BatchResult {
+	if part.Request != nil {
+		resp := c.executeMockBatchRequest(part.Request)
+		result := BatchResult{Responses: []BatchResponse{resp}}
+		if resp.StatusCode >= http.StatusMultipleChoices {
+			result.Err = &APIError{StatusCode: resp.StatusCode, Body: string(resp.Body)}
+		}
+		return result
+	}
+
+	for _, req := range part.Changeset {
+		etag := req.Headers["If-Match"]
+		if req.Method == http.MethodPatch && etag != "*" && etag != c.mockETag(req.Path) {
+			body := []byte(`{"error":{"message":{"value":"ETag mismatch"}}}`)
+			return BatchResult{
+				Responses: []BatchResponse{{StatusCode: http.StatusPreconditionFailed, Header: http.Header{}, Body: body}},
+				Err:       &APIError{StatusCode: http.StatusPreconditionFailed, Body: string(body)},
+			}
+		}
+	}
+
+	var result BatchResult
+	for i := range part.Changeset {
+		result.Responses = append(result.Responses, c.executeMockBatchRequest(&part.Changeset[i]))
+	}
+	return result
+}
+
+// executeMockBatchRequest answers one batch request from the simulator state
+func (c *Client) executeMockBatchRequest(req *BatchRequest) BatchResponse {
+	header := http.Header{}
+	switch req.Method {
+	case http.MethodGet:
+		header.Set("ETag", c.mockETag(req.Path))
+		return BatchResponse{StatusCode: http.StatusOK, Header: header, Body: []byte(`{"d":{}}`)}
+	case http.MethodPatch:
+		etag, err := c.patchMockEntity(req.Path, req.Headers["If-Match"])
+		if err != nil {
+			return BatchResponse{StatusCode: http.StatusPreconditionFailed, Header: header, Body: []byte(err.Error())}
+		}
+		header.Set("ETag", etag)
+		return BatchResponse{StatusCode: http.StatusNoContent, Header: header}
+	case http.MethodPost:
+		payload, _ := json.Marshal(map[string]interface{}{"d": req.Body})
+		return BatchResponse{StatusCode: http.StatusCreated, Header: header, Body: payload}
+	default:
+		return BatchResponse{StatusCode: http.StatusNoContent, Header: header}
+	}
+}
+
+// OperationUpdate is an operation change of a bulk order update. ETag is the version of the
+// operation the change is based on.
+type OperationUpdate struct {
+	OperationID string
+	ETag        string
+	Request     *models.SAPOrderOperationUpdateRequest
+}
+
+// UpdateOrderWithOperations changes the order header (if req is not nil) and the given operations
+// in one $batch changeset, so either all changes are applied or none. Every change carries its own
+// If-Match: etag for the header, the operation ETag for each operation. Without header changes
+// nothing is written to the header; etag is compared with the current order ETag before the
+// changeset is sent instead. A missing ETag returns ErrETagRequired. The new order ETag and the
+// new ETag of each operation are returned on success.
+func (c *Client) UpdateOrderWithOperations(ctx context.Context, orderID string, req *models.SAPOrderUpdateRequest, ops []OperationUpdate, etag string) (string, map[string]string, error) {
+	c.logger.WithFields(logrus.Fields{
+		"orderId":       orderID,
+		"etag":          etag,
+		"operations":    len(ops),
+		"simulatorMode": c.simulatorMode,
+	}).Info("Updating SAP maintenance order in one changeset")
+
+	if etag == "" {
+		return "", nil, ErrETagRequired
+	}
+	for _, op := range ops {
+		if op.ETag == "" {
+			return "", nil, fmt.Errorf("%w: operation %s", ErrETagRequired, op.OperationID)
+		}
+	}
+
+	var changeset []BatchRequest
+	if req != nil {
+		changeset = append(changeset, BatchRequest{
+			Method:  http.MethodPatch,
+			Path:    c.orderEntityPath(orderID),
+			Headers: map[string]string{"If-Match": etag},
+			Body:    c.orderUpdateBody(req),
+		})
+	} else {
+		current, err := c.GetOrderETag(ctx, orderID)
+		if err != nil {
+			return "", nil, err
+		}
+		if current != etag {
+			return "", nil, c.resolveConflict(ctx, orderID, &APIError{StatusCode: http.StatusPreconditionFailed, Body: "order ETag " + etag + " is not current"})
+		}
+	}
+	for _, op := range ops {
+		changeset = append(changeset, BatchRequest{
+			Method:  http.MethodPatch,
+			Path:    c.operationEntityPath(orderID, op.OperationID),
+			Headers: map[string]string{"If-Match": op.ETag},
+			Body:    c.operationUpdateBody(op.Request),
+		})
+	}
+	if len(changeset) == 0 {
+		return etag, nil, nil
+	}
+
+	results, err := c.Batch(ctx, c.orderService(), []BatchPart{{Changeset: changeset}})
+	if err != nil {
+		return "", nil, err
+	}
+	if results[0].Err != nil {
+		return "", nil, c.resolveConflict(ctx, orderID, results[0].Err)
+	}
+	responses := results[0].Responses
+
+	newETag := ""
+	if req != nil {
+		newETag = responses[0].Header.Get("ETag")
+		responses = responses[1:]
+	}
+	if newETag == "" {
+		if newETag, err = c.GetOrderETag(ctx, orderID); err != nil {
+			return "", nil, err
+		}
+	}
+
+	operationETags := make(map[string]string, len(ops))
+	for i, op := range ops {
+		opETag := ""
+		if i < len(responses) {
+			opETag = responses[i].Header.Get("ETag")
+		}
+		if opETag == "" {
+			if opETag, err = c.GetETag(ctx, c.operationEntityPath(orderID, op.OperationID)); err != nil {
+				return "", nil, err
+			}
+		}
+		operationETags[op.OperationID] = opETag
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId":    orderID,
+		"etag":       newETag,
+		"operations": len(ops),
+	}).Info("SAP maintenance order updated successfully")
+
+	return newETag, operationETags, nil
+}
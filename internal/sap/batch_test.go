@@ -0,0 +1,154 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchSendsChangesetsAndMapsResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/API_MAINTENANCE_ORDER/$batch" {
+			t.Errorf("Expected POST to $batch, got %s %s", r.Method, r.URL.Path)
+		}
+
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		reader := multipart.NewReader(r.Body, params["boundary"])
+
+		read, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("Expected read part, got %v", err)
+		}
+		body, _ := io.ReadAll(read)
+		if !strings.HasPrefix(string(body), "GET A_MaintenanceOrder('4711') HTTP/1.1\r\n") {
+			t.Errorf("Unexpected read request:\n%s", body)
+		}
+
+		changeset, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("Expected changeset part, got %v", err)
+		}
+		_, changesetParams, _ := mime.ParseMediaType(changeset.Header.Get("Content-Type"))
+		inner := multipart.NewReader(changeset, changesetParams["boundary"])
+		for _, expected := range []string{"PATCH A_MaintenanceOrder('4711')", "PATCH A_MaintenanceOrderOperation("} {
+			part, err := inner.NextPart()
+			if err != nil {
+				t.Fatalf("Expected changeset request, got %v", err)
+			}
+			body, _ := io.ReadAll(part)
+			if !strings.HasPrefix(string(body), expected) || !strings.Contains(string(body), "If-Match: W/\"1\"\r\n") {
+				t.Errorf("Unexpected changeset request:\n%s", body)
+			}
+		}
+
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=batchresp")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "--batchresp\r\n"+
+			"Content-Type: application/http\r\nContent-Transfer-Encoding: binary\r\n\r\n"+
+			"HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nETag: W/\"2\"\r\n\r\n"+
+			"{\"d\":{\"MaintenanceOrder\":\"4711\"}}\r\n"+
+			"--batchresp\r\n"+
+			"Content-Type: application/http\r\nContent-Transfer-Encoding: binary\r\n\r\n"+
+			"HTTP/1.1 412 Precondition Failed\r\nContent-Type: application/json\r\n\r\n"+
+			"{\"error\":{\"code\":\"/IWBEP/CM_MGW_RT/020\"}}\r\n"+
+			"--batchresp--\r\n")
+	}))
+	defer server.Close()
+
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, SkipMetadataValidation: true}, logrus.New())
+	ifMatch := map[string]string{"If-Match": `W/"1"`}
+	results, err := client.Batch(context.Background(), orderServicePath, []BatchPart{
+		{Request: &BatchRequest{Method: http.MethodGet, Path: orderPath("4711")}},
+		{Changeset: []BatchRequest{
+			{Method: http.MethodPatch, Path: orderPath("4711"), Headers: ifMatch, Body: &models.SAPOrderUpdateRequest{Priority: "1"}},
+			{Method: http.MethodPatch, Path: operationPath("4711", "10"), Headers: ifMatch, Body: &models.SAPOrderOperationUpdateRequest{}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	var order models.SAPOrderResponse
+	if results[0].Err != nil || results[0].Responses[0].Header.Get("ETag") != `W/"2"` {
+		t.Errorf("Unexpected read result: %+v", results[0])
+	}
+	if err := results[0].Responses[0].Decode(&order); err != nil || order.D.MaintenanceOrder != "4711" {
+		t.Errorf("Expected decoded order 4711, got %+v (%v)", order.D, err)
+	}
+	if !IsPreconditionFailed(results[1].Err) {
+		t.Errorf("Expected rolled back changeset with 412, got %v", results[1].Err)
+	}
+}
+
+func TestUpdateOrderWithOperationsIsAtomicInSimulator(t *testing.T) {
+	client := NewClient(config.SAPConfig{SimulatorMode: true}, logrus.New())
+	ctx := context.Background()
+
+	operationETag, _ := client.GetETag(ctx, operationPath("4711", "0010"))
+	ops := []OperationUpdate{{OperationID: "0010", ETag: operationETag, Request: &models.SAPOrderOperationUpdateRequest{}}}
+
+	_, _, err := client.UpdateOrderWithOperations(ctx, "4711", &models.SAPOrderUpdateRequest{Priority: "1"}, ops, `W/"stale"`)
+	if !IsConflict(err) {
+		t.Fatalf("Expected conflict for stale ETag, got %v", err)
+	}
+	if current, _ := client.GetETag(ctx, operationPath("4711", "0010")); current != operationETag {
+		t.Error("Expected operation to stay unchanged when the changeset fails")
+	}
+
+	orderETag, _ := client.GetOrderETag(ctx, "4711")
+	newETag, operationETags, err := client.UpdateOrderWithOperations(ctx, "4711", &models.SAPOrderUpdateRequest{Priority: "1"}, ops, orderETag)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if newETag == orderETag {
+		t.Error("Expected a new order ETag")
+	}
+	current, _ := client.GetETag(ctx, operationPath("4711", "0010"))
+	if current == operationETag || operationETags["0010"] != current {
+		t.Errorf("Expected the operation to be updated and its new ETag returned, got %v", operationETags)
+	}
+
+	// The ETag the operation was read with is stale now, even with the current order ETag
+	if _, _, err := client.UpdateOrderWithOperations(ctx, "4711", nil, ops, newETag); !IsConflict(err) {
+		t.Fatalf("Expected conflict for a stale operation ETag, got %v", err)
+	}
+
+	// Operation changes alone leave the header untouched, but still require its current ETag
+	ops[0].ETag = current
+	if _, _, err := client.UpdateOrderWithOperations(ctx, "4711", nil, ops, ""); !errors.Is(err, ErrETagRequired) {
+		t.Errorf("Expected ErrETagRequired without order ETag, got %v", err)
+	}
+	if _, _, err := client.UpdateOrderWithOperations(ctx, "4711", nil, []OperationUpdate{{OperationID: "0010", Request: ops[0].Request}}, newETag); !errors.Is(err, ErrETagRequired) {
+		t.Errorf("Expected ErrETagRequired without operation ETag, got %v", err)
+	}
+	if _, _, err := client.UpdateOrderWithOperations(ctx, "4711", nil, ops, orderETag); !IsConflict(err) {
+		t.Fatalf("Expected conflict for the order ETag before the last change, got %v", err)
+	}
+	if unchanged, _ := client.GetETag(ctx, operationPath("4711", "0010")); unchanged != current {
+		t.Error("Expected operation to stay unchanged for a stale order ETag")
+	}
+	orderETag, operationETags, err = client.UpdateOrderWithOperations(ctx, "4711", nil, ops, newETag)
+	if err != nil {
+		t.Fatalf("Expected the current ETags to be accepted, got %v", err)
+	}
+	if orderETag != newETag {
+		t.Error("Expected the order header not to be written without header changes")
+	}
+	if operationETags["0010"] == current {
+		t.Error("Expected a new operation ETag")
+	}
+}
@@ -0,0 +1,99 @@
+package sap
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"sap-adaptor/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Default actions used to withdraw an order and its notification, overridden per destination
+// with sap.actions
+const (
+	defaultOrderDeletionFlagAction    = "SetMaintOrderDeletionFlag"
+	defaultOrderLockAction            = "LockMaintenanceOrder"
+	defaultNotificationCompleteAction = "CompleteMaintNotification"
+)
+
+// Actions returns the names of the withdrawal actions of the destination, with defaults for
+// unset names
+func (c *Client) Actions() config.SAPActions {
+	actions := c.config.Actions
+	if actions.OrderDeletionFlag == "" {
+		actions.OrderDeletionFlag = defaultOrderDeletionFlagAction
+	}
+	if actions.OrderLock == "" {
+		actions.OrderLock = defaultOrderLockAction
+	}
+	if actions.NotificationComplete == "" {
+		actions.NotificationComplete = defaultNotificationCompleteAction
+	}
+	return actions
+}
+
+// SetOrderDeletionFlag marks a maintenance order for deletion
+func (c *Client) SetOrderDeletionFlag(ctx context.Context, orderID string) error {
+	return c.orderAction(ctx, orderID, c.Actions().OrderDeletionFlag)
+}
+
+// LockOrder locks a maintenance order, so no further work can be planned or confirmed on it
+func (c *Client) LockOrder(ctx context.Context, orderID string) error {
+	return c.orderAction(ctx, orderID, c.Actions().OrderLock)
+}
+
+// orderAction calls an action on a maintenance order: a function import in OData v2, a bound
+// action in v4
+func (c *Client) orderAction(ctx context.Context, orderID, action string) error {
+	c.logger.WithFields(logrus.Fields{
+		"orderId":       orderID,
+		"action":        action,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Calling SAP maintenance order action")
+
+	// If in simulator mode, the action always succeeds
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - skipping order action")
+		return nil
+	}
+
+	path := orderServicePath + "/" + action + "?MaintenanceOrder=" + url.QueryEscape("'"+odataEscape(orderID)+"'")
+	if c.odataV4() {
+		path = c.orderEntityPath(orderID) + "/SAP__self." + action
+	}
+	if err := c.doRequest(ctx, http.MethodPost, path, nil, http.StatusOK, nil); err != nil {
+		return err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId": orderID,
+		"action":  action,
+	}).Info("SAP maintenance order action completed successfully")
+
+	return nil
+}
+
+// CompleteNotification completes a maintenance notification
+func (c *Client) CompleteNotification(ctx context.Context, notificationID string) error {
+	c.logger.WithFields(logrus.Fields{
+		"notificationId": notificationID,
+		"simulatorMode":  c.simulatorMode,
+	}).Info("Completing SAP maintenance notification")
+
+	// If in simulator mode, completion always succeeds
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - skipping notification completion")
+		return nil
+	}
+
+	path := notificationServicePath + "/" + c.Actions().NotificationComplete + "?Notification=" + url.QueryEscape("'"+odataEscape(notificationID)+"'")
+	if err := c.doRequest(ctx, http.MethodPost, path, nil, http.StatusOK, nil); err != nil {
+		return err
+	}
+
+	c.logger.WithField("notificationId", notificationID).Info("SAP maintenance notification completed successfully")
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package sap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sap-adaptor/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithdrawalActionsAreConfigurable(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{
+		BaseURL:                server.URL,
+		Timeout:                5,
+		SkipMetadataValidation: true,
+		Actions:                config.SAPActions{OrderLock: "ZZ1_LockOrder", NotificationComplete: "ZZ1_CloseNotification"},
+	}, logger)
+
+	ctx := context.Background()
+	if err := client.LockOrder(ctx, "400000123"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.SetOrderDeletionFlag(ctx, "400000123"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.CompleteNotification(ctx, "200000123"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		orderServicePath + "/ZZ1_LockOrder",
+		orderServicePath + "/" + defaultOrderDeletionFlagAction,
+		notificationServicePath + "/ZZ1_CloseNotification",
+	}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %d requests, got %v", len(expected), paths)
+	}
+	for i, path := range expected {
+		if paths[i] != path {
+			t.Errorf("Expected %s, got %s", path, paths[i])
+		}
+	}
+}
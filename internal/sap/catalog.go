@@ -0,0 +1,163 @@
+package sap
+
+import (
+	"fmt"
+	"strings"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+)
+
+// notificationDescriptionLength is the length of the short text of an SAP notification
+const notificationDescriptionLength = 40
+
+// FaultClassCatalogs returns the configured mapping of fault classes to catalog code groups
+func (c *Client) FaultClassCatalogs() map[string]config.FaultClassCatalog {
+	return c.config.FaultClasses
+}
+
+// lookupFaultClass finds the catalog mapping of a fault class. Viper lower-cases map keys,
+// so the lookup ignores case.
+func lookupFaultClass(catalogs map[string]config.FaultClassCatalog, faultClass string) (config.FaultClassCatalog, bool) {
+	if faultClass == "" {
+		return config.FaultClassCatalog{}, false
+	}
+	if catalog, ok := catalogs[faultClass]; ok {
+		return catalog, true
+	}
+	for class, catalog := range catalogs {
+		if strings.EqualFold(class, faultClass) {
+			return catalog, true
+		}
+	}
+	return config.FaultClassCatalog{}, false
+}
+
+// BuildNotificationLongText assembles the notification long text from the event text and the
+// Digital Twin diagnostics. A description that does not fit the short text is repeated in full.
+func BuildNotificationLongText(event *models.MaintenanceOrderEvent) string {
+	var sections []string
+	if len([]rune(event.Description)) > notificationDescriptionLength {
+		sections = append(sections, event.Description)
+	}
+	if event.LongText != "" {
+		sections = append(sections, event.LongText)
+	}
+
+	if diag := event.Diagnostics; diag != nil {
+		if diag.AnomalyExplanation != "" {
+			sections = append(sections, "Anomaly: "+diag.AnomalyExplanation)
+		}
+		if len(diag.SensorReadings) > 0 {
+			lines := []string{"Sensor readings:"}
+			for _, reading := range diag.SensorReadings {
+				line := fmt.Sprintf("- %s: %g", reading.Sensor, reading.Value)
+				if reading.Unit != "" {
+					line += " " + reading.Unit
+				}
+				if reading.Threshold != nil {
+					line += fmt.Sprintf(" (threshold %g)", *reading.Threshold)
+				}
+				if reading.Timestamp != nil {
+					line += " at " + reading.Timestamp.UTC().Format("2006-01-02 15:04:05") + " UTC"
+				}
+				lines = append(lines, line)
+			}
+			sections = append(sections, strings.Join(lines, "\n"))
+		}
+		if diag.SuggestedRootCause != "" {
+			sections = append(sections, "Suggested root cause: "+diag.SuggestedRootCause)
+		}
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+// ConvertNotificationItemsToSAP converts the event items to SAP notification items. Missing code
+// groups and codes are filled from the fault class mapping. If the event has no items but a mapped
+// fault class, a single item is derived from the mapping and the suggested root cause.
+func ConvertNotificationItemsToSAP(event *models.MaintenanceOrderEvent, catalogs map[string]config.FaultClassCatalog) []models.SAPNotificationItem {
+	items := event.Items
+	if len(items) == 0 {
+		if _, ok := lookupFaultClass(catalogs, event.FaultClass); !ok {
+			return nil
+		}
+		item := models.NotificationItem{Text: truncateText(event.Description, notificationDescriptionLength)}
+		if event.Diagnostics != nil && event.Diagnostics.SuggestedRootCause != "" {
+			item.Causes = []models.NotificationCatalogEntry{{Text: truncateText(event.Diagnostics.SuggestedRootCause, notificationDescriptionLength)}}
+		}
+		items = []models.NotificationItem{item}
+	}
+
+	var sapItems []models.SAPNotificationItem
+	for i, item := range items {
+		faultClass := item.FaultClass
+		if faultClass == "" {
+			faultClass = event.FaultClass
+		}
+		catalog, mapped := lookupFaultClass(catalogs, faultClass)
+
+		sapItem := models.SAPNotificationItem{
+			MaintNotificationItem:       catalogNumber(i),
+			MaintNotifItemText:          item.Text,
+			MaintNotifObjPrtCodeGroup:   firstNonEmpty(item.ObjectPartCodeGroup, catalog.ObjectPartCodeGroup),
+			MaintNotifObjPrtCode:        firstNonEmpty(item.ObjectPartCode, catalog.ObjectPartCode),
+			MaintNotifDamageCodeGroup:   firstNonEmpty(item.DamageCodeGroup, catalog.DamageCodeGroup),
+			MaintNotificationDamageCode: firstNonEmpty(item.DamageCode, catalog.DamageCode),
+		}
+
+		causes := item.Causes
+		if len(causes) == 0 && mapped && catalog.CauseCode != "" {
+			causes = []models.NotificationCatalogEntry{{}}
+		}
+		for j, cause := range causes {
+			sapItem.ToItemCause = append(sapItem.ToItemCause, models.SAPNotificationItemCause{
+				MaintNotificationCause:     catalogNumber(j),
+				MaintNotifCauseText:        cause.Text,
+				MaintNotifCauseCodeGroup:   firstNonEmpty(cause.CodeGroup, catalog.CauseCodeGroup),
+				MaintNotificationCauseCode: firstNonEmpty(cause.Code, catalog.CauseCode),
+			})
+		}
+
+		activities := item.Activities
+		if len(activities) == 0 && mapped && catalog.ActivityCode != "" {
+			activities = []models.NotificationCatalogEntry{{}}
+		}
+		for j, activity := range activities {
+			sapItem.ToItemActivity = append(sapItem.ToItemActivity, models.SAPNotificationItemActivity{
+				MaintNotificationActivity:     catalogNumber(j),
+				MaintNotifActivityText:        activity.Text,
+				MaintNotifActivityCodeGroup:   firstNonEmpty(activity.CodeGroup, catalog.ActivityCodeGroup),
+				MaintNotificationActivityCode: firstNonEmpty(activity.Code, catalog.ActivityCode),
+			})
+		}
+
+		sapItems = append(sapItems, sapItem)
+	}
+
+	return sapItems
+}
+
+// catalogNumber returns the SAP sort number of the i-th item, cause or activity
+func catalogNumber(i int) string {
+	return fmt.Sprintf("%04d", i+1)
+}
+
+// truncateText shortens text to at most n runes
+func truncateText(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
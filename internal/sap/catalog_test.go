@@ -0,0 +1,79 @@
+package sap
+
+import (
+	"strings"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+)
+
+func TestConvertNotificationRequestWithCatalogs(t *testing.T) {
+	catalogs := map[string]config.FaultClassCatalog{
+		"bearing_wear": {
+			ObjectPartCodeGroup: "PM-BRG",
+			ObjectPartCode:      "0010",
+			DamageCodeGroup:     "PM-DMG",
+			DamageCode:          "WEAR",
+			CauseCodeGroup:      "PM-CAUS",
+			CauseCode:           "LUBR",
+		},
+	}
+	threshold := 7.1
+	event := &models.MaintenanceOrderEvent{
+		EquipmentID: "10000045",
+		Plant:       "1000",
+		Description: "Pump P-101 bearing vibration above limit on drive end",
+		FaultClass:  "Bearing_Wear",
+		Diagnostics: &models.FaultDiagnostics{
+			AnomalyExplanation: "Vibration trend rising for 3 days",
+			SensorReadings:     []models.SensorReading{{Sensor: "VIB-DE", Value: 9.4, Unit: "mm/s", Threshold: &threshold}},
+			SuggestedRootCause: "Insufficient lubrication",
+		},
+	}
+
+	req := ConvertMaintenanceOrderEventToNotificationRequest(event, catalogs, nil)
+
+	if len(req.Description) != notificationDescriptionLength {
+		t.Errorf("Expected description truncated to %d characters, got %q", notificationDescriptionLength, req.Description)
+	}
+	for _, expected := range []string{event.Description, "Anomaly: Vibration trend rising", "- VIB-DE: 9.4 mm/s (threshold 7.1)", "Suggested root cause: Insufficient lubrication"} {
+		if !strings.Contains(req.NotificationText, expected) {
+			t.Errorf("Expected long text to contain %q, got %q", expected, req.NotificationText)
+		}
+	}
+
+	if len(req.ToItem) != 1 {
+		t.Fatalf("Expected one derived item, got %d", len(req.ToItem))
+	}
+	item := req.ToItem[0]
+	if item.MaintNotifObjPrtCodeGroup != "PM-BRG" || item.MaintNotificationDamageCode != "WEAR" {
+		t.Errorf("Expected catalog codes from fault class mapping, got %+v", item)
+	}
+	if len(item.ToItemCause) != 1 || item.ToItemCause[0].MaintNotificationCauseCode != "LUBR" || item.ToItemCause[0].MaintNotifCauseText != "Insufficient lubrication" {
+		t.Errorf("Expected mapped cause with root cause text, got %+v", item.ToItemCause)
+	}
+	if len(item.ToItemActivity) != 0 {
+		t.Errorf("Expected no activity without an activity code, got %+v", item.ToItemActivity)
+	}
+}
+
+func TestConvertNotificationItemsKeepsExplicitCodes(t *testing.T) {
+	event := &models.MaintenanceOrderEvent{
+		Items: []models.NotificationItem{{
+			Text:           "Seal leaking",
+			ObjectPartCode: "0030",
+			DamageCode:     "LEAK",
+			Activities:     []models.NotificationCatalogEntry{{CodeGroup: "PM-ACT", Code: "REPL"}},
+		}},
+	}
+
+	items := ConvertNotificationItemsToSAP(event, nil)
+
+	if len(items) != 1 || items[0].MaintNotifObjPrtCode != "0030" || items[0].MaintNotifDamageCodeGroup != "" {
+		t.Fatalf("Expected explicit codes without mapping, got %+v", items)
+	}
+	if len(items[0].ToItemActivity) != 1 || items[0].ToItemActivity[0].MaintNotificationActivity != "0001" {
+		t.Errorf("Expected numbered activity, got %+v", items[0].ToItemActivity)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 
 	"sap-adaptor/internal/config"
@@ -23,19 +24,66 @@ type Client struct {
 	httpClient *http.Client
 	logger     *logrus.Logger
 	simulatorMode bool
+
+	// mockMu guards the in-memory state kept by the simulator
+	mockMu            sync.Mutex
+	mockConfirmations map[string][]models.SAPConfirmation
+	mockETags         map[string]string
+
+	// referenceData holds simulator master data loaded from a local file (optional)
+	referenceData *referenceData
+
+	// fieldMapping converts events to SAP payloads; nil uses the default mapping
+	fieldMapping *FieldMapping
+
+	// metadata caches the $metadata of the OData services, keyed by service path
+	metadataMu sync.Mutex
+	metadata   map[string]*metadataEntry
 }
 
 // NewClient creates a new SAP client
 func NewClient(cfg config.SAPConfig, logger *logrus.Logger) *Client {
 	simulatorMode := cfg.SimulatorMode || cfg.BaseURL == "" || cfg.BaseURL == "simulator"
-	
+	if cfg.Name == "" {
+		cfg.Name = DefaultDestination
+	}
+
+	var refData *referenceData
+	if simulatorMode && cfg.ReferenceDataFile != "" {
+		var err error
+		if refData, err = newReferenceData(cfg.ReferenceDataFile, logger); err != nil {
+			logger.WithError(err).Error("Failed to load simulator reference data, accepting all work centers and functional locations")
+		}
+	}
+
+	var fieldMapping *FieldMapping
+	if cfg.MappingFile != "" {
+		var err error
+		if fieldMapping, err = watchFieldMapping(cfg.MappingFile, logger); err != nil {
+			logger.WithError(err).WithField("destination", cfg.Name).Error("Failed to load field mapping, using the default mapping until the file is valid")
+		}
+	}
+
+	httpClient, err := newHTTPClient(cfg, logger)
+	if err == nil {
+		err = validateODataVersion(cfg.ODataVersion)
+	}
+	if err != nil {
+		// Keep the client usable for simulator mode; real requests report the configuration error
+		logger.WithError(err).WithField("destination", cfg.Name).Error("Invalid SAP connection settings")
+		httpClient = &http.Client{Transport: errorTransport{err: err}}
+	}
+
 	return &Client{
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: time.Duration(cfg.Timeout) * time.Second,
-		},
-		logger: logger,
+		config:     cfg,
+		httpClient: httpClient,
+		logger:     logger,
 		simulatorMode: simulatorMode,
+		mockConfirmations: make(map[string][]models.SAPConfirmation),
+		mockETags:         make(map[string]string),
+		referenceData:     refData,
+		fieldMapping:      fieldMapping,
+		metadata:          make(map[string]*metadataEntry),
 	}
 }
 
@@ -53,6 +101,10 @@ func (c *Client) CreateNotification(ctx context.Context, req *models.SAPNotifica
 		return c.createMockNotificationResponse(req), nil
 	}
 
+	if err := c.validatePayload(ctx, notificationServicePath+"/A_MaintenanceNotification", req, true); err != nil {
+		return nil, err
+	}
+
 	// Prepare request
 	reqBody, err := json.Marshal(req)
 	if err != nil {
@@ -60,8 +112,8 @@ func (c *Client) CreateNotification(ctx context.Context, req *models.SAPNotifica
 	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", 
-		c.config.BaseURL+"/API_MAINTENANCE_NOTIFICATION/A_MaintenanceNotification", 
+	httpReq, err := c.newRequest(ctx, "POST",
+		"/API_MAINTENANCE_NOTIFICATION/A_MaintenanceNotification",
 		bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -89,7 +141,7 @@ func (c *Client) CreateNotification(ctx context.Context, req *models.SAPNotifica
 			"status": resp.StatusCode,
 			"body":   string(respBody),
 		}).Error("SAP notification creation failed")
-		return nil, fmt.Errorf("SAP API returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	// Parse response
@@ -120,6 +172,14 @@ func (c *Client) CreateOrder(ctx context.Context, req *models.SAPOrderRequest) (
 		return c.createMockOrderResponse(req), nil
 	}
 
+	if c.odataV4() {
+		return c.createOrderV4(ctx, req)
+	}
+
+	if err := c.validatePayload(ctx, orderServicePath+"/A_MaintenanceOrder", req, true); err != nil {
+		return nil, err
+	}
+
 	// Prepare request
 	reqBody, err := json.Marshal(req)
 	if err != nil {
@@ -127,8 +187,8 @@ func (c *Client) CreateOrder(ctx context.Context, req *models.SAPOrderRequest) (
 	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", 
-		c.config.BaseURL+"/API_MAINTENANCE_ORDER/A_MaintenanceOrder", 
+	httpReq, err := c.newRequest(ctx, "POST",
+		"/API_MAINTENANCE_ORDER/A_MaintenanceOrder",
 		bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -156,7 +216,7 @@ func (c *Client) CreateOrder(ctx context.Context, req *models.SAPOrderRequest) (
 			"status": resp.StatusCode,
 			"body":   string(respBody),
 		}).Error("SAP order creation failed")
-		return nil, fmt.Errorf("SAP API returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	// Parse response
@@ -185,14 +245,18 @@ func (c *Client) GetOrder(ctx context.Context, orderID string) (*models.SAPOrder
 		return c.createMockOrderStatusResponse(orderID), nil
 	}
 
+	if c.odataV4() {
+		return c.getOrderV4(ctx, orderID)
+	}
+
 	// Create URL with expand parameter
-	baseURL := c.config.BaseURL + "/API_MAINTENANCE_ORDER/A_MaintenanceOrder('" + orderID + "')"
+	basePath := orderPath(orderID)
 	params := url.Values{}
-	params.Add("$expand", "to_MaintenanceOrderOperation")
-	fullURL := baseURL + "?" + params.Encode()
+	params.Add("$expand", "to_MaintenanceOrderOperation,to_MaintenanceOrderOperation/to_MaintenanceOrderComponent")
+	fullPath := basePath + "?" + params.Encode()
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	httpReq, err := c.newRequest(ctx, "GET", fullPath, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -218,7 +282,7 @@ func (c *Client) GetOrder(ctx context.Context, orderID string) (*models.SAPOrder
 			"status": resp.StatusCode,
 			"body":   string(respBody),
 		}).Error("SAP order retrieval failed")
-		return nil, fmt.Errorf("SAP API returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	// Parse response
@@ -235,6 +299,75 @@ func (c *Client) GetOrder(ctx context.Context, orderID string) (*models.SAPOrder
 	return &orderResp, nil
 }
 
+// doRequest sends a JSON request to SAP and decodes the response body into out
+func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}, expectedStatus int, out interface{}) error {
+	_, err := c.doRequestWithHeaders(ctx, method, path, nil, body, expectedStatus, out)
+	return err
+}
+
+// doRequestWithHeaders is like doRequest but sends additional headers and returns the response headers
+func (c *Client) doRequestWithHeaders(ctx context.Context, method, path string, headers map[string]string, body interface{}, expectedStatus int, out interface{}) (http.Header, error) {
+	if err := c.validatePayload(ctx, path, body, method == http.MethodPost); err != nil {
+		return nil, err
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(payload)
+	}
+
+	// Create HTTP request
+	httpReq, err := c.newRequest(ctx, method, path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers (no authentication in simulator mode)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	// Send request
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read response
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != expectedStatus {
+		c.logger.WithFields(logrus.Fields{
+			"method": method,
+			"path":   path,
+			"status": resp.StatusCode,
+			"body":   string(respBody),
+		}).Error("SAP request failed")
+		return resp.Header, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	// Parse response
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+
+	return resp.Header, nil
+}
+
 // createMockNotificationResponse creates a mock notification response for simulator mode
 func (c *Client) createMockNotificationResponse(req *models.SAPNotificationRequest) *models.SAPNotificationResponse {
 	// Generate a mock notification ID
@@ -261,7 +394,7 @@ func (c *Client) createMockOrderResponse(req *models.SAPOrderRequest) *models.SA
 	// Create mock operations
 	var operations []models.SAPOrderOperationResponse
 	for i, op := range req.ToMaintenanceOrderOperation {
-		operationID := fmt.Sprintf("%04d", (i+1)*10)
+		operationID := models.OperationNumberForIndex(op.MaintenanceOrderOperation, i)
 		operations = append(operations, models.SAPOrderOperationResponse{
 			MaintenanceOrder:                orderID,
 			MaintenanceOrderOperation:       operationID,
@@ -270,10 +403,12 @@ func (c *Client) createMockOrderResponse(req *models.SAPOrderRequest) *models.SA
 			OperationControlKey:             op.OperationControlKey,
 			OperationStandardDuration:       op.OperationStandardDuration,
 			OperationDurationUnit:           op.OperationDurationUnit,
+			ToMaintenanceOrderComponent:     createMockComponents(orderID, operationID, op.ToMaintenanceOrderComponent),
 			Metadata: struct {
 				ID  string `json:"id"`
 				URI string `json:"uri"`
 				Type string `json:"type"`
+				ETag string `json:"etag,omitempty"`
 			}{
 				ID:   fmt.Sprintf(".../A_MaintenanceOrderOperation(MaintenanceOrder='%s',MaintenanceOrderOperation='%s')", orderID, operationID),
 				URI:  fmt.Sprintf(".../A_MaintenanceOrderOperation(MaintenanceOrder='%s',MaintenanceOrderOperation='%s')", orderID, operationID),
@@ -288,7 +423,9 @@ func (c *Client) createMockOrderResponse(req *models.SAPOrderRequest) *models.SA
 			MaintenanceOrderType            string `json:"MaintenanceOrderType"`
 			Description                     string `json:"Description"`
 			Equipment                       string `json:"Equipment"`
+			FunctionalLocation              string `json:"FunctionalLocation"`
 			Plant                           string `json:"Plant"`
+			Priority                        string `json:"Priority"`
 			OrderStatus                     string `json:"OrderStatus"`
 			MaintOrdBasicStartDateTime      string `json:"MaintOrdBasicStartDateTime"`
 			MaintOrdBasicEndDateTime        string `json:"MaintOrdBasicEndDateTime"`
@@ -297,16 +434,20 @@ func (c *Client) createMockOrderResponse(req *models.SAPOrderRequest) *models.SA
 				ID  string `json:"id"`
 				URI string `json:"uri"`
 				Type string `json:"type"`
+				ETag string `json:"etag,omitempty"`
 			} `json:"__metadata"`
 			ToMaintenanceOrderOperation struct {
 				Results []models.SAPOrderOperationResponse `json:"results"`
 			} `json:"to_MaintenanceOrderOperation"`
+			Extensions map[string]interface{} `json:"-"`
 		}{
 			MaintenanceOrder:                orderID,
 			MaintenanceOrderType:            req.MaintenanceOrderType,
 			Description:                     req.Description,
 			Equipment:                       req.Equipment,
+			FunctionalLocation:              req.FunctionalLocation,
 			Plant:                           req.Plant,
+			Priority:                        req.Priority,
 			OrderStatus:                     "CRTD", // Created status
 			MaintOrdBasicStartDateTime:      req.MaintOrdBasicStartDateTime,
 			MaintOrdBasicEndDateTime:        req.MaintOrdBasicEndDateTime,
@@ -315,6 +456,7 @@ func (c *Client) createMockOrderResponse(req *models.SAPOrderRequest) *models.SA
 				ID  string `json:"id"`
 				URI string `json:"uri"`
 				Type string `json:"type"`
+				ETag string `json:"etag,omitempty"`
 			}{
 				ID:   fmt.Sprintf(".../A_MaintenanceOrder('%s')", orderID),
 				URI:  fmt.Sprintf(".../A_MaintenanceOrder('%s')", orderID),
@@ -325,10 +467,32 @@ func (c *Client) createMockOrderResponse(req *models.SAPOrderRequest) *models.SA
 			}{
 				Results: operations,
 			},
+			Extensions: req.Extensions,
 		},
 	}
 }
 
+// createMockComponents creates mock reservations for the components of an operation
+func createMockComponents(orderID, operationID string, components []models.SAPOrderComponent) models.SAPOrderComponentList {
+	list := models.SAPOrderComponentList{}
+	for i, comp := range components {
+		itemID := fmt.Sprintf("%04d", (i+1)*10)
+		list.Results = append(list.Results, models.SAPOrderComponentResponse{
+			MaintenanceOrder:              orderID,
+			MaintenanceOrderOperation:     operationID,
+			MaintenanceOrderComponent:     itemID,
+			Material:                      comp.Material,
+			RequirementQuantityInBaseUnit: comp.RequirementQuantityInBaseUnit,
+			WithdrawnQuantity:             "0",
+			BaseUnit:                      comp.BaseUnit,
+			StorageLocation:               comp.StorageLocation,
+			Reservation:                   "00" + orderID,
+			ReservationItem:               fmt.Sprintf("%04d", i+1),
+		})
+	}
+	return list
+}
+
 // createMockOrderStatusResponse creates a mock order status response for simulator mode
 func (c *Client) createMockOrderStatusResponse(orderID string) *models.SAPOrderResponse {
 	// Simulate different statuses based on order ID
@@ -348,13 +512,21 @@ func (c *Client) createMockOrderStatusResponse(orderID string) *models.SAPOrderR
 		}
 	}
 	
+	// Spare parts are withdrawn once the order is released
+	withdrawnQuantity := "0"
+	if status != "CRTD" {
+		withdrawnQuantity = "1"
+	}
+	
 	return &models.SAPOrderResponse{
 		D: struct {
 			MaintenanceOrder                string `json:"MaintenanceOrder"`
 			MaintenanceOrderType            string `json:"MaintenanceOrderType"`
 			Description                     string `json:"Description"`
 			Equipment                       string `json:"Equipment"`
+			FunctionalLocation              string `json:"FunctionalLocation"`
 			Plant                           string `json:"Plant"`
+			Priority                        string `json:"Priority"`
 			OrderStatus                     string `json:"OrderStatus"`
 			MaintOrdBasicStartDateTime      string `json:"MaintOrdBasicStartDateTime"`
 			MaintOrdBasicEndDateTime        string `json:"MaintOrdBasicEndDateTime"`
@@ -363,16 +535,20 @@ func (c *Client) createMockOrderStatusResponse(orderID string) *models.SAPOrderR
 				ID  string `json:"id"`
 				URI string `json:"uri"`
 				Type string `json:"type"`
+				ETag string `json:"etag,omitempty"`
 			} `json:"__metadata"`
 			ToMaintenanceOrderOperation struct {
 				Results []models.SAPOrderOperationResponse `json:"results"`
 			} `json:"to_MaintenanceOrderOperation"`
+			Extensions map[string]interface{} `json:"-"`
 		}{
 			MaintenanceOrder:                orderID,
 			MaintenanceOrderType:            "PM01",
 			Description:                     "Mock maintenance order",
 			Equipment:                       "10000045",
+			FunctionalLocation:              "FL100-200-300",
 			Plant:                           "1000",
+			Priority:                        "3",
 			OrderStatus:                     status,
 			MaintOrdBasicStartDateTime:      time.Now().Format(time.RFC3339),
 			MaintOrdBasicEndDateTime:        time.Now().Add(8 * time.Hour).Format(time.RFC3339),
@@ -381,10 +557,12 @@ func (c *Client) createMockOrderStatusResponse(orderID string) *models.SAPOrderR
 				ID  string `json:"id"`
 				URI string `json:"uri"`
 				Type string `json:"type"`
+				ETag string `json:"etag,omitempty"`
 			}{
 				ID:   fmt.Sprintf(".../A_MaintenanceOrder('%s')", orderID),
 				URI:  fmt.Sprintf(".../A_MaintenanceOrder('%s')", orderID),
 				Type: "API_MAINTENANCE_ORDER.A_MaintenanceOrderType",
+				ETag: c.mockETag(c.orderEntityPath(orderID)),
 			},
 			ToMaintenanceOrderOperation: struct {
 				Results []models.SAPOrderOperationResponse `json:"results"`
@@ -401,14 +579,32 @@ func (c *Client) createMockOrderStatusResponse(orderID string) *models.SAPOrderR
 						OperationStatus:                 "CNF",
 						ActualWorkQuantity:              "4.0",
 						WorkQuantityUnit:                "H",
+						ToMaintenanceOrderComponent: models.SAPOrderComponentList{
+							Results: []models.SAPOrderComponentResponse{
+								{
+									MaintenanceOrder:              orderID,
+									MaintenanceOrderOperation:     "0010",
+									MaintenanceOrderComponent:     "0010",
+									Material:                      "SEAL-KIT-100",
+									RequirementQuantityInBaseUnit: "1",
+									WithdrawnQuantity:             withdrawnQuantity,
+									BaseUnit:                      "EA",
+									StorageLocation:               "0001",
+									Reservation:                   "00" + orderID,
+									ReservationItem:               "0001",
+								},
+							},
+						},
 						Metadata: struct {
 							ID  string `json:"id"`
 							URI string `json:"uri"`
 							Type string `json:"type"`
+							ETag string `json:"etag,omitempty"`
 						}{
 							ID:   fmt.Sprintf(".../A_MaintenanceOrderOperation(MaintenanceOrder='%s',MaintenanceOrderOperation='0010')", orderID),
 							URI:  fmt.Sprintf(".../A_MaintenanceOrderOperation(MaintenanceOrder='%s',MaintenanceOrderOperation='0010')", orderID),
 							Type: "API_MAINTENANCE_ORDER.A_MaintenanceOrderOperationType",
+							ETag: c.mockETag(c.operationEntityPath(orderID, "0010")),
 						},
 					},
 				},
@@ -417,30 +613,34 @@ func (c *Client) createMockOrderStatusResponse(orderID string) *models.SAPOrderR
 	}
 }
 
-// ConvertMaintenanceOrderEventToNotificationRequest converts a MaintenanceOrderEvent to SAP notification request
-func ConvertMaintenanceOrderEventToNotificationRequest(event *models.MaintenanceOrderEvent) *models.SAPNotificationRequest {
-	return &models.SAPNotificationRequest{
-		NotificationType:   "M1", // Default notification type
-		Description:        event.Description,
-		Equipment:          event.EquipmentID,
-		FunctionalLocation: event.FunctionalLocation,
-		Plant:              event.Plant,
-		Priority:           event.Priority,
+// ConvertMaintenanceOrderEventToNotificationRequest converts a MaintenanceOrderEvent to SAP notification request.
+// Fault classes are mapped to catalog code groups using catalogs (may be nil); header fields are
+// set by mapping (nil uses the default mapping).
+func ConvertMaintenanceOrderEventToNotificationRequest(event *models.MaintenanceOrderEvent, catalogs map[string]config.FaultClassCatalog, mapping *FieldMapping) *models.SAPNotificationRequest {
+	req := &models.SAPNotificationRequest{
+		NotificationText: BuildNotificationLongText(event),
+		ToItem:           ConvertNotificationItemsToSAP(event, catalogs),
 	}
+	mapping.ApplyNotification(event, req)
+	req.Description = truncateText(req.Description, notificationDescriptionLength)
+
+	// The planned window of the event is the required window of the notification
+	if event.PlannedStartTime != nil {
+		req.RequiredStartDate = event.PlannedStartTime.Format(time.RFC3339)
+	}
+	if event.PlannedEndTime != nil {
+		req.RequiredEndDate = event.PlannedEndTime.Format(time.RFC3339)
+	}
+	return req
 }
 
-// ConvertMaintenanceOrderEventToOrderRequest converts a MaintenanceOrderEvent to SAP order request
-func ConvertMaintenanceOrderEventToOrderRequest(event *models.MaintenanceOrderEvent, notificationID string) *models.SAPOrderRequest {
+// ConvertMaintenanceOrderEventToOrderRequest converts a MaintenanceOrderEvent to SAP order request.
+// Header and operation fields are set by mapping (nil uses the default mapping).
+func ConvertMaintenanceOrderEventToOrderRequest(event *models.MaintenanceOrderEvent, notificationID string, mapping *FieldMapping) *models.SAPOrderRequest {
 	req := &models.SAPOrderRequest{
-		MaintenanceOrderType:    event.MaintenanceOrderType,
-		Description:             event.Description,
-		Equipment:               event.EquipmentID,
-		FunctionalLocation:      event.FunctionalLocation,
-		Plant:                   event.Plant,
-		MaintenancePlanningPlant: event.Plant, // Default to same plant
-		Priority:                event.Priority,
 		MaintenanceNotification: notificationID,
 	}
+	mapping.ApplyOrder(event, req)
 
 	// Add time fields if provided
 	if event.PlannedStartTime != nil {
@@ -450,31 +650,86 @@ func ConvertMaintenanceOrderEventToOrderRequest(event *models.MaintenanceOrderEv
 		req.MaintOrdBasicEndDateTime = event.PlannedEndTime.Format(time.RFC3339)
 	}
 
+	// SAP copies the operations of a referenced task list into the order
+	if event.TaskList != nil {
+		req.TaskListType = event.TaskList.TaskListType
+		req.TaskListGroup = event.TaskList.TaskListGroup
+		req.TaskListGroupCounter = event.TaskList.TaskListGroupCounter
+	}
+
 	// Convert operations
-	for _, op := range event.Operations {
+	for i := range event.Operations {
+		op := &event.Operations[i]
 		sapOp := models.SAPOrderOperation{
-			OperationText:             op.Text,
-			WorkCenter:                op.WorkCenter,
-			Plant:                     event.Plant,
-			OperationControlKey:       event.MaintenanceOrderType,
+			MaintenanceOrderOperation: models.OperationNumberForIndex(op.OperationID, i),
 			OperationStandardDuration: strconv.FormatFloat(op.Duration, 'f', -1, 64),
-			OperationDurationUnit:     op.DurationUnit,
 		}
+		mapping.ApplyOperation(event, op, &sapOp)
 		req.ToMaintenanceOrderOperation = append(req.ToMaintenanceOrderOperation, sapOp)
 	}
 
+	// Attach material components to their operations
+	AssignComponentsToOperations(event, req, mapping)
+
 	return req
 }
 
+// AssignComponentsToOperations adds the event components to their operations in the order request.
+// Components without an operation go to the first operation; if the event has no operations a
+// default operation with the order description is created, as SAP does for orders without operations.
+func AssignComponentsToOperations(event *models.MaintenanceOrderEvent, req *models.SAPOrderRequest, mapping *FieldMapping) {
+	if len(event.Components) == 0 {
+		return
+	}
+	if len(req.ToMaintenanceOrderOperation) == 0 {
+		sapOp := models.SAPOrderOperation{MaintenanceOrderOperation: models.OperationNumberForIndex("", 0)}
+		mapping.ApplyOperation(event, &models.MaintenanceOperation{Text: event.Description}, &sapOp)
+		req.ToMaintenanceOrderOperation = append(req.ToMaintenanceOrderOperation, sapOp)
+	}
+
+	for _, comp := range event.Components {
+		target := 0
+		if comp.OperationID != "" {
+			for i, op := range req.ToMaintenanceOrderOperation {
+				if op.MaintenanceOrderOperation == models.NormalizeOperationID(comp.OperationID) {
+					target = i
+					break
+				}
+			}
+		}
+		req.ToMaintenanceOrderOperation[target].ToMaintenanceOrderComponent = append(
+			req.ToMaintenanceOrderOperation[target].ToMaintenanceOrderComponent,
+			ConvertMaintenanceComponentToSAP(&comp, event.Plant))
+	}
+}
+
+// ConvertMaintenanceComponentToSAP converts a component to an SAP order component
+func ConvertMaintenanceComponentToSAP(comp *models.MaintenanceComponent, plant string) models.SAPOrderComponent {
+	unit := comp.Unit
+	if unit == "" {
+		unit = "EA" // Default base unit: each
+	}
+	return models.SAPOrderComponent{
+		Material:                      comp.Material,
+		RequirementQuantityInBaseUnit: strconv.FormatFloat(comp.Quantity, 'f', -1, 64),
+		BaseUnit:                      unit,
+		Plant:                         plant,
+		StorageLocation:               comp.StorageLocation,
+	}
+}
+
 // ConvertSAPOrderResponseToStatus converts SAP order response to MaintenanceOrderStatus
 func ConvertSAPOrderResponseToStatus(resp *models.SAPOrderResponse) *models.MaintenanceOrderStatus {
 	status := &models.MaintenanceOrderStatus{
-		OrderID:        resp.D.MaintenanceOrder,
-		Status:         resp.D.OrderStatus,
-		Description:    resp.D.Description,
-		EquipmentID:    resp.D.Equipment,
-		Plant:          resp.D.Plant,
-		NotificationID: resp.D.MaintenanceNotification,
+		OrderID:            resp.D.MaintenanceOrder,
+		Status:             resp.D.OrderStatus,
+		Description:        resp.D.Description,
+		EquipmentID:        resp.D.Equipment,
+		FunctionalLocation: resp.D.FunctionalLocation,
+		Plant:              resp.D.Plant,
+		Priority:           resp.D.Priority,
+		NotificationID:     resp.D.MaintenanceNotification,
+		ETag:               resp.D.Metadata.ETag,
 	}
 
 	// Parse time fields if provided
@@ -490,20 +745,54 @@ func ConvertSAPOrderResponseToStatus(resp *models.SAPOrderResponse) *models.Main
 	}
 
 	// Convert operations
-	for _, op := range resp.D.ToMaintenanceOrderOperation.Results {
-		opStatus := models.OperationStatus{
-			OperationID:      op.MaintenanceOrderOperation,
-			Text:             op.OperationText,
-			Status:           op.OperationStatus,
-			WorkQuantityUnit: op.WorkQuantityUnit,
+	for i := range resp.D.ToMaintenanceOrderOperation.Results {
+		op := &resp.D.ToMaintenanceOrderOperation.Results[i]
+		status.Operations = append(status.Operations, *ConvertSAPOperationToStatus(op))
+		for j := range op.ToMaintenanceOrderComponent.Results {
+			status.Components = append(status.Components, *ConvertSAPComponentToStatus(&op.ToMaintenanceOrderComponent.Results[j]))
 		}
-		if op.ActualWorkQuantity != "" {
-			if qty, err := strconv.ParseFloat(op.ActualWorkQuantity, 64); err == nil {
-				opStatus.ActualWorkQuantity = qty
-			}
-		}
-		status.Operations = append(status.Operations, opStatus)
 	}
 
 	return status
 }
+
+// ConvertSAPOperationToStatus converts an SAP order operation to OperationStatus
+func ConvertSAPOperationToStatus(op *models.SAPOrderOperationResponse) *models.OperationStatus {
+	opStatus := &models.OperationStatus{
+		OperationID:      op.MaintenanceOrderOperation,
+		Text:             op.OperationText,
+		Status:           op.OperationStatus,
+		WorkCenter:       op.WorkCenter,
+		ControlKey:       op.OperationControlKey,
+		WorkQuantityUnit: op.WorkQuantityUnit,
+		ETag:             op.Metadata.ETag,
+	}
+	if op.ActualWorkQuantity != "" {
+		if qty, err := strconv.ParseFloat(op.ActualWorkQuantity, 64); err == nil {
+			opStatus.ActualWorkQuantity = qty
+		}
+	}
+	return opStatus
+}
+
+// ConvertSAPComponentToStatus converts an SAP order component to ComponentStatus
+func ConvertSAPComponentToStatus(comp *models.SAPOrderComponentResponse) *models.ComponentStatus {
+	compStatus := &models.ComponentStatus{
+		ComponentID:     comp.MaintenanceOrderComponent,
+		OperationID:     comp.MaintenanceOrderOperation,
+		Material:        comp.Material,
+		Unit:            comp.BaseUnit,
+		StorageLocation: comp.StorageLocation,
+		Reservation:     comp.Reservation,
+		ReservationItem: comp.ReservationItem,
+	}
+	if qty, err := strconv.ParseFloat(comp.RequirementQuantityInBaseUnit, 64); err == nil {
+		compStatus.RequiredQuantity = qty
+	}
+	if comp.WithdrawnQuantity != "" {
+		if qty, err := strconv.ParseFloat(comp.WithdrawnQuantity, 64); err == nil {
+			compStatus.WithdrawnQuantity = qty
+		}
+	}
+	return compStatus
+}
@@ -0,0 +1,230 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const confirmationServicePath = "/API_MAINTORDERCONFIRMATION"
+
+// CreateConfirmation posts a time confirmation for an order operation to SAP
+func (c *Client) CreateConfirmation(ctx context.Context, req *models.SAPConfirmationRequest) (*models.SAPConfirmationResponse, error) {
+	c.logger.WithFields(logrus.Fields{
+		"orderId":       req.MaintenanceOrder,
+		"operationId":   req.MaintenanceOrderOperation,
+		"actualWork":    req.ActualWorkQuantity,
+		"final":         req.IsFinalConfirmation,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Creating SAP order confirmation")
+
+	// If in simulator mode, return mock response
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - returning mock confirmation response")
+		return c.createMockConfirmationResponse(req), nil
+	}
+
+	var confirmationResp models.SAPConfirmationResponse
+	if err := c.doRequest(ctx, http.MethodPost, confirmationServicePath+"/MaintOrderConfirmation", req, http.StatusCreated, &confirmationResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"confirmationId": confirmationResp.D.MaintOrderConf,
+		"counter":        confirmationResp.D.MaintOrderConfCntrValue,
+	}).Info("SAP order confirmation created successfully")
+
+	return &confirmationResp, nil
+}
+
+// CancelConfirmation reverses a previously posted confirmation in SAP
+func (c *Client) CancelConfirmation(ctx context.Context, confirmationID, counter string) (*models.SAPConfirmationResponse, error) {
+	c.logger.WithFields(logrus.Fields{
+		"confirmationId": confirmationID,
+		"counter":        counter,
+		"simulatorMode":  c.simulatorMode,
+	}).Info("Cancelling SAP order confirmation")
+
+	// If in simulator mode, reverse the stored mock confirmation
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - cancelling mock confirmation")
+		return c.cancelMockConfirmation(confirmationID, counter)
+	}
+
+	params := url.Values{}
+	params.Add("MaintOrderConf", "'"+confirmationID+"'")
+	params.Add("MaintOrderConfCntrValue", "'"+counter+"'")
+
+	var confirmationResp models.SAPConfirmationResponse
+	if err := c.doRequest(ctx, http.MethodPost, confirmationServicePath+"/CancelMaintOrderConf?"+params.Encode(), nil, http.StatusOK, &confirmationResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.WithField("confirmationId", confirmationID).Info("SAP order confirmation cancelled successfully")
+
+	return &confirmationResp, nil
+}
+
+// GetOrderConfirmations retrieves all confirmations posted against a maintenance order
+func (c *Client) GetOrderConfirmations(ctx context.Context, orderID string) ([]models.SAPConfirmation, error) {
+	if c.simulatorMode {
+		c.mockMu.Lock()
+		defer c.mockMu.Unlock()
+		return append([]models.SAPConfirmation(nil), c.mockConfirmations[orderID]...), nil
+	}
+
+	params := url.Values{}
+	params.Add("$filter", "MaintenanceOrder eq '"+orderID+"'")
+
+	var listResp models.SAPConfirmationListResponse
+	if err := c.doRequest(ctx, http.MethodGet, confirmationServicePath+"/MaintOrderConfirmation?"+params.Encode(), nil, http.StatusOK, &listResp); err != nil {
+		return nil, err
+	}
+
+	return listResp.D.Results, nil
+}
+
+// createMockConfirmationResponse stores a confirmation in the simulator and returns it
+func (c *Client) createMockConfirmationResponse(req *models.SAPConfirmationRequest) *models.SAPConfirmationResponse {
+	c.mockMu.Lock()
+	defer c.mockMu.Unlock()
+
+	total := 0
+	for _, confs := range c.mockConfirmations {
+		total += len(confs)
+	}
+
+	confirmation := models.SAPConfirmation{
+		MaintOrderConf:            fmt.Sprintf("500000%04d", total+1),
+		MaintOrderConfCntrValue:   "00000001",
+		MaintenanceOrder:          req.MaintenanceOrder,
+		MaintenanceOrderOperation: req.MaintenanceOrderOperation,
+		ActualWorkQuantity:        req.ActualWorkQuantity,
+		ActualWorkQuantityUnit:    req.ActualWorkQuantityUnit,
+		IsFinalConfirmation:       req.IsFinalConfirmation,
+		PersonnelNumber:           req.PersonnelNumber,
+		PostingDate:               req.PostingDate,
+	}
+	if confirmation.PostingDate == "" {
+		// SAP posts on the current date when none is given
+		confirmation.PostingDate = time.Now().UTC().Format(time.RFC3339)
+	}
+	c.mockConfirmations[req.MaintenanceOrder] = append(c.mockConfirmations[req.MaintenanceOrder], confirmation)
+
+	return &models.SAPConfirmationResponse{D: confirmation}
+}
+
+// cancelMockConfirmation marks a simulator confirmation as reversed
+func (c *Client) cancelMockConfirmation(confirmationID, counter string) (*models.SAPConfirmationResponse, error) {
+	c.mockMu.Lock()
+	defer c.mockMu.Unlock()
+
+	for orderID, confs := range c.mockConfirmations {
+		for i := range confs {
+			if confs[i].MaintOrderConf == confirmationID && confs[i].MaintOrderConfCntrValue == counter {
+				c.mockConfirmations[orderID][i].IsReversed = true
+				return &models.SAPConfirmationResponse{D: c.mockConfirmations[orderID][i]}, nil
+			}
+		}
+	}
+
+	return nil, &APIError{StatusCode: http.StatusNotFound, Body: "confirmation " + confirmationID + " not found"}
+}
+
+// ConvertConfirmationRequestToSAP converts a technician confirmation to an SAP confirmation request
+func ConvertConfirmationRequestToSAP(orderID, operationID string, req *models.OperationConfirmationRequest) *models.SAPConfirmationRequest {
+	sapReq := &models.SAPConfirmationRequest{
+		MaintenanceOrder:          orderID,
+		MaintenanceOrderOperation: models.NormalizeOperationID(operationID),
+		ActualWorkQuantity:        strconv.FormatFloat(req.ActualWork, 'f', -1, 64),
+		ActualWorkQuantityUnit:    req.ActualWorkUnit,
+		IsFinalConfirmation:       req.FinalConfirmation,
+		PersonnelNumber:           req.PersonnelNumber,
+		ConfirmationText:          req.ConfirmationText,
+	}
+	if sapReq.ActualWorkQuantityUnit == "" {
+		sapReq.ActualWorkQuantityUnit = "H"
+	}
+
+	// Add time fields if provided
+	if req.PostingDate != nil {
+		sapReq.PostingDate = req.PostingDate.Format(time.RFC3339)
+	}
+	if req.WorkStartTime != nil {
+		sapReq.ConfirmedExecutionStartDate = req.WorkStartTime.Format(time.RFC3339)
+	}
+	if req.WorkEndTime != nil {
+		sapReq.ConfirmedExecutionEndDate = req.WorkEndTime.Format(time.RFC3339)
+	}
+
+	return sapReq
+}
+
+// ConvertSAPConfirmationToResponse converts an SAP confirmation to the adaptor response model
+func ConvertSAPConfirmationToResponse(conf *models.SAPConfirmation) *models.OperationConfirmationResponse {
+	resp := &models.OperationConfirmationResponse{
+		ConfirmationID:      conf.MaintOrderConf,
+		ConfirmationCounter: conf.MaintOrderConfCntrValue,
+		OrderID:             conf.MaintenanceOrder,
+		OperationID:         conf.MaintenanceOrderOperation,
+		ActualWorkUnit:      conf.ActualWorkQuantityUnit,
+		FinalConfirmation:   conf.IsFinalConfirmation,
+		PersonnelNumber:     conf.PersonnelNumber,
+		Cancelled:           conf.IsReversed,
+		CreatedAt:           parseSAPTime(conf.PostingDate),
+	}
+	if qty, err := strconv.ParseFloat(conf.ActualWorkQuantity, 64); err == nil {
+		resp.ActualWork = qty
+	}
+	return resp
+}
+
+// ApplyConfirmationsToStatus aggregates confirmed work per operation into the order status.
+// Reversed confirmations are ignored; operations without confirmations keep the values SAP returned.
+func ApplyConfirmationsToStatus(status *models.MaintenanceOrderStatus, confirmations []models.SAPConfirmation) {
+	type totals struct {
+		work  float64
+		unit  string
+		final bool
+	}
+	perOperation := make(map[string]*totals)
+	for _, conf := range confirmations {
+		if conf.IsReversed {
+			continue
+		}
+		qty, err := strconv.ParseFloat(conf.ActualWorkQuantity, 64)
+		if err != nil {
+			continue
+		}
+		opID := models.NormalizeOperationID(conf.MaintenanceOrderOperation)
+		t, ok := perOperation[opID]
+		if !ok {
+			t = &totals{}
+			perOperation[opID] = t
+		}
+		t.work += qty
+		t.unit = conf.ActualWorkQuantityUnit
+		t.final = t.final || conf.IsFinalConfirmation
+	}
+
+	for i := range status.Operations {
+		t, ok := perOperation[models.NormalizeOperationID(status.Operations[i].OperationID)]
+		if !ok {
+			continue
+		}
+		status.Operations[i].ActualWorkQuantity = t.work
+		status.Operations[i].WorkQuantityUnit = t.unit
+		if t.final {
+			status.Operations[i].Status = "CNF" // Finally confirmed
+		} else {
+			status.Operations[i].Status = "PCNF" // Partially confirmed
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCreateAndCancelConfirmation(t *testing.T) {
+	var cancelQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == confirmationServicePath+"/MaintOrderConfirmation":
+			var req models.SAPConfirmationRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Failed to decode confirmation: %v", err)
+			}
+			if req.MaintenanceOrderOperation != "0010" || req.ActualWorkQuantity != "2.5" || !req.IsFinalConfirmation {
+				t.Errorf("Unexpected confirmation request %+v", req)
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(models.SAPConfirmationResponse{D: models.SAPConfirmation{
+				MaintOrderConf:            "5000000001",
+				MaintOrderConfCntrValue:   "00000001",
+				MaintenanceOrder:          req.MaintenanceOrder,
+				MaintenanceOrderOperation: req.MaintenanceOrderOperation,
+				ActualWorkQuantity:        req.ActualWorkQuantity,
+				ActualWorkQuantityUnit:    req.ActualWorkQuantityUnit,
+				IsFinalConfirmation:       true,
+				PostingDate:               "2025-08-18T00:00:00Z",
+			}})
+		case r.Method == http.MethodPost && r.URL.Path == confirmationServicePath+"/CancelMaintOrderConf":
+			cancelQuery = r.URL.RawQuery
+			json.NewEncoder(w).Encode(models.SAPConfirmationResponse{D: models.SAPConfirmation{
+				MaintOrderConf:          "5000000001",
+				MaintOrderConfCntrValue: "00000001",
+				IsReversed:              true,
+			}})
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, Timeout: 5, SkipMetadataValidation: true}, logger)
+
+	req := ConvertConfirmationRequestToSAP("400000123", "10", &models.OperationConfirmationRequest{ActualWork: 2.5, FinalConfirmation: true})
+	created, err := client.CreateConfirmation(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	response := ConvertSAPConfirmationToResponse(&created.D)
+	if response.ConfirmationID != "5000000001" || response.ActualWork != 2.5 || response.ActualWorkUnit != "H" {
+		t.Errorf("Unexpected confirmation response %+v", response)
+	}
+	if response.CreatedAt == nil || !response.CreatedAt.Equal(time.Date(2025, 8, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the posting date as creation time, got %v", response.CreatedAt)
+	}
+
+	cancelled, err := client.CancelConfirmation(context.Background(), "5000000001", "00000001")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !cancelled.D.IsReversed {
+		t.Errorf("Expected the confirmation to be reversed, got %+v", cancelled.D)
+	}
+	if cancelQuery != "MaintOrderConf=%275000000001%27&MaintOrderConfCntrValue=%2700000001%27" {
+		t.Errorf("Unexpected cancel parameters %q", cancelQuery)
+	}
+}
+
+func TestApplyConfirmationsToStatus(t *testing.T) {
+	status := &models.MaintenanceOrderStatus{
+		OrderID: "400000789",
+		Operations: []models.OperationStatus{
+			{OperationID: "0010", Text: "Disassemble pump"},
+			{OperationID: "0020", Text: "Replace seal", Status: "REL", ActualWorkQuantity: 1.5, WorkQuantityUnit: "H"},
+		},
+	}
+
+	confirmations := []models.SAPConfirmation{
+		{MaintOrderConf: "5000000001", MaintenanceOrderOperation: "10", ActualWorkQuantity: "2.5", ActualWorkQuantityUnit: "H"},
+		{MaintOrderConf: "5000000002", MaintenanceOrderOperation: "0010", ActualWorkQuantity: "1", ActualWorkQuantityUnit: "H", IsFinalConfirmation: true},
+		{MaintOrderConf: "5000000003", MaintenanceOrderOperation: "0010", ActualWorkQuantity: "8", ActualWorkQuantityUnit: "H", IsReversed: true},
+	}
+
+	confirmations = append(confirmations, models.SAPConfirmation{MaintOrderConf: "5000000004", MaintenanceOrderOperation: "0020", ActualWorkQuantity: "0.5", ActualWorkQuantityUnit: "H", IsReversed: true})
+	status.Operations = append(status.Operations, models.OperationStatus{OperationID: "0030", Text: "Test run"})
+	confirmations = append(confirmations, models.SAPConfirmation{MaintOrderConf: "5000000005", MaintenanceOrderOperation: "0030", ActualWorkQuantity: "0.75", ActualWorkQuantityUnit: "H"})
+
+	ApplyConfirmationsToStatus(status, confirmations)
+
+	if status.Operations[0].ActualWorkQuantity != 3.5 {
+		t.Errorf("Expected actual work 3.5, got %v", status.Operations[0].ActualWorkQuantity)
+	}
+	if status.Operations[0].Status != "CNF" {
+		t.Errorf("Expected status CNF, got %s", status.Operations[0].Status)
+	}
+	if status.Operations[1].ActualWorkQuantity != 1.5 || status.Operations[1].Status != "REL" {
+		t.Errorf("Expected unconfirmed operation to be unchanged, got %+v", status.Operations[1])
+	}
+	if status.Operations[2].ActualWorkQuantity != 0.75 || status.Operations[2].Status != "PCNF" {
+		t.Errorf("Expected a partially confirmed operation, got %+v", status.Operations[2])
+	}
+}
+
+func TestNextOperationNumber(t *testing.T) {
+	if got := NextOperationNumber([]string{"0010", "0020"}); got != "0030" {
+		t.Errorf("Expected 0030, got %s", got)
+	}
+	if got := NextOperationNumber([]string{"0010", "0025"}); got != "0030" {
+		t.Errorf("Expected 0030, got %s", got)
+	}
+	if got := NextOperationNumber(nil); got != "0010" {
+		t.Errorf("Expected 0010, got %s", got)
+	}
+}
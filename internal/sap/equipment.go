@@ -0,0 +1,85 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const equipmentServicePath = "/API_EQUIPMENT"
+
+// MasterDataCacheTTL returns how long master data lookups may be cached. Lookups served
+// from the simulator reference data file are not cached, so edits to the file apply at once.
+func (c *Client) MasterDataCacheTTL() time.Duration {
+	if c.referenceData != nil {
+		return 0
+	}
+	return time.Duration(c.config.MasterDataCacheTTL) * time.Second
+}
+
+// GetEquipment retrieves the current master record of an equipment. An unknown equipment
+// results in a 404 APIError.
+func (c *Client) GetEquipment(ctx context.Context, equipmentID string) (*models.SAPEquipment, error) {
+	c.logger.WithFields(logrus.Fields{
+		"equipmentId":   equipmentID,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Retrieving SAP equipment master data")
+
+	// If in simulator mode, return mock master data
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - returning mock equipment")
+		equipment := createMockEquipment(equipmentID)
+		if equipment == nil {
+			return nil, &APIError{StatusCode: http.StatusNotFound, Body: fmt.Sprintf("equipment %s does not exist", equipmentID)}
+		}
+		return equipment, nil
+	}
+
+	// Equipment is time-dependent; the record with the latest validity end is the current one
+	params := url.Values{}
+	params.Set("$filter", fmt.Sprintf("Equipment eq '%s'", odataEscape(equipmentID)))
+	params.Set("$orderby", "ValidityEndDate desc")
+	params.Set("$top", "1")
+
+	var listResp models.SAPEquipmentListResponse
+	if err := c.doRequest(ctx, http.MethodGet, equipmentServicePath+"/Equipment?"+params.Encode(), nil, http.StatusOK, &listResp); err != nil {
+		return nil, err
+	}
+	if len(listResp.D.Results) == 0 {
+		return nil, &APIError{StatusCode: http.StatusNotFound, Body: fmt.Sprintf("equipment %s does not exist", equipmentID)}
+	}
+
+	return &listResp.D.Results[0], nil
+}
+
+// createMockEquipment returns simulator master data. Equipment IDs starting with 9 do not
+// exist and IDs ending in 99 are inactive.
+func createMockEquipment(equipmentID string) *models.SAPEquipment {
+	if equipmentID == "" || strings.HasPrefix(equipmentID, "9") {
+		return nil
+	}
+
+	abc := "B"
+	if strings.HasSuffix(equipmentID, "45") {
+		abc = "A"
+	}
+	return &models.SAPEquipment{
+		Equipment:                equipmentID,
+		EquipmentName:            "Mock equipment " + equipmentID,
+		FunctionalLocation:       "FL100-200-300",
+		MaintenancePlant:         "1000",
+		MaintenancePlanningPlant: "1000",
+		MainWorkCenter:           "MECH-01",
+		MainWorkCenterPlant:      "1000",
+		ABCIndicator:             abc,
+		TechnicalObjectType:      "PUMP",
+		EquipmentIsInactive:      strings.HasSuffix(equipmentID, "99"),
+	}
+}
@@ -0,0 +1,54 @@
+package sap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"sap-adaptor/internal/models"
+)
+
+// APIError represents a non-successful HTTP response returned by SAP
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("SAP API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether err is an SAP 404 response
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsPreconditionFailed reports whether err is an SAP 412 response caused by a stale ETag
+func IsPreconditionFailed(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// PayloadError is returned before sending when a payload does not match the SAP $metadata
+type PayloadError struct {
+	EntitySet string
+	Errors    []models.FieldError
+}
+
+// Error implements the error interface
+func (e *PayloadError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		fields = append(fields, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return fmt.Sprintf("payload for %s does not match SAP metadata (%s)", e.EntitySet, strings.Join(fields, "; "))
+}
+
+// IsPayloadInvalid reports whether err is a payload rejected by $metadata validation
+func IsPayloadInvalid(err error) bool {
+	var payloadErr *PayloadError
+	return errors.As(err, &payloadErr)
+}
@@ -0,0 +1,145 @@
+package sap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+)
+
+// Types of extension fields
+const (
+	ExtensionTypeString  = "string"
+	ExtensionTypeDecimal = "decimal"
+	ExtensionTypeBoolean = "boolean"
+)
+
+// ExtensionError is returned when an event extension cannot be converted to the type of its SAP field
+type ExtensionError struct {
+	Errors []models.FieldError
+}
+
+// Error implements the error interface
+func (e *ExtensionError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+	return "invalid extensions: " + strings.Join(messages, "; ")
+}
+
+// ExtensionFields returns the configured mapping of event extensions to SAP custom fields
+func (c *Client) ExtensionFields() []config.ExtensionField {
+	return c.config.ExtensionFields
+}
+
+// ConvertEventExtensions maps the extensions of an event to the custom fields of the
+// notification and the order. Extensions without a mapping are ignored.
+func (c *Client) ConvertEventExtensions(extensions map[string]models.ExtensionValue) (notification, order map[string]interface{}, err error) {
+	var fieldErrors []models.FieldError
+	for _, key := range sortedKeys(extensions) {
+		field, ok := c.extensionField(key)
+		if !ok {
+			c.logger.WithField("extension", key).Warn("No SAP field configured for extension, ignoring it")
+			continue
+		}
+
+		value := extensions[key]
+		// Notifications are always OData v2, where decimals are sent as strings
+		if field.NotificationField != "" {
+			converted, err := convertExtensionValue(value, field.Type, false)
+			if err != nil {
+				fieldErrors = append(fieldErrors, models.FieldError{Field: "extensions." + key, Value: value.String(), Message: err.Error()})
+				continue
+			}
+			if notification == nil {
+				notification = make(map[string]interface{})
+			}
+			notification[field.NotificationField] = converted
+		}
+		if field.OrderField != "" {
+			converted, err := convertExtensionValue(value, field.Type, c.odataV4())
+			if err != nil {
+				fieldErrors = append(fieldErrors, models.FieldError{Field: "extensions." + key, Value: value.String(), Message: err.Error()})
+				continue
+			}
+			if order == nil {
+				order = make(map[string]interface{})
+			}
+			order[field.OrderField] = converted
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, nil, &ExtensionError{Errors: fieldErrors}
+	}
+	return notification, order, nil
+}
+
+// ExtensionsFromOrder returns the custom fields of an order. Mapped fields are keyed by
+// their extension name, other custom fields by their SAP name.
+func (c *Client) ExtensionsFromOrder(order *models.SAPOrder) map[string]models.ExtensionValue {
+	if len(order.Extensions) == 0 {
+		return nil
+	}
+
+	extensions := make(map[string]models.ExtensionValue, len(order.Extensions))
+	for name, value := range order.Extensions {
+		key := name
+		for _, field := range c.config.ExtensionFields {
+			if field.OrderField == name {
+				key = field.Extension
+				break
+			}
+		}
+		if value == nil {
+			continue
+		}
+		extensions[key] = models.NewExtensionValue(value)
+	}
+	return extensions
+}
+
+// extensionField finds the mapping of an extension
+func (c *Client) extensionField(extension string) (config.ExtensionField, bool) {
+	for _, field := range c.config.ExtensionFields {
+		if field.Extension == extension {
+			return field, true
+		}
+	}
+	return config.ExtensionField{}, false
+}
+
+// convertExtensionValue converts an extension value to the JSON representation of the SAP field type
+func convertExtensionValue(value models.ExtensionValue, fieldType string, v4 bool) (interface{}, error) {
+	switch fieldType {
+	case "", ExtensionTypeString:
+		return value.String(), nil
+	case ExtensionTypeDecimal:
+		text := value.String()
+		if _, ok := value.Value().(bool); ok {
+			return nil, fmt.Errorf("expected a decimal")
+		}
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, fmt.Errorf("expected a decimal")
+		}
+		if v4 {
+			return json.Number(text), nil
+		}
+		return text, nil
+	case ExtensionTypeBoolean:
+		if b, ok := value.Value().(bool); ok {
+			return b, nil
+		}
+		b, err := strconv.ParseBool(value.String())
+		if err != nil {
+			return nil, fmt.Errorf("expected a boolean")
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported extension type %q", fieldType)
+	}
+}
@@ -0,0 +1,66 @@
+package sap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestExtensionsRoundTrip(t *testing.T) {
+	client := NewClient(config.SAPConfig{
+		SimulatorMode: true,
+		ExtensionFields: []config.ExtensionField{
+			{Extension: "digitalTwinRef", OrderField: "YY1_DigitalTwinRef_ORD"},
+			{Extension: "anomalyScore", NotificationField: "YY1_AnomalyScore_NOT", Type: ExtensionTypeDecimal},
+		},
+	}, logrus.New())
+
+	var event models.MaintenanceOrderEvent
+	if err := json.Unmarshal([]byte(`{"extensions":{"digitalTwinRef":"DT-4711","anomalyScore":0.87,"unmapped":true}}`), &event); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	notification, order, err := client.ConvertEventExtensions(event.Extensions)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	body, _ := json.Marshal(models.SAPNotificationRequest{NotificationText: "Bearing", Extensions: notification})
+	var sent map[string]interface{}
+	json.Unmarshal(body, &sent)
+	if sent["YY1_AnomalyScore_NOT"] != "0.87" || sent["NotificationText"] != "Bearing" || len(order) != 1 {
+		t.Errorf("Unexpected notification payload %s", body)
+	}
+
+	body, _ = json.Marshal(models.SAPOrderRequest{Description: "Bearing", Extensions: order})
+	sent = nil
+	json.Unmarshal(body, &sent)
+	if sent["YY1_DigitalTwinRef_ORD"] != "DT-4711" || sent["Description"] != "Bearing" {
+		t.Errorf("Unexpected order payload %s", body)
+	}
+
+	var returned models.SAPOrder
+	if err := json.Unmarshal([]byte(`{"MaintenanceOrder":"4000001","YY1_DigitalTwinRef_ORD":"DT-4711","ZZ1_Shift":"B"}`), &returned); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	extensions := client.ExtensionsFromOrder(&returned)
+	if extensions["digitalTwinRef"].String() != "DT-4711" || extensions["ZZ1_Shift"].String() != "B" {
+		t.Errorf("Unexpected extensions %+v", extensions)
+	}
+}
+
+func TestConvertEventExtensionsRejectsWrongType(t *testing.T) {
+	client := NewClient(config.SAPConfig{
+		SimulatorMode:   true,
+		ExtensionFields: []config.ExtensionField{{Extension: "anomalyScore", OrderField: "YY1_AnomalyScore_ORD", Type: ExtensionTypeDecimal}},
+	}, logrus.New())
+
+	_, _, err := client.ConvertEventExtensions(map[string]models.ExtensionValue{"anomalyScore": models.NewExtensionValue("high")})
+	extensionErr, ok := err.(*ExtensionError)
+	if !ok || len(extensionErr.Errors) != 1 || extensionErr.Errors[0].Field != "extensions.anomalyScore" {
+		t.Errorf("Expected an extension error for anomalyScore, got %v", err)
+	}
+}
@@ -0,0 +1,440 @@
+package sap
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Entities of a field mapping
+const (
+	mappingNotification = "notification"
+	mappingOrder        = "order"
+	mappingOperation    = "operation"
+)
+
+// MappingDefinition is the layout of the field mapping file. Each entity maps SAP property
+// names to the rule that produces their value.
+type MappingDefinition struct {
+	Notification map[string]FieldRule `yaml:"notification"`
+	Order        map[string]FieldRule `yaml:"order"`
+	Operation    map[string]FieldRule `yaml:"operation"`
+}
+
+// FieldRule produces the value of one SAP property. The first matching conditional rule wins,
+// then the constant value, then the first non-empty source field (translated through lookup),
+// then the default.
+type FieldRule struct {
+	When    []ConditionalRule `yaml:"when"`
+	Value   string            `yaml:"value"`   // Constant
+	From    SourceList        `yaml:"from"`    // Event fields, e.g. plant, operation.workCenter, extensions.shift
+	Lookup  map[string]string `yaml:"lookup"`  // Translates the source value; unlisted values are kept
+	Default string            `yaml:"default"` // Used when the result is empty
+}
+
+// ConditionalRule sets a value when all its conditions match. A condition value may list
+// alternatives separated by "|"; an empty value matches an empty field.
+type ConditionalRule struct {
+	If    map[string]string `yaml:"if"`
+	Value string            `yaml:"value"`
+	From  SourceList        `yaml:"from"`
+}
+
+// SourceList is one event field or a list of fallbacks
+type SourceList []string
+
+// UnmarshalYAML accepts a single field name or a list
+func (s *SourceList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = SourceList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// DefaultMappingDefinition reproduces the built-in conversion of events to SAP payloads.
+// It is used when no mapping file is configured.
+func DefaultMappingDefinition() MappingDefinition {
+	return MappingDefinition{
+		Notification: map[string]FieldRule{
+			"NotificationType":   {From: SourceList{"notificationType"}, Default: "M1"},
+			"Description":        {From: SourceList{"description"}},
+			"Equipment":          {From: SourceList{"equipmentId"}},
+			"FunctionalLocation": {From: SourceList{"functionalLocation"}},
+			"Plant":              {From: SourceList{"plant"}},
+			"Priority":           {From: SourceList{"priority"}},
+		},
+		Order: map[string]FieldRule{
+			"MaintenanceOrderType":     {From: SourceList{"maintenanceOrderType"}},
+			"Description":              {From: SourceList{"description"}},
+			"Equipment":                {From: SourceList{"equipmentId"}},
+			"FunctionalLocation":       {From: SourceList{"functionalLocation"}},
+			"Plant":                    {From: SourceList{"plant"}},
+			"MaintenancePlanningPlant": {From: SourceList{"planningPlant", "plant"}},
+			"MainWorkCenter":           {From: SourceList{"mainWorkCenter"}},
+			"Priority":                 {From: SourceList{"priority"}},
+		},
+		Operation: map[string]FieldRule{
+			"OperationText":         {From: SourceList{"operation.text"}},
+			"WorkCenter":            {From: SourceList{"operation.workCenter", "mainWorkCenter"}},
+			"Plant":                 {From: SourceList{"plant"}},
+			"OperationControlKey":   {From: SourceList{"operation.controlKey"}},
+			"OperationDurationUnit": {From: SourceList{"operation.durationUnit"}},
+		},
+	}
+}
+
+// mappingTargets are the payloads of each entity. Properties the adaptor fills itself
+// (texts, dates, numbering, navigation) cannot be mapped.
+var mappingTargets = map[string]struct {
+	payload  reflect.Type
+	reserved []string
+}{
+	mappingNotification: {reflect.TypeOf(models.SAPNotificationRequest{}), []string{"NotificationText", "RequiredStartDate", "RequiredEndDate"}},
+	mappingOrder:        {reflect.TypeOf(models.SAPOrderRequest{}), []string{"MaintenanceNotification", "MaintOrdBasicStartDateTime", "MaintOrdBasicEndDateTime", "TaskListType", "TaskListGroup", "TaskListGroupCounter"}},
+	mappingOperation:    {reflect.TypeOf(models.SAPOrderOperation{}), []string{"MaintenanceOrder", "MaintenanceOrderOperation", "OperationStandardDuration"}},
+}
+
+// compiledMapping is a validated mapping definition with the struct field of every target
+type compiledMapping struct {
+	entities map[string][]compiledField
+}
+
+type compiledField struct {
+	name  string
+	index []int
+	rule  FieldRule
+}
+
+// compileMapping checks targets and sources of a definition
+func compileMapping(def MappingDefinition) (*compiledMapping, error) {
+	compiled := &compiledMapping{entities: make(map[string][]compiledField)}
+	entities := map[string]map[string]FieldRule{
+		mappingNotification: def.Notification,
+		mappingOrder:        def.Order,
+		mappingOperation:    def.Operation,
+	}
+	for _, entity := range sortedKeys(entities) {
+		target := mappingTargets[entity]
+		for _, name := range sortedKeys(entities[entity]) {
+			rule := entities[entity][name]
+			field, ok := stringFieldByJSONName(target.payload, name)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s: not a text property of the SAP payload", entity, name)
+			}
+			for _, reserved := range target.reserved {
+				if reserved == name {
+					return nil, fmt.Errorf("%s.%s: property is filled by the adaptor and cannot be mapped", entity, name)
+				}
+			}
+			if err := checkRuleSources(entity, rule); err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", entity, name, err)
+			}
+			compiled.entities[entity] = append(compiled.entities[entity], compiledField{name: name, index: field.Index, rule: rule})
+		}
+	}
+	return compiled, nil
+}
+
+// checkRuleSources reports event fields that do not exist
+func checkRuleSources(entity string, rule FieldRule) error {
+	sources := append([]string{}, rule.From...)
+	for _, cond := range rule.When {
+		if len(cond.If) == 0 {
+			return fmt.Errorf("conditional rule without conditions")
+		}
+		sources = append(sources, cond.From...)
+		for source := range cond.If {
+			sources = append(sources, source)
+		}
+	}
+	for _, source := range sources {
+		if !validMappingSource(entity, source) {
+			return fmt.Errorf("unknown source field %q", source)
+		}
+	}
+	return nil
+}
+
+// validMappingSource reports whether a source field exists for an entity.
+// Operation fields are only available when mapping operations.
+func validMappingSource(entity, source string) bool {
+	if strings.HasPrefix(source, "extensions.") {
+		return true
+	}
+	if name, ok := strings.CutPrefix(source, "operation."); ok {
+		_, found := mappingSourceFields(reflect.TypeOf(models.MaintenanceOperation{}))[name]
+		return entity == mappingOperation && found
+	}
+	_, found := mappingSourceFields(reflect.TypeOf(models.MaintenanceOrderEvent{}))[source]
+	return found
+}
+
+// mappingSourceFields returns the text and number fields of an event struct by JSON name
+func mappingSourceFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Float64, reflect.Int:
+		default:
+			continue
+		}
+		if name := jsonFieldName(field); name != "" {
+			fields[name] = field.Index
+		}
+	}
+	return fields
+}
+
+// stringFieldByJSONName finds a string field of an SAP payload by its JSON name
+func stringFieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if jsonFieldName(field) == name && field.Type.Kind() == reflect.String {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// mappingSources collects the values the rules can read from an event and, for operations,
+// the operation being converted
+func mappingSources(event *models.MaintenanceOrderEvent, op *models.MaintenanceOperation) map[string]string {
+	sources := make(map[string]string)
+	addMappingSources(sources, "", reflect.ValueOf(event).Elem())
+	if op != nil {
+		addMappingSources(sources, "operation.", reflect.ValueOf(op).Elem())
+	}
+	for key, value := range event.Extensions {
+		sources["extensions."+key] = value.String()
+	}
+	return sources
+}
+
+func addMappingSources(sources map[string]string, prefix string, v reflect.Value) {
+	for name, index := range mappingSourceFields(v.Type()) {
+		field := v.FieldByIndex(index)
+		switch field.Kind() {
+		case reflect.String:
+			sources[prefix+name] = field.String()
+		case reflect.Float64:
+			if field.Float() != 0 {
+				sources[prefix+name] = strconv.FormatFloat(field.Float(), 'f', -1, 64)
+			}
+		case reflect.Int:
+			if field.Int() != 0 {
+				sources[prefix+name] = strconv.FormatInt(field.Int(), 10)
+			}
+		}
+	}
+}
+
+// resolve evaluates a rule against the source values
+func (r FieldRule) resolve(sources map[string]string) string {
+	for _, cond := range r.When {
+		if cond.matches(sources) {
+			if cond.Value != "" {
+				return cond.Value
+			}
+			if value := firstSource(cond.From, sources); value != "" {
+				return value
+			}
+		}
+	}
+	if r.Value != "" {
+		return r.Value
+	}
+	value := firstSource(r.From, sources)
+	if translated, ok := r.Lookup[value]; ok && value != "" {
+		value = translated
+	}
+	if value == "" {
+		value = r.Default
+	}
+	return value
+}
+
+func (c ConditionalRule) matches(sources map[string]string) bool {
+	for source, expected := range c.If {
+		matched := false
+		for _, alternative := range strings.Split(expected, "|") {
+			if strings.EqualFold(sources[source], alternative) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func firstSource(from SourceList, sources map[string]string) string {
+	for _, source := range from {
+		if value := sources[source]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// apply sets the mapped properties of an SAP payload
+func (m *compiledMapping) apply(entity string, sources map[string]string, target interface{}) {
+	v := reflect.ValueOf(target).Elem()
+	for _, field := range m.entities[entity] {
+		v.FieldByIndex(field.index).SetString(field.rule.resolve(sources))
+	}
+}
+
+var (
+	defaultMappingOnce sync.Once
+	defaultMapping     *compiledMapping
+)
+
+// builtinMapping returns the compiled default mapping
+func builtinMapping() *compiledMapping {
+	defaultMappingOnce.Do(func() {
+		var err error
+		if defaultMapping, err = compileMapping(DefaultMappingDefinition()); err != nil {
+			panic(fmt.Sprintf("invalid default field mapping: %v", err))
+		}
+	})
+	return defaultMapping
+}
+
+// FieldMapping converts event fields to SAP properties as defined in a YAML file. The file is
+// reloaded whenever its modification time changes; a nil FieldMapping uses the default mapping.
+type FieldMapping struct {
+	path   string
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	modTime time.Time
+	mapping *compiledMapping
+}
+
+// FieldMapping returns the field mapping of the destination (nil for the default mapping)
+func (c *Client) FieldMapping() *FieldMapping {
+	return c.fieldMapping
+}
+
+// PriorityConfig returns the priority matrices and SLAs of the destination
+func (c *Client) PriorityConfig() config.PriorityConfig {
+	return c.config.Priority
+}
+
+// DeterminationRules returns the order type, notification type and control key rules of the destination
+func (c *Client) DeterminationRules() config.DeterminationRules {
+	return c.config.Rules
+}
+
+// LoadFieldMapping loads and validates a mapping file
+func LoadFieldMapping(path string, logger *logrus.Logger) (*FieldMapping, error) {
+	m, err := watchFieldMapping(path, logger)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// watchFieldMapping returns a mapping that follows the file even if it is missing or invalid
+// now: the default mapping is used until the first valid version is loaded. The error reports
+// why the initial load failed.
+func watchFieldMapping(path string, logger *logrus.Logger) (*FieldMapping, error) {
+	m := &FieldMapping{path: path, logger: logger, mapping: builtinMapping()}
+	return m, m.reload()
+}
+
+// parseMapping parses and validates the content of a mapping file
+func parseMapping(content []byte) (*compiledMapping, error) {
+	var def MappingDefinition
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("failed to parse field mapping: %w", err)
+	}
+	compiled, err := compileMapping(def)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field mapping: %w", err)
+	}
+	return compiled, nil
+}
+
+// reload reads the file if it changed since it was last loaded
+func (m *FieldMapping) reload() error {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat field mapping file: %w", err)
+	}
+	if info.ModTime().Equal(m.modTime) {
+		return nil
+	}
+
+	content, err := os.ReadFile(m.path)
+	if err != nil {
+		return fmt.Errorf("failed to read field mapping file: %w", err)
+	}
+	compiled, err := parseMapping(content)
+	if err != nil {
+		return err
+	}
+	m.mapping = compiled
+	m.modTime = info.ModTime()
+
+	m.logger.WithFields(logrus.Fields{
+		"file":         m.path,
+		"notification": len(compiled.entities[mappingNotification]),
+		"order":        len(compiled.entities[mappingOrder]),
+		"operation":    len(compiled.entities[mappingOperation]),
+	}).Info("Loaded field mapping")
+
+	return nil
+}
+
+// current returns the mapping to use, reloading a changed file and keeping the previous
+// mapping if the new content is invalid
+func (m *FieldMapping) current() *compiledMapping {
+	if m == nil {
+		return builtinMapping()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.reload(); err != nil {
+		m.logger.WithError(err).Error("Failed to reload field mapping, keeping previous mapping")
+	}
+	return m.mapping
+}
+
+// ApplyNotification sets the mapped properties of a notification request
+func (m *FieldMapping) ApplyNotification(event *models.MaintenanceOrderEvent, req *models.SAPNotificationRequest) {
+	m.current().apply(mappingNotification, mappingSources(event, nil), req)
+}
+
+// ApplyOrder sets the mapped header properties of an order request
+func (m *FieldMapping) ApplyOrder(event *models.MaintenanceOrderEvent, req *models.SAPOrderRequest) {
+	m.current().apply(mappingOrder, mappingSources(event, nil), req)
+}
+
+// ApplyOperation sets the mapped properties of an order operation
+func (m *FieldMapping) ApplyOperation(event *models.MaintenanceOrderEvent, op *models.MaintenanceOperation, sapOp *models.SAPOrderOperation) {
+	m.current().apply(mappingOperation, mappingSources(event, op), sapOp)
+}
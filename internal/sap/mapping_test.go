@@ -0,0 +1,215 @@
+package sap
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestFieldMappingGolden converts testdata/mapping/<case>/event.json with the mapping.yaml of the
+// case (the default mapping if there is none) and compares the payloads with golden.json.
+// Run with -update to rewrite the golden files.
+func TestFieldMappingGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "mapping", "*"))
+	if err != nil || len(dirs) == 0 {
+		t.Fatalf("Expected mapping test cases, got %v", err)
+	}
+
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			var mapping *FieldMapping
+			if _, err := os.Stat(filepath.Join(dir, "mapping.yaml")); err == nil {
+				if mapping, err = LoadFieldMapping(filepath.Join(dir, "mapping.yaml"), quietLogger()); err != nil {
+					t.Fatalf("Expected mapping to load, got %v", err)
+				}
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, "event.json"))
+			if err != nil {
+				t.Fatalf("Expected event, got %v", err)
+			}
+			var event models.MaintenanceOrderEvent
+			if err := json.Unmarshal(content, &event); err != nil {
+				t.Fatalf("Expected valid event, got %v", err)
+			}
+
+			got, _ := json.MarshalIndent(map[string]interface{}{
+				"notification": ConvertMaintenanceOrderEventToNotificationRequest(&event, nil, mapping),
+				"order":        ConvertMaintenanceOrderEventToOrderRequest(&event, "200000123", mapping),
+			}, "", "  ")
+			got = append(got, '\n')
+
+			golden := filepath.Join(dir, "golden.json")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("Failed to write golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Expected golden file (run with -update to create it), got %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Payloads differ from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestShippedMappingFileMatchesDefault(t *testing.T) {
+	mapping, err := LoadFieldMapping(filepath.Join("..", "..", "field-mapping.yaml"), quietLogger())
+	if err != nil {
+		t.Fatalf("Expected field-mapping.yaml to load, got %v", err)
+	}
+	content, _ := os.ReadFile(filepath.Join("testdata", "mapping", "default", "event.json"))
+	var event models.MaintenanceOrderEvent
+	json.Unmarshal(content, &event)
+
+	shipped, _ := json.Marshal(ConvertMaintenanceOrderEventToOrderRequest(&event, "", mapping))
+	builtin, _ := json.Marshal(ConvertMaintenanceOrderEventToOrderRequest(&event, "", nil))
+	if !bytes.Equal(shipped, builtin) {
+		t.Errorf("field-mapping.yaml differs from the built-in mapping:\n%s\n%s", shipped, builtin)
+	}
+}
+
+func TestOrderRequestOperationNumbering(t *testing.T) {
+	event := &models.MaintenanceOrderEvent{
+		EquipmentID: "10000045",
+		Plant:       "1000",
+		Description: "Test maintenance order",
+		Operations: []models.MaintenanceOperation{
+			{Text: "First operation"},
+			{Text: "Second operation"},
+			{OperationID: "50", Text: "Explicitly numbered operation"},
+		},
+	}
+
+	orderReq := ConvertMaintenanceOrderEventToOrderRequest(event, "", nil)
+	expected := []string{"0010", "0020", "0050"}
+	for i, op := range orderReq.ToMaintenanceOrderOperation {
+		if op.MaintenanceOrderOperation != expected[i] {
+			t.Errorf("Expected operation number %s, got %s", expected[i], op.MaintenanceOrderOperation)
+		}
+	}
+}
+
+func TestOrderRequestComponents(t *testing.T) {
+	event := &models.MaintenanceOrderEvent{
+		EquipmentID: "10000045",
+		Plant:       "1000",
+		Description: "Replace pump seal",
+		Operations: []models.MaintenanceOperation{
+			{Text: "Disassemble pump"},
+			{Text: "Replace seal"},
+		},
+		Components: []models.MaintenanceComponent{
+			{Material: "SEAL-KIT-100", Quantity: 1, StorageLocation: "0001", OperationID: "0020"},
+			{Material: "GREASE-5", Quantity: 0.5, Unit: "KG"},
+		},
+	}
+
+	orderReq := ConvertMaintenanceOrderEventToOrderRequest(event, "200000123", nil)
+	first := orderReq.ToMaintenanceOrderOperation[0].ToMaintenanceOrderComponent
+	second := orderReq.ToMaintenanceOrderOperation[1].ToMaintenanceOrderComponent
+	if len(first) != 1 || first[0].Material != "GREASE-5" || first[0].BaseUnit != "KG" {
+		t.Errorf("Expected unassigned component on first operation, got %+v", first)
+	}
+	if len(second) != 1 || second[0].Material != "SEAL-KIT-100" || second[0].BaseUnit != "EA" || second[0].Plant != "1000" {
+		t.Errorf("Expected seal kit on operation 0020, got %+v", second)
+	}
+}
+
+func TestFieldMappingRejectsInvalidDefinitions(t *testing.T) {
+	tests := map[string]string{
+		"unknown target":    "order:\n  Colour: {value: red}\n",
+		"reserved target":   "order:\n  MaintenanceNotification: {value: \"1\"}\n",
+		"unknown source":    "notification:\n  Plant: {from: site}\n",
+		"operation source":  "order:\n  MainWorkCenter: {from: operation.workCenter}\n",
+		"unknown attribute": "order:\n  Plant: {copy: plant}\n",
+	}
+	for name, content := range tests {
+		if _, err := parseMapping([]byte(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFieldMappingReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.yaml")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modTime, modTime)
+	}
+	notificationType := func(m *FieldMapping) string {
+		req := ConvertMaintenanceOrderEventToNotificationRequest(&models.MaintenanceOrderEvent{Plant: "1000"}, nil, m)
+		return req.NotificationType
+	}
+
+	now := time.Now()
+	write("notification:\n  NotificationType: {value: M1}\n", now.Add(-time.Minute))
+	mapping, err := LoadFieldMapping(path, quietLogger())
+	if err != nil {
+		t.Fatalf("Expected mapping to load, got %v", err)
+	}
+	if got := notificationType(mapping); got != "M1" {
+		t.Errorf("Expected M1, got %s", got)
+	}
+
+	write("notification:\n  NotificationType: {value: M2}\n", now)
+	if got := notificationType(mapping); got != "M2" {
+		t.Errorf("Expected reloaded mapping to give M2, got %s", got)
+	}
+
+	write("notification:\n  NotificationType: {from: nowhere}\n", now.Add(time.Minute))
+	if got := notificationType(mapping); got != "M2" {
+		t.Errorf("Expected invalid file to keep the previous mapping, got %s", got)
+	}
+}
+
+func TestClientPicksUpMappingFileThatWasInvalidAtStartup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.yaml")
+	if err := os.WriteFile(path, []byte("notification:\n  NotificationType: {from: nowhere}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notificationType := func(m *FieldMapping) string {
+		req := ConvertMaintenanceOrderEventToNotificationRequest(&models.MaintenanceOrderEvent{Plant: "1000"}, nil, m)
+		return req.NotificationType
+	}
+
+	client := NewClient(config.SAPConfig{SimulatorMode: true, MappingFile: path}, quietLogger())
+	mapping := client.FieldMapping()
+	if mapping == nil {
+		t.Fatal("Expected the client to watch the mapping file")
+	}
+	if got, want := notificationType(mapping), notificationType(nil); got != want {
+		t.Errorf("Expected the default mapping (%s) while the file is invalid, got %s", want, got)
+	}
+
+	if err := os.WriteFile(path, []byte("notification:\n  NotificationType: {value: M2}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if got := notificationType(mapping); got != "M2" {
+		t.Errorf("Expected the first valid version to be loaded, got %s", got)
+	}
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
@@ -0,0 +1,179 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	workCenterServicePath         = "/API_WORK_CENTERS"
+	functionalLocationServicePath = "/API_FUNCTIONALLOCATION"
+)
+
+// GetWorkCenter retrieves a work center of a plant. An unknown work center results in a 404 APIError.
+func (c *Client) GetWorkCenter(ctx context.Context, plant, workCenter string) (*models.SAPWorkCenter, error) {
+	c.logger.WithFields(logrus.Fields{
+		"plant":         plant,
+		"workCenter":    workCenter,
+		"simulatorMode": c.simulatorMode,
+	}).Debug("Retrieving SAP work center")
+
+	notFound := &APIError{StatusCode: http.StatusNotFound, Body: fmt.Sprintf("work center %s does not exist in plant %s", workCenter, plant)}
+
+	// If in simulator mode, use the reference data file
+	if c.simulatorMode {
+		if c.referenceData == nil {
+			return &models.SAPWorkCenter{WorkCenter: workCenter, Plant: plant}, nil
+		}
+		if wc, ok := c.referenceData.workCenter(plant, workCenter); ok {
+			return wc, nil
+		}
+		return nil, notFound
+	}
+
+	params := url.Values{}
+	params.Set("$filter", fmt.Sprintf("WorkCenter eq '%s' and Plant eq '%s'", odataEscape(workCenter), odataEscape(plant)))
+	params.Set("$top", "1")
+
+	var listResp models.SAPWorkCenterListResponse
+	if err := c.doRequest(ctx, http.MethodGet, workCenterServicePath+"/A_WorkCenters?"+params.Encode(), nil, http.StatusOK, &listResp); err != nil {
+		return nil, err
+	}
+	if len(listResp.D.Results) == 0 {
+		return nil, notFound
+	}
+
+	return &listResp.D.Results[0], nil
+}
+
+// GetFunctionalLocation retrieves a functional location. An unknown functional location results in a 404 APIError.
+func (c *Client) GetFunctionalLocation(ctx context.Context, functionalLocation string) (*models.SAPFunctionalLocation, error) {
+	c.logger.WithFields(logrus.Fields{
+		"functionalLocation": functionalLocation,
+		"simulatorMode":      c.simulatorMode,
+	}).Debug("Retrieving SAP functional location")
+
+	// If in simulator mode, use the reference data file
+	if c.simulatorMode {
+		if c.referenceData == nil {
+			return &models.SAPFunctionalLocation{FunctionalLocation: functionalLocation}, nil
+		}
+		if fl, ok := c.referenceData.functionalLocation(functionalLocation); ok {
+			return fl, nil
+		}
+		return nil, &APIError{StatusCode: http.StatusNotFound, Body: fmt.Sprintf("functional location %s does not exist", functionalLocation)}
+	}
+
+	var flResp models.SAPFunctionalLocationResponse
+	path := functionalLocationServicePath + "/FunctionalLocation('" + odataKey(functionalLocation) + "')"
+	if err := c.doRequest(ctx, http.MethodGet, path, nil, http.StatusOK, &flResp); err != nil {
+		return nil, err
+	}
+
+	return &flResp.D, nil
+}
+
+// referenceDataFile is the layout of the simulator reference data file
+type referenceDataFile struct {
+	WorkCenters         []models.SAPWorkCenter         `yaml:"workCenters"`
+	FunctionalLocations []models.SAPFunctionalLocation `yaml:"functionalLocations"`
+}
+
+// referenceData serves simulator master data from a local file. The file is reloaded
+// whenever its modification time changes.
+type referenceData struct {
+	path   string
+	logger *logrus.Logger
+
+	mu                  sync.Mutex
+	modTime             time.Time
+	workCenters         map[string]models.SAPWorkCenter
+	functionalLocations map[string]models.SAPFunctionalLocation
+}
+
+// newReferenceData loads the reference data file
+func newReferenceData(path string, logger *logrus.Logger) (*referenceData, error) {
+	ref := &referenceData{path: path, logger: logger}
+	if err := ref.reload(); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// reload reads the file if it changed since it was last loaded
+func (r *referenceData) reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat reference data file: %w", err)
+	}
+	if info.ModTime().Equal(r.modTime) {
+		return nil
+	}
+
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read reference data file: %w", err)
+	}
+	var file referenceDataFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return fmt.Errorf("failed to parse reference data file: %w", err)
+	}
+
+	r.workCenters = make(map[string]models.SAPWorkCenter, len(file.WorkCenters))
+	for _, wc := range file.WorkCenters {
+		r.workCenters[workCenterKey(wc.Plant, wc.WorkCenter)] = wc
+	}
+	r.functionalLocations = make(map[string]models.SAPFunctionalLocation, len(file.FunctionalLocations))
+	for _, fl := range file.FunctionalLocations {
+		r.functionalLocations[fl.FunctionalLocation] = fl
+	}
+	r.modTime = info.ModTime()
+
+	r.logger.WithFields(logrus.Fields{
+		"file":                r.path,
+		"workCenters":         len(r.workCenters),
+		"functionalLocations": len(r.functionalLocations),
+	}).Info("Loaded simulator reference data")
+
+	return nil
+}
+
+// refresh reloads a changed file, keeping the previous data if the new content is invalid
+func (r *referenceData) refresh() {
+	if err := r.reload(); err != nil {
+		r.logger.WithError(err).Error("Failed to reload simulator reference data, keeping previous data")
+	}
+}
+
+func (r *referenceData) workCenter(plant, workCenter string) (*models.SAPWorkCenter, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refresh()
+	wc, ok := r.workCenters[workCenterKey(plant, workCenter)]
+	return &wc, ok
+}
+
+func (r *referenceData) functionalLocation(functionalLocation string) (*models.SAPFunctionalLocation, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refresh()
+	fl, ok := r.functionalLocations[functionalLocation]
+	return &fl, ok
+}
+
+// workCenterKey identifies a work center; work center IDs are unique per plant only
+func workCenterKey(plant, workCenter string) string {
+	return plant + "/" + workCenter
+}
@@ -0,0 +1,46 @@
+package sap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestReferenceDataReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reference-data.yaml")
+	if err := os.WriteFile(path, []byte("workCenters:\n  - plant: \"1000\"\n    workCenter: \"MECH-01\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{SimulatorMode: true, ReferenceDataFile: path}, logger)
+
+	if _, err := client.GetWorkCenter(context.Background(), "1000", "MECH-01"); err != nil {
+		t.Fatalf("Expected MECH-01 to exist, got %v", err)
+	}
+	if _, err := client.GetWorkCenter(context.Background(), "2000", "MECH-01"); !IsNotFound(err) {
+		t.Errorf("Expected MECH-01 not to exist in plant 2000, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("workCenters:\n  - plant: \"1000\"\n    workCenter: \"ELEC-01\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetWorkCenter(context.Background(), "1000", "MECH-01"); !IsNotFound(err) {
+		t.Errorf("Expected MECH-01 to be gone after reload, got %v", err)
+	}
+	if _, err := client.GetWorkCenter(context.Background(), "1000", "ELEC-01"); err != nil {
+		t.Errorf("Expected ELEC-01 to exist after reload, got %v", err)
+	}
+}
@@ -0,0 +1,510 @@
+package sap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// metadataRetryInterval is how long a failed $metadata fetch is remembered before retrying
+const metadataRetryInterval = time.Minute
+
+// ServiceMetadata is the parsed $metadata document of an OData v2 or v4 service
+type ServiceMetadata struct {
+	entitySets  map[string]string // entity set -> entity type
+	entityTypes map[string]*entityType
+}
+
+// entityType describes the properties and navigation properties of an OData entity type
+type entityType struct {
+	keys       map[string]bool
+	properties map[string]edmProperty
+	navigation map[string]string // navigation property -> target entity type
+}
+
+// metadataEntry caches the metadata of one service, or the error of the last fetch
+type metadataEntry struct {
+	metadata  *ServiceMetadata
+	err       error
+	fetchedAt time.Time
+}
+
+// EDMX document structure, shared by OData v2 and v4 (elements are matched by local name)
+type edmxDocument struct {
+	Schemas []edmSchema `xml:"DataServices>Schema"`
+}
+
+type edmSchema struct {
+	EntityTypes  []edmEntityType  `xml:"EntityType"`
+	Associations []edmAssociation `xml:"Association"`
+	EntitySets   []edmEntitySet   `xml:"EntityContainer>EntitySet"`
+}
+
+type edmEntityType struct {
+	Name string `xml:"Name,attr"`
+	Keys []struct {
+		Name string `xml:"Name,attr"`
+	} `xml:"Key>PropertyRef"`
+	Properties []edmProperty           `xml:"Property"`
+	Navigation []edmNavigationProperty `xml:"NavigationProperty"`
+}
+
+type edmProperty struct {
+	Name         string `xml:"Name,attr"`
+	Type         string `xml:"Type,attr"`
+	Nullable     string `xml:"Nullable,attr"`
+	MaxLength    string `xml:"MaxLength,attr"`
+	DefaultValue string `xml:"DefaultValue,attr"`
+	Creatable    string `xml:"creatable,attr"` // sap:creatable
+}
+
+type edmNavigationProperty struct {
+	Name         string `xml:"Name,attr"`
+	Type         string `xml:"Type,attr"`         // v4
+	Relationship string `xml:"Relationship,attr"` // v2
+	ToRole       string `xml:"ToRole,attr"`       // v2
+}
+
+type edmAssociation struct {
+	Name string `xml:"Name,attr"`
+	Ends []struct {
+		Role string `xml:"Role,attr"`
+		Type string `xml:"Type,attr"`
+	} `xml:"End"`
+}
+
+type edmEntitySet struct {
+	Name       string `xml:"Name,attr"`
+	EntityType string `xml:"EntityType,attr"`
+}
+
+// parseMetadata parses a $metadata document
+func parseMetadata(data []byte) (*ServiceMetadata, error) {
+	var doc edmxDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse $metadata: %w", err)
+	}
+
+	meta := &ServiceMetadata{
+		entitySets:  make(map[string]string),
+		entityTypes: make(map[string]*entityType),
+	}
+
+	// v2 navigation properties point to association ends
+	associationEnds := make(map[string]string)
+	for _, schema := range doc.Schemas {
+		for _, assoc := range schema.Associations {
+			for _, end := range assoc.Ends {
+				associationEnds[assoc.Name+"/"+end.Role] = unqualified(end.Type)
+			}
+		}
+	}
+
+	for _, schema := range doc.Schemas {
+		for _, set := range schema.EntitySets {
+			meta.entitySets[set.Name] = unqualified(set.EntityType)
+		}
+		for _, et := range schema.EntityTypes {
+			t := &entityType{
+				keys:       make(map[string]bool),
+				properties: make(map[string]edmProperty),
+				navigation: make(map[string]string),
+			}
+			for _, key := range et.Keys {
+				t.keys[key.Name] = true
+			}
+			for _, prop := range et.Properties {
+				t.properties[prop.Name] = prop
+			}
+			for _, nav := range et.Navigation {
+				if nav.Type != "" {
+					t.navigation[nav.Name] = unqualified(strings.TrimSuffix(strings.TrimPrefix(nav.Type, "Collection("), ")"))
+				} else {
+					t.navigation[nav.Name] = associationEnds[unqualified(nav.Relationship)+"/"+nav.ToRole]
+				}
+			}
+			meta.entityTypes[et.Name] = t
+		}
+	}
+
+	return meta, nil
+}
+
+// unqualified strips the namespace from a qualified OData name
+func unqualified(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// MetadataValidationEnabled reports whether payloads are checked against the SAP $metadata
+func (c *Client) MetadataValidationEnabled() bool {
+	return !c.simulatorMode && !c.config.SkipMetadataValidation
+}
+
+// Metadata returns the $metadata of an OData service (e.g. /API_MAINTENANCE_ORDER). It is
+// fetched once and cached; a failed fetch is retried after metadataRetryInterval.
+func (c *Client) Metadata(ctx context.Context, service string) (*ServiceMetadata, error) {
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+
+	if entry, ok := c.metadata[service]; ok && (entry.err == nil || time.Since(entry.fetchedAt) < metadataRetryInterval) {
+		return entry.metadata, entry.err
+	}
+
+	meta, err := c.fetchMetadata(ctx, service)
+	c.metadata[service] = &metadataEntry{metadata: meta, err: err, fetchedAt: time.Now()}
+	return meta, err
+}
+
+// fetchMetadata reads and parses the $metadata document of a service
+func (c *Client) fetchMetadata(ctx context.Context, service string) (*ServiceMetadata, error) {
+	c.logger.WithField("service", service).Info("Fetching SAP $metadata")
+
+	httpReq, err := c.newRequest(ctx, http.MethodGet, service+"/$metadata", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/xml")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return parseMetadata(body)
+}
+
+// outgoingPayload is a payload type the adaptor sends to an entity set
+type outgoingPayload struct {
+	service   string
+	entitySet string
+	payload   interface{}
+}
+
+// outgoingPayloads lists the payload types this client sends, per the configured protocol
+func (c *Client) outgoingPayloads() []outgoingPayload {
+	payloads := []outgoingPayload{
+		{notificationServicePath, "A_MaintenanceNotification", models.SAPNotificationRequest{}},
+		{notificationServicePath, "A_MaintenanceNotification", models.SAPNotificationUpdateRequest{}},
+		{confirmationServicePath, "MaintOrderConfirmation", models.SAPConfirmationRequest{}},
+	}
+	if c.odataV4() {
+		return append(payloads,
+			outgoingPayload{orderServiceV4Path, "MaintenanceOrder", models.SAPV4Order{}},
+			outgoingPayload{orderServiceV4Path, "MaintenanceOrderOperation", models.SAPV4OrderOperation{}},
+		)
+	}
+	return append(payloads,
+		outgoingPayload{orderServicePath, "A_MaintenanceOrder", models.SAPOrderRequest{}},
+		outgoingPayload{orderServicePath, "A_MaintenanceOrder", models.SAPOrderUpdateRequest{}},
+		outgoingPayload{orderServicePath, "A_MaintenanceOrderOperation", models.SAPOrderOperation{}},
+		outgoingPayload{orderServicePath, "A_MaintenanceOrderOperation", models.SAPOrderOperationUpdateRequest{}},
+	)
+}
+
+// CheckMetadata compares every field the adaptor sends with the $metadata of the destination
+// and returns the fields that do not exist or have an incompatible type
+func (c *Client) CheckMetadata(ctx context.Context) ([]models.MetadataDrift, error) {
+	var drift []models.MetadataDrift
+	seen := make(map[string]bool)
+	add := func(d models.MetadataDrift) {
+		key := d.Service + "/" + d.EntitySet + "/" + d.Field
+		if !seen[key] {
+			seen[key] = true
+			drift = append(drift, d)
+		}
+	}
+
+	for _, out := range c.outgoingPayloads() {
+		meta, err := c.Metadata(ctx, out.service)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read $metadata of %s: %w", out.service, err)
+		}
+		typeName, ok := meta.entitySets[out.entitySet]
+		if !ok {
+			add(models.MetadataDrift{Service: out.service, EntitySet: out.entitySet, Message: "entity set does not exist"})
+			continue
+		}
+		for _, d := range meta.checkStruct(typeName, reflect.TypeOf(out.payload), "") {
+			d.Service = out.service
+			d.EntitySet = out.entitySet
+			add(d)
+		}
+	}
+
+	// Custom fields are only known from the configuration
+	orderService, orderEntitySet := orderServicePath, "A_MaintenanceOrder"
+	if c.odataV4() {
+		orderService, orderEntitySet = orderServiceV4Path, "MaintenanceOrder"
+	}
+	for _, field := range c.config.ExtensionFields {
+		if field.NotificationField != "" {
+			d, err := c.checkProperty(ctx, notificationServicePath, "A_MaintenanceNotification", field.NotificationField)
+			if err != nil {
+				return nil, err
+			}
+			if d != nil {
+				add(*d)
+			}
+		}
+		if field.OrderField != "" {
+			d, err := c.checkProperty(ctx, orderService, orderEntitySet, field.OrderField)
+			if err != nil {
+				return nil, err
+			}
+			if d != nil {
+				add(*d)
+			}
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"destination": c.Name(),
+		"drift":       len(drift),
+	}).Info("SAP $metadata check completed")
+
+	return drift, nil
+}
+
+// checkProperty reports drift when an entity set has no property with the given name
+func (c *Client) checkProperty(ctx context.Context, service, entitySet, property string) (*models.MetadataDrift, error) {
+	meta, err := c.Metadata(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read $metadata of %s: %w", service, err)
+	}
+	typeName, ok := meta.entitySets[entitySet]
+	if !ok {
+		return &models.MetadataDrift{Service: service, EntitySet: entitySet, Message: "entity set does not exist"}, nil
+	}
+	if et, ok := meta.entityTypes[typeName]; ok {
+		if _, ok := et.properties[property]; ok {
+			return nil, nil
+		}
+	}
+	return &models.MetadataDrift{Service: service, EntitySet: entitySet, Field: property, Message: "extension field does not exist"}, nil
+}
+
+// checkStruct checks the JSON fields of a payload struct against an entity type
+func (m *ServiceMetadata) checkStruct(typeName string, t reflect.Type, prefix string) []models.MetadataDrift {
+	et, ok := m.entityTypes[typeName]
+	if !ok {
+		return []models.MetadataDrift{{Field: strings.TrimSuffix(prefix, "."), Message: fmt.Sprintf("entity type %s does not exist", typeName)}}
+	}
+
+	var drift []models.MetadataDrift
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			target, ok := et.navigation[name]
+			if !ok {
+				drift = append(drift, models.MetadataDrift{Field: prefix + name, Message: "navigation property does not exist"})
+				continue
+			}
+			drift = append(drift, m.checkStruct(target, field.Type.Elem(), prefix+name+".")...)
+			continue
+		}
+
+		prop, ok := et.properties[name]
+		if !ok {
+			// Optional properties are only sent when used, e.g. a task list reference
+			if field.Tag.Get("metadata") != "optional" {
+				drift = append(drift, models.MetadataDrift{Field: prefix + name, Message: "property does not exist"})
+			}
+			continue
+		}
+		if !compatibleKind(field.Type.Kind(), prop.Type) {
+			drift = append(drift, models.MetadataDrift{Field: prefix + name, Message: fmt.Sprintf("sent as %s but SAP expects %s", field.Type.Kind(), prop.Type)})
+		}
+	}
+	return drift
+}
+
+// jsonFieldName returns the JSON name of a struct field, or "" for fields that are not sent
+// as entity properties (control information such as @odata.etag or __metadata)
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" || strings.HasPrefix(name, "@") || strings.HasPrefix(name, "__") {
+		return ""
+	}
+	return name
+}
+
+// compatibleKind reports whether values of a Go kind can carry an Edm type. Decimals are sent
+// as strings in OData v2, so strings are accepted for numeric types.
+func compatibleKind(kind reflect.Kind, edmType string) bool {
+	switch edmType {
+	case "Edm.Boolean":
+		return kind == reflect.Bool
+	case "Edm.Decimal", "Edm.Double", "Edm.Single", "Edm.Int16", "Edm.Int32", "Edm.Int64", "Edm.Byte", "Edm.SByte":
+		return kind == reflect.String || (kind >= reflect.Int && kind <= reflect.Float64)
+	default:
+		return kind == reflect.String
+	}
+}
+
+// validatePayload checks a write to path against the $metadata of its service. Validation is
+// skipped when the metadata cannot be read, so SAP remains the final authority.
+func (c *Client) validatePayload(ctx context.Context, path string, body interface{}, create bool) error {
+	if !c.MetadataValidationEnabled() || body == nil {
+		return nil
+	}
+
+	service, entitySet, ok := splitEntityPath(path)
+	if !ok {
+		return nil
+	}
+	meta, err := c.Metadata(ctx, service)
+	if err != nil {
+		c.logger.WithError(err).WithField("service", service).Warn("SAP $metadata unavailable, payload not validated")
+		return nil
+	}
+	typeName, ok := meta.entitySets[entitySet]
+	if !ok {
+		return nil
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return fmt.Errorf("failed to decode request: %w", err)
+	}
+
+	if errs := meta.validateEntity(typeName, values, create, ""); len(errs) > 0 {
+		return &PayloadError{EntitySet: entitySet, Errors: errs}
+	}
+	return nil
+}
+
+// splitEntityPath splits /SERVICE/EntitySet('key') into the service and the entity set
+func splitEntityPath(path string) (string, string, bool) {
+	path, _, _ = strings.Cut(path, "?")
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "", "", false
+	}
+	service, entitySet := path[:i], path[i+1:]
+	entitySet, _, _ = strings.Cut(entitySet, "(")
+	if strings.Contains(service, "(") || entitySet == "" {
+		// Navigation from an entity, e.g. MaintenanceOrder('1')/_MaintenanceOrderOperation
+		return "", "", false
+	}
+	return service, entitySet, true
+}
+
+// validateEntity checks the values of an entity payload: unknown properties, max lengths and
+// types, and for creates the required properties
+func (m *ServiceMetadata) validateEntity(typeName string, values map[string]interface{}, create bool, prefix string) []models.FieldError {
+	et, ok := m.entityTypes[typeName]
+	if !ok {
+		return nil
+	}
+
+	var errs []models.FieldError
+	for _, name := range sortedKeys(values) {
+		value := values[name]
+		if strings.HasPrefix(name, "@") || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		if target, ok := et.navigation[name]; ok {
+			items, _ := value.([]interface{})
+			for i, item := range items {
+				if child, ok := item.(map[string]interface{}); ok {
+					errs = append(errs, m.validateEntity(target, child, create, fmt.Sprintf("%s%s[%d].", prefix, name, i))...)
+				}
+			}
+			continue
+		}
+
+		prop, ok := et.properties[name]
+		if !ok {
+			errs = append(errs, models.FieldError{Field: prefix + name, Message: "property does not exist in SAP"})
+			continue
+		}
+		if msg := checkValue(prop, value); msg != "" {
+			errs = append(errs, models.FieldError{Field: prefix + name, Value: fmt.Sprint(value), Message: msg})
+		}
+	}
+
+	if create {
+		for _, name := range sortedKeys(et.properties) {
+			prop := et.properties[name]
+			if _, sent := values[name]; sent || prop.Nullable != "false" || et.keys[name] || prop.DefaultValue != "" || prop.Creatable == "false" {
+				continue
+			}
+			errs = append(errs, models.FieldError{Field: prefix + name, Message: "required property is missing"})
+		}
+	}
+	return errs
+}
+
+// checkValue validates a single value against its property definition
+func checkValue(prop edmProperty, value interface{}) string {
+	if value == nil {
+		if prop.Nullable == "false" {
+			return "property must not be null"
+		}
+		return ""
+	}
+
+	switch prop.Type {
+	case "Edm.Boolean":
+		if _, ok := value.(bool); !ok {
+			return "expected Edm.Boolean"
+		}
+	case "Edm.Decimal", "Edm.Double", "Edm.Single", "Edm.Int16", "Edm.Int32", "Edm.Int64", "Edm.Byte", "Edm.SByte":
+		if _, err := strconv.ParseFloat(fmt.Sprint(value), 64); err != nil {
+			return "expected " + prop.Type
+		}
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return "expected " + prop.Type
+		}
+		if maxLength, err := strconv.Atoi(prop.MaxLength); err == nil && utf8.RuneCountInString(s) > maxLength {
+			return fmt.Sprintf("exceeds max length %d", maxLength)
+		}
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of a map in a stable order for reproducible error lists
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const testOrderMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="1.0" xmlns:edmx="http://schemas.microsoft.com/ado/2007/06/edmx" xmlns:sap="http://www.sap.com/Protocols/SAPData">
+ <edmx:DataServices m:DataServiceVersion="2.0" xmlns:m="http://schemas.microsoft.com/ado/2007/08/dataservices/metadata">
+  <Schema Namespace="API_MAINTENANCE_ORDER" xmlns="http://schemas.microsoft.com/ado/2008/09/edm">
+   <EntityType Name="A_MaintenanceOrderType">
+    <Key><PropertyRef Name="MaintenanceOrder"/></Key>
+    <Property Name="MaintenanceOrder" Type="Edm.String" Nullable="false" MaxLength="12"/>
+    <Property Name="MaintenanceOrderType" Type="Edm.String" Nullable="false" MaxLength="4"/>
+    <Property Name="Description" Type="Edm.String" MaxLength="40"/>
+    <Property Name="Equipment" Type="Edm.String" MaxLength="18"/>
+    <Property Name="FunctionalLocation" Type="Edm.String" MaxLength="40"/>
+    <Property Name="Plant" Type="Edm.String" Nullable="false" MaxLength="4"/>
+    <Property Name="MaintenancePlanningPlant" Type="Edm.String" Nullable="false" MaxLength="4"/>
+    <Property Name="Priority" Type="Edm.String" MaxLength="1"/>
+    <Property Name="MaintOrdBasicStartDateTime" Type="Edm.DateTimeOffset"/>
+    <Property Name="MaintOrdBasicEndDateTime" Type="Edm.DateTimeOffset"/>
+    <Property Name="MaintenanceNotification" Type="Edm.String" MaxLength="12"/>
+    <Property Name="OrderStatus" Type="Edm.String" MaxLength="4" sap:creatable="false"/>
+    <Property Name="YY1_Shift" Type="Edm.String" Nullable="false" MaxLength="2" sap:creatable="false"/>
+    <NavigationProperty Name="to_MaintenanceOrderOperation" Relationship="API_MAINTENANCE_ORDER.assoc_Order_Operation" FromRole="FromRole_Order" ToRole="ToRole_Operation"/>
+   </EntityType>
+   <EntityType Name="A_MaintenanceOrderOperationType">
+    <Key><PropertyRef Name="MaintenanceOrder"/><PropertyRef Name="MaintenanceOrderOperation"/></Key>
+    <Property Name="MaintenanceOrder" Type="Edm.String" Nullable="false" MaxLength="12"/>
+    <Property Name="MaintenanceOrderOperation" Type="Edm.String" Nullable="false" MaxLength="4"/>
+    <Property Name="OperationText" Type="Edm.String" MaxLength="40"/>
+    <Property Name="WorkCenter" Type="Edm.String" MaxLength="8"/>
+    <Property Name="Plant" Type="Edm.String" MaxLength="4"/>
+    <Property Name="OperationControlKey" Type="Edm.String" MaxLength="4"/>
+    <Property Name="OperationStandardDuration" Type="Edm.Decimal" Precision="5" Scale="1"/>
+    <Property Name="OperationDurationUnit" Type="Edm.String" MaxLength="3"/>
+   </EntityType>
+   <Association Name="assoc_Order_Operation">
+    <End Type="API_MAINTENANCE_ORDER.A_MaintenanceOrderType" Multiplicity="1" Role="FromRole_Order"/>
+    <End Type="API_MAINTENANCE_ORDER.A_MaintenanceOrderOperationType" Multiplicity="*" Role="ToRole_Operation"/>
+   </Association>
+   <EntityContainer Name="API_MAINTENANCE_ORDER_Entities" m:IsDefaultEntityContainer="true">
+    <EntitySet Name="A_MaintenanceOrder" EntityType="API_MAINTENANCE_ORDER.A_MaintenanceOrderType"/>
+    <EntitySet Name="A_MaintenanceOrderOperation" EntityType="API_MAINTENANCE_ORDER.A_MaintenanceOrderOperationType"/>
+   </EntityContainer>
+  </Schema>
+ </edmx:DataServices>
+</edmx:Edmx>`
+
+func TestCheckStructReportsDrift(t *testing.T) {
+	meta, err := parseMetadata([]byte(testOrderMetadata))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	drift := meta.checkStruct(meta.entitySets["A_MaintenanceOrder"], reflect.TypeOf(models.SAPOrderRequest{}), "")
+
+	var fields []string
+	for _, d := range drift {
+		fields = append(fields, d.Field+": "+d.Message)
+	}
+	expected := []string{
+		"MainWorkCenter: property does not exist",
+		"to_MaintenanceOrderOperation.to_MaintenanceOrderComponent: navigation property does not exist",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected drift %v, got %v", expected, fields)
+	}
+}
+
+func TestCreateOrderValidatesPayloadAgainstMetadata(t *testing.T) {
+	posted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/$metadata") {
+			io.WriteString(w, testOrderMetadata)
+			return
+		}
+		posted = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(config.SAPConfig{BaseURL: server.URL}, logrus.New())
+	_, err := client.CreateOrder(context.Background(), &models.SAPOrderRequest{
+		MaintenanceOrderType: "PM01",
+		Description:          strings.Repeat("x", 41),
+		Priority:             "1",
+		ToMaintenanceOrderOperation: []models.SAPOrderOperation{
+			{OperationText: "Disassemble", OperationStandardDuration: "four"},
+		},
+	})
+
+	var payloadErr *PayloadError
+	if !errors.As(err, &payloadErr) {
+		t.Fatalf("Expected PayloadError, got %v", err)
+	}
+	if posted {
+		t.Error("Expected invalid payload not to be sent")
+	}
+
+	var fields []string
+	for _, fieldErr := range payloadErr.Errors {
+		fields = append(fields, fieldErr.Field+": "+fieldErr.Message)
+	}
+	expected := []string{
+		"Description: exceeds max length 40",
+		"to_MaintenanceOrderOperation[0].OperationStandardDuration: expected Edm.Decimal",
+		"MaintenancePlanningPlant: required property is missing",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected field errors %v, got %v", expected, fields)
+	}
+}
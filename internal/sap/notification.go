@@ -0,0 +1,321 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const notificationServicePath = "/API_MAINTENANCE_NOTIFICATION"
+
+// notificationPhases maps SAP processing phases (MaintNotifProcessingPhase) to adaptor statuses
+var notificationPhases = map[string]string{
+	"1": "OUTSTANDING",
+	"2": "POSTPONED",
+	"3": "IN_PROCESS",
+	"4": "COMPLETED",
+	"5": "REJECTED", // Deletion flag set by the planner
+}
+
+// notificationPath returns the entity path of a maintenance notification
+func notificationPath(notificationID string) string {
+	return notificationServicePath + "/A_MaintenanceNotification('" + odataKey(notificationID) + "')"
+}
+
+// GetNotification retrieves a maintenance notification from SAP
+func (c *Client) GetNotification(ctx context.Context, notificationID string) (*models.SAPNotification, error) {
+	c.logger.WithFields(logrus.Fields{
+		"notificationId": notificationID,
+		"simulatorMode":  c.simulatorMode,
+	}).Info("Retrieving SAP maintenance notification")
+
+	// If in simulator mode, return mock response
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - returning mock notification")
+		notification := createMockNotification(notificationID, "10000045")
+		notification.Metadata.ETag = c.mockETag(notificationPath(notificationID))
+		return notification, nil
+	}
+
+	var notificationResp models.SAPNotificationDetailResponse
+	if err := c.doRequest(ctx, http.MethodGet, notificationPath(notificationID), nil, http.StatusOK, &notificationResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"notificationId": notificationResp.D.Notification,
+		"phase":          notificationResp.D.MaintNotifProcessingPhase,
+	}).Info("SAP maintenance notification retrieved successfully")
+
+	return &notificationResp.D, nil
+}
+
+// ListNotifications retrieves the notifications of an equipment. It returns at most limit
+// notifications starting at offset, and reports whether more notifications match.
+func (c *Client) ListNotifications(ctx context.Context, query *models.MaintenanceNotificationQuery, offset, limit int) ([]models.SAPNotification, bool, error) {
+	c.logger.WithFields(logrus.Fields{
+		"equipmentId":   query.EquipmentID,
+		"plant":         query.Plant,
+		"status":        query.Status,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Listing SAP maintenance notifications")
+
+	// If in simulator mode, return mock notifications for the equipment
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - returning mock notifications")
+		notifications, hasMore := listMockNotifications(query, offset, limit)
+		return notifications, hasMore, nil
+	}
+
+	filters := []string{fmt.Sprintf("Equipment eq '%s'", odataEscape(query.EquipmentID))}
+	if query.Plant != "" {
+		filters = append(filters, fmt.Sprintf("Plant eq '%s'", odataEscape(query.Plant)))
+	}
+	if query.Status != "" {
+		for phase, status := range notificationPhases {
+			if status == query.Status {
+				filters = append(filters, fmt.Sprintf("MaintNotifProcessingPhase eq '%s'", phase))
+			}
+		}
+	}
+
+	params := url.Values{}
+	params.Set("$filter", strings.Join(filters, " and "))
+	params.Set("$orderby", "Notification desc")
+	params.Set("$top", fmt.Sprint(limit+1))
+	if offset > 0 {
+		params.Set("$skip", fmt.Sprint(offset))
+	}
+
+	var listResp models.SAPNotificationListResponse
+	if err := c.doRequest(ctx, http.MethodGet, notificationServicePath+"/A_MaintenanceNotification?"+params.Encode(), nil, http.StatusOK, &listResp); err != nil {
+		return nil, false, err
+	}
+
+	notifications := listResp.D.Results
+	hasMore := len(notifications) > limit || listResp.D.Next != ""
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+
+	return notifications, hasMore, nil
+}
+
+// DuplicateDetection returns the settings for detecting repeated faults on an equipment
+func (c *Client) DuplicateDetection() config.DuplicateDetection {
+	return c.config.Duplicates
+}
+
+// maxOpenNotifications bounds the open notifications of an equipment read for duplicate detection;
+// the newest ones are kept
+const maxOpenNotifications = 200
+
+// FindOpenNotifications retrieves the outstanding, postponed and in-process notifications of an
+// equipment created since the given time, with their items. Server-driven paging (__next) is
+// followed up to maxOpenNotifications.
+func (c *Client) FindOpenNotifications(ctx context.Context, equipmentID string, since time.Time) ([]models.SAPNotification, error) {
+	c.logger.WithFields(logrus.Fields{
+		"equipmentId":   equipmentID,
+		"since":         since,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Searching open SAP maintenance notifications")
+
+	var notifications []models.SAPNotification
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - returning mock notifications")
+		for i := 9; i >= 0; i-- {
+			notifications = append(notifications, *createMockNotification(fmt.Sprintf("200000%03d", 100+i), equipmentID))
+		}
+	} else {
+		// CreationDate is a date, so the window is filtered by day in SAP and to the time below
+		params := url.Values{}
+		params.Set("$filter", fmt.Sprintf("Equipment eq '%s' and CreationDate ge datetime'%s' and (MaintNotifProcessingPhase eq '1' or MaintNotifProcessingPhase eq '2' or MaintNotifProcessingPhase eq '3')",
+			odataEscape(equipmentID), since.UTC().Format("2006-01-02")+"T00:00:00"))
+		params.Set("$expand", "to_Item")
+		params.Set("$orderby", "Notification desc")
+		params.Set("$top", fmt.Sprint(maxOpenNotifications))
+
+		path := notificationServicePath + "/A_MaintenanceNotification?" + params.Encode()
+		for links := 0; path != "" && len(notifications) < maxOpenNotifications; links++ {
+			if links > maxNextLinks {
+				return nil, fmt.Errorf("SAP notification search exceeded %d paging links", maxNextLinks)
+			}
+
+			var listResp models.SAPNotificationListResponse
+			if err := c.doRequest(ctx, http.MethodGet, path, nil, http.StatusOK, &listResp); err != nil {
+				return nil, err
+			}
+			notifications = append(notifications, listResp.D.Results...)
+			path = nextLinkPath(notificationServicePath, listResp.D.Next)
+		}
+		if len(notifications) > maxOpenNotifications {
+			notifications = notifications[:maxOpenNotifications]
+		}
+	}
+
+	// The exact creation time is compared here, where SAP returns it
+	var open []models.SAPNotification
+	for _, notification := range notifications {
+		status := notificationPhases[notification.MaintNotifProcessingPhase]
+		if status == "COMPLETED" || status == "REJECTED" {
+			continue
+		}
+		if created := parseSAPTime(notification.CreationDate); created != nil && created.Before(since) {
+			continue
+		}
+		open = append(open, notification)
+	}
+	return open, nil
+}
+
+// UpdateNotification changes a maintenance notification in SAP. etag is sent as If-Match;
+// without it ErrETagRequired is returned. The new ETag is returned on success.
+func (c *Client) UpdateNotification(ctx context.Context, notificationID string, req *models.SAPNotificationUpdateRequest, etag string) (string, error) {
+	c.logger.WithFields(logrus.Fields{
+		"notificationId": notificationID,
+		"etag":           etag,
+		"simulatorMode":  c.simulatorMode,
+	}).Info("Updating SAP maintenance notification")
+
+	newETag, err := c.patchEntity(ctx, notificationPath(notificationID), req, etag)
+	if err != nil {
+		return "", err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"notificationId": notificationID,
+		"etag":           newETag,
+	}).Info("SAP maintenance notification updated successfully")
+
+	return newETag, nil
+}
+
+// createMockNotification creates a mock notification whose phase depends on the notification ID
+func createMockNotification(notificationID, equipmentID string) *models.SAPNotification {
+	phase := "1"
+	orderID := ""
+	if len(notificationID) > 0 {
+		switch notificationID[len(notificationID)-1] {
+		case '3', '4', '5':
+			phase = "3" // Converted into an order
+		case '6', '7':
+			phase = "2"
+		case '8':
+			phase = "4"
+		case '9':
+			phase = "5"
+		}
+	}
+	if phase == "3" || phase == "4" {
+		orderID = "400000" + notificationID[max(0, len(notificationID)-3):]
+	}
+
+	created := time.Now().Add(-48 * time.Hour)
+	notification := &models.SAPNotification{
+		Notification:              notificationID,
+		NotificationType:          "M1",
+		Description:               "Mock maintenance notification",
+		Equipment:                 equipmentID,
+		FunctionalLocation:        "FL100-200-300",
+		Plant:                     "1000",
+		Priority:                  "3",
+		MaintNotifProcessingPhase: phase,
+		MaintenanceOrder:          orderID,
+		RequiredStartDate:         created.Add(24 * time.Hour).Format(time.RFC3339),
+		RequiredEndDate:           created.Add(72 * time.Hour).Format(time.RFC3339),
+		CreationDate:              created.Format(time.RFC3339),
+	}
+	if phase == "4" {
+		notification.CompletionDate = time.Now().Format(time.RFC3339)
+	}
+	notification.ToItem = &models.SAPNotificationItemList{Results: []models.SAPNotificationItem{{
+		MaintNotificationItem:       "0001",
+		MaintNotifItemText:          "Mock bearing wear",
+		MaintNotifDamageCodeGroup:   "PM-DMG",
+		MaintNotificationDamageCode: "WEAR",
+	}}}
+	return notification
+}
+
+// listMockNotifications returns a fixed set of simulator notifications for an equipment
+func listMockNotifications(query *models.MaintenanceNotificationQuery, offset, limit int) ([]models.SAPNotification, bool) {
+	var matches []models.SAPNotification
+	for i := 9; i >= 0; i-- {
+		notification := createMockNotification(fmt.Sprintf("200000%03d", 100+i), query.EquipmentID)
+		if query.Plant != "" && notification.Plant != query.Plant {
+			continue
+		}
+		if query.Status != "" && notificationPhases[notification.MaintNotifProcessingPhase] != query.Status {
+			continue
+		}
+		matches = append(matches, *notification)
+	}
+
+	if offset >= len(matches) {
+		return nil, false
+	}
+	end := offset + limit
+	if end >= len(matches) {
+		return matches[offset:], false
+	}
+	return matches[offset:end], true
+}
+
+// ConvertSAPNotificationToStatus converts an SAP notification to MaintenanceNotificationStatus
+func ConvertSAPNotificationToStatus(notification *models.SAPNotification) *models.MaintenanceNotificationStatus {
+	status := &models.MaintenanceNotificationStatus{
+		NotificationID:     notification.Notification,
+		NotificationType:   notification.NotificationType,
+		Status:             notificationPhases[notification.MaintNotifProcessingPhase],
+		Description:        notification.Description,
+		EquipmentID:        notification.Equipment,
+		FunctionalLocation: notification.FunctionalLocation,
+		Plant:              notification.Plant,
+		Priority:           notification.Priority,
+		OrderID:            notification.MaintenanceOrder,
+		RequiredStartTime:  parseSAPTime(notification.RequiredStartDate),
+		RequiredEndTime:    parseSAPTime(notification.RequiredEndDate),
+		CreatedAt:          parseSAPTime(notification.CreationDate),
+		CompletedAt:        parseSAPTime(notification.CompletionDate),
+		ETag:               notification.Metadata.ETag,
+	}
+	if status.Status == "" {
+		status.Status = "OUTSTANDING"
+	}
+	return status
+}
+
+// ConvertNotificationUpdateToSAP maps the non-empty fields of a notification update to SAP
+func ConvertNotificationUpdateToSAP(update *models.MaintenanceNotificationUpdate) *models.SAPNotificationUpdateRequest {
+	req := &models.SAPNotificationUpdateRequest{
+		Description: update.Description,
+		Priority:    update.Priority,
+	}
+	if update.RequiredStartTime != nil {
+		req.RequiredStartDate = update.RequiredStartTime.Format(time.RFC3339)
+	}
+	if update.RequiredEndTime != nil {
+		req.RequiredEndDate = update.RequiredEndTime.Format(time.RFC3339)
+	}
+	return req
+}
+
+// parseSAPTime parses an optional RFC3339 timestamp returned by SAP
+func parseSAPTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,151 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFindOpenNotificationsFiltersWindowAndFollowsNextLinks(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp models.SAPNotificationListResponse
+		if r.URL.Query().Get("$skiptoken") == "" {
+			if filter := r.URL.Query().Get("$filter"); !strings.Contains(filter, "CreationDate ge datetime'2025-08-18T00:00:00'") {
+				t.Errorf("Expected the window in the filter, got %q", filter)
+			}
+			if r.URL.Query().Get("$top") == "" {
+				t.Error("Expected $top to bound the search")
+			}
+			resp.D.Results = []models.SAPNotification{{Notification: "200000002", MaintNotifProcessingPhase: "1"}}
+			resp.D.Next = server.URL + "/API_MAINTENANCE_NOTIFICATION/A_MaintenanceNotification?$skiptoken=1"
+		} else {
+			resp.D.Results = []models.SAPNotification{{Notification: "200000001", MaintNotifProcessingPhase: "2"}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, Timeout: 5, SkipMetadataValidation: true}, logger)
+
+	since := time.Date(2025, 8, 18, 14, 30, 0, 0, time.UTC)
+	notifications, err := client.FindOpenNotifications(context.Background(), "10000045", since)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(notifications) != 2 || notifications[1].Notification != "200000001" {
+		t.Errorf("Expected the notifications of both pages, got %+v", notifications)
+	}
+}
+
+func TestGetAndListNotifications(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case notificationServicePath + "/A_MaintenanceNotification('200000123')":
+			var resp models.SAPNotificationDetailResponse
+			resp.D = models.SAPNotification{Notification: "200000123", Equipment: "10000045", MaintNotifProcessingPhase: "3", MaintenanceOrder: "400000123"}
+			resp.D.Metadata.ETag = `W/"1"`
+			json.NewEncoder(w).Encode(resp)
+		case notificationServicePath + "/A_MaintenanceNotification":
+			query := r.URL.Query()
+			if filter := query.Get("$filter"); filter != "Equipment eq '10000045' and MaintNotifProcessingPhase eq '1'" {
+				t.Errorf("Unexpected filter %q", filter)
+			}
+			if query.Get("$top") != "3" || query.Get("$skip") != "2" {
+				t.Errorf("Expected one notification more than the page after the offset, got $top=%s $skip=%s", query.Get("$top"), query.Get("$skip"))
+			}
+			var resp models.SAPNotificationListResponse
+			for _, id := range []string{"200000105", "200000104", "200000103"} {
+				resp.D.Results = append(resp.D.Results, models.SAPNotification{Notification: id, MaintNotifProcessingPhase: "1"})
+			}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, Timeout: 5, SkipMetadataValidation: true}, logger)
+
+	notification, err := client.GetNotification(context.Background(), "200000123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status := ConvertSAPNotificationToStatus(notification)
+	if status.Status != "IN_PROCESS" || status.OrderID != "400000123" || status.ETag != `W/"1"` {
+		t.Errorf("Unexpected notification status %+v", status)
+	}
+
+	notifications, hasMore, err := client.ListNotifications(context.Background(), &models.MaintenanceNotificationQuery{EquipmentID: "10000045", Status: "OUTSTANDING"}, 2, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(notifications) != 2 || !hasMore || notifications[1].Notification != "200000104" {
+		t.Errorf("Expected a full page with more to come, got %d notifications (more: %v)", len(notifications), hasMore)
+	}
+}
+
+func TestConvertSAPNotificationToStatusMapsPhases(t *testing.T) {
+	tests := map[string]string{
+		"":  "OUTSTANDING",
+		"1": "OUTSTANDING",
+		"2": "POSTPONED",
+		"3": "IN_PROCESS",
+		"4": "COMPLETED",
+		"5": "REJECTED",
+	}
+	for phase, expected := range tests {
+		status := ConvertSAPNotificationToStatus(&models.SAPNotification{Notification: "200000001", MaintNotifProcessingPhase: phase, CreationDate: "2025-08-18T08:00:00Z"})
+		if status.Status != expected {
+			t.Errorf("Phase %q: expected %s, got %s", phase, expected, status.Status)
+		}
+		if status.CreatedAt == nil || status.CreatedAt.Hour() != 8 {
+			t.Errorf("Phase %q: expected the creation date, got %v", phase, status.CreatedAt)
+		}
+	}
+}
+
+func TestUpdateNotificationSendsIfMatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != notificationServicePath+"/A_MaintenanceNotification('200000123')" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("If-Match") != `W/"1"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.Header().Set("ETag", `W/"2"`)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, Timeout: 5, SkipMetadataValidation: true}, logger)
+
+	update := ConvertNotificationUpdateToSAP(&models.MaintenanceNotificationUpdate{Priority: "1"})
+	etag, err := client.UpdateNotification(context.Background(), "200000123", update, `W/"1"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if etag != `W/"2"` {
+		t.Errorf("Expected the new ETag, got %s", etag)
+	}
+	if _, err := client.UpdateNotification(context.Background(), "200000123", update, `W/"0"`); err == nil {
+		t.Error("Expected a stale ETag to be refused")
+	}
+}
@@ -0,0 +1,124 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NextOperationNumber returns the operation number following the highest existing one,
+// rounded up to the next step of 10
+func NextOperationNumber(existing []string) string {
+	highest := 0
+	for _, id := range existing {
+		if n, err := strconv.Atoi(id); err == nil && n > highest {
+			highest = n
+		}
+	}
+	next := (highest/models.OperationNumberStep + 1) * models.OperationNumberStep
+	return fmt.Sprintf("%04d", next)
+}
+
+// CreateOrderOperation adds an operation to an existing maintenance order in SAP
+func (c *Client) CreateOrderOperation(ctx context.Context, req *models.SAPOrderOperation) (*models.SAPOrderOperationResponse, error) {
+	c.logger.WithFields(logrus.Fields{
+		"orderId":       req.MaintenanceOrder,
+		"operationId":   req.MaintenanceOrderOperation,
+		"workCenter":    req.WorkCenter,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Creating SAP maintenance order operation")
+
+	// If in simulator mode, return mock response
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - returning mock operation response")
+		return &models.SAPOrderOperationResponse{
+			MaintenanceOrder:          req.MaintenanceOrder,
+			MaintenanceOrderOperation: req.MaintenanceOrderOperation,
+			OperationText:             req.OperationText,
+			WorkCenter:                req.WorkCenter,
+			OperationControlKey:       req.OperationControlKey,
+			OperationStandardDuration: req.OperationStandardDuration,
+			OperationDurationUnit:     req.OperationDurationUnit,
+			OperationStatus:           "CRTD",
+		}, nil
+	}
+
+	if c.odataV4() {
+		return c.createOrderOperationV4(ctx, req)
+	}
+
+	var operationResp models.SAPOrderOperationCreateResponse
+	if err := c.doRequest(ctx, http.MethodPost, "/API_MAINTENANCE_ORDER/A_MaintenanceOrderOperation", req, http.StatusCreated, &operationResp); err != nil {
+		return nil, err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId":     operationResp.D.MaintenanceOrder,
+		"operationId": operationResp.D.MaintenanceOrderOperation,
+	}).Info("SAP maintenance order operation created successfully")
+
+	return &operationResp.D, nil
+}
+
+// DeleteOrderOperation removes an operation from a maintenance order in SAP. etag is sent as
+// If-Match; without it ErrETagRequired is returned.
+func (c *Client) DeleteOrderOperation(ctx context.Context, orderID, operationID, etag string) error {
+	c.logger.WithFields(logrus.Fields{
+		"orderId":       orderID,
+		"operationId":   operationID,
+		"etag":          etag,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Deleting SAP maintenance order operation")
+
+	path := c.operationEntityPath(orderID, operationID)
+	if etag == "" {
+		return ErrETagRequired
+	}
+
+	// If in simulator mode, check the ETag and drop the simulator state
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - applying mock delete")
+		if _, err := c.patchMockEntity(path, etag); err != nil {
+			return c.resolveConflict(ctx, orderID, err)
+		}
+		c.mockMu.Lock()
+		delete(c.mockETags, path)
+		c.mockMu.Unlock()
+		return nil
+	}
+
+	if _, err := c.doRequestWithHeaders(ctx, http.MethodDelete, path, map[string]string{"If-Match": etag}, nil, http.StatusNoContent, nil); err != nil {
+		return c.resolveConflict(ctx, orderID, err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId":     orderID,
+		"operationId": operationID,
+	}).Info("SAP maintenance order operation deleted successfully")
+
+	return nil
+}
+
+// ConvertMaintenanceOperationToSAP converts an operation to be added to an existing order. Its
+// properties are set by mapping (nil uses the default mapping) like the operations of a new order;
+// event holds the order fields the mapping reads. The operation number is taken from
+// op.OperationID when set, otherwise it follows the existing numbers.
+func ConvertMaintenanceOperationToSAP(orderID string, event *models.MaintenanceOrderEvent, op *models.MaintenanceOperation, existing []string, mapping *FieldMapping) *models.SAPOrderOperation {
+	operationID := NextOperationNumber(existing)
+	if op.OperationID != "" {
+		operationID = models.NormalizeOperationID(op.OperationID)
+	}
+
+	sapOp := &models.SAPOrderOperation{
+		MaintenanceOrder:          orderID,
+		MaintenanceOrderOperation: operationID,
+		OperationStandardDuration: strconv.FormatFloat(op.Duration, 'f', -1, 64),
+	}
+	mapping.ApplyOperation(event, op, sapOp)
+	return sapOp
+}
@@ -0,0 +1,247 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	orderServicePath = "/API_MAINTENANCE_ORDER"
+
+	// DefaultOrderPageSize is used when a search does not specify a limit
+	DefaultOrderPageSize = 50
+
+	// maxNextLinks bounds how many server-driven paging links are followed for one page
+	maxNextLinks = 20
+)
+
+// orderSortFields maps the sort keys of the adaptor API to SAP order properties
+var orderSortFields = map[string]string{
+	"orderId":      "MaintenanceOrder",
+	"plannedStart": "MaintOrdBasicStartDateTime",
+	"plannedEnd":   "MaintOrdBasicEndDateTime",
+	"priority":     "Priority",
+	"status":       "OrderStatus",
+}
+
+// ListOrders searches maintenance orders in SAP. It returns at most limit orders starting at
+// offset, and reports whether more orders match. Server-driven paging (__next) is followed
+// until the page is full.
+func (c *Client) ListOrders(ctx context.Context, query *models.MaintenanceOrderQuery, offset, limit int) ([]models.SAPOrder, bool, error) {
+	c.logger.WithFields(logrus.Fields{
+		"equipmentId":   query.EquipmentID,
+		"plant":         query.Plant,
+		"status":        query.Status,
+		"offset":        offset,
+		"limit":         limit,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Searching SAP maintenance orders")
+
+	// If in simulator mode, search the mock order set
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - searching mock orders")
+		orders, hasMore := c.listMockOrders(query, offset, limit)
+		return orders, hasMore, nil
+	}
+
+	if c.odataV4() {
+		return c.listOrdersV4(ctx, query, offset, limit)
+	}
+
+	// Ask for one extra order to find out whether there is a next page
+	params := BuildOrderQueryParams(query, offset, limit+1)
+	path := orderServicePath + "/A_MaintenanceOrder?" + params.Encode()
+
+	var orders []models.SAPOrder
+	for links := 0; path != "" && len(orders) <= limit; links++ {
+		if links > maxNextLinks {
+			return nil, false, fmt.Errorf("SAP order search exceeded %d paging links", maxNextLinks)
+		}
+
+		var listResp models.SAPOrderListResponse
+		if err := c.doRequest(ctx, http.MethodGet, path, nil, http.StatusOK, &listResp); err != nil {
+			return nil, false, err
+		}
+		orders = append(orders, listResp.D.Results...)
+		path = nextLinkPath(orderServicePath, listResp.D.Next)
+	}
+
+	hasMore := len(orders) > limit
+	if hasMore {
+		orders = orders[:limit]
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"count":   len(orders),
+		"hasMore": hasMore,
+	}).Info("SAP maintenance order search completed")
+
+	return orders, hasMore, nil
+}
+
+// nextLinkPath converts an OData __next (v2) or @odata.nextLink (v4) link of service into a
+// service path that newRequest resolves again, so the gateway root and logon parameters are
+// not duplicated
+func nextLinkPath(service, next string) string {
+	switch {
+	case next == "":
+		return ""
+	case strings.HasPrefix(next, "http://") || strings.HasPrefix(next, "https://") || strings.HasPrefix(next, "/"):
+		// Skip everything up to and including the service segment (which may carry ;v=0002)
+		i := strings.Index(next, service)
+		if i < 0 {
+			return ""
+		}
+		rest := next[i+len(service):]
+		if j := strings.IndexAny(rest, "/?"); j >= 0 {
+			return service + rest[j:]
+		}
+		return ""
+	default:
+		return service + "/" + next
+	}
+}
+
+// BuildOrderQueryParams translates an order search into OData $filter, $orderby, $top and $skip
+func BuildOrderQueryParams(query *models.MaintenanceOrderQuery, offset, top int) url.Values {
+	return buildOrderQueryParams(query, offset, top, false)
+}
+
+// buildOrderQueryParams builds the search parameters for the v2 or the v4 order service, which
+// differ in property names and date literals
+func buildOrderQueryParams(query *models.MaintenanceOrderQuery, offset, top int, v4 bool) url.Values {
+	name := func(property string) string {
+		if renamed, ok := orderPropertiesV4[property]; ok && v4 {
+			return renamed
+		}
+		return property
+	}
+
+	var filters []string
+	addEq := func(property, value string) {
+		if value != "" {
+			filters = append(filters, fmt.Sprintf("%s eq '%s'", name(property), odataEscape(value)))
+		}
+	}
+	addRange := func(property, op string, value *time.Time) {
+		if value == nil {
+			return
+		}
+		literal := "datetimeoffset'" + value.UTC().Format(time.RFC3339) + "'"
+		if v4 {
+			literal = value.UTC().Format(time.RFC3339)
+		}
+		filters = append(filters, fmt.Sprintf("%s %s %s", name(property), op, literal))
+	}
+
+	addEq("Equipment", query.EquipmentID)
+	addEq("FunctionalLocation", query.FunctionalLocation)
+	addEq("Plant", query.Plant)
+	addEq("OrderStatus", query.Status)
+	addEq("MaintenanceOrderType", query.OrderType)
+	addEq("Priority", query.Priority)
+	addRange("MaintOrdBasicStartDateTime", "ge", query.PlannedStartFrom)
+	addRange("MaintOrdBasicStartDateTime", "le", query.PlannedStartTo)
+	addRange("MaintOrdBasicEndDateTime", "ge", query.PlannedEndFrom)
+	addRange("MaintOrdBasicEndDateTime", "le", query.PlannedEndTo)
+
+	params := url.Values{}
+	if len(filters) > 0 {
+		params.Set("$filter", strings.Join(filters, " and "))
+	}
+
+	sortField := orderSortFields["orderId"]
+	if field, ok := orderSortFields[query.SortBy]; ok {
+		sortField = name(field)
+	}
+	if query.SortOrder == "desc" {
+		sortField += " desc"
+	}
+	params.Set("$orderby", sortField)
+	params.Set("$top", fmt.Sprint(top))
+	if offset > 0 {
+		params.Set("$skip", fmt.Sprint(offset))
+	}
+
+	return params
+}
+
+// odataEscape escapes a string literal for use in an OData filter
+func odataEscape(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
+// odataKey escapes a value for a quoted key predicate in an entity path
+func odataKey(value string) string {
+	return url.PathEscape(odataEscape(value))
+}
+
+// listMockOrders searches a fixed set of simulator orders
+func (c *Client) listMockOrders(query *models.MaintenanceOrderQuery, offset, limit int) ([]models.SAPOrder, bool) {
+	var matches []models.SAPOrder
+	for i := 0; i < 40; i++ {
+		order := c.createMockOrderStatusResponse(fmt.Sprintf("400000%03d", 100+i)).D
+		if i%2 == 1 {
+			order.Equipment = "10000046"
+		}
+		if i >= 20 {
+			order.Plant = "2000"
+		}
+		order.Priority = fmt.Sprint(1 + i%4)
+		if !matchesMockOrder(&order, query) {
+			continue
+		}
+		matches = append(matches, order)
+	}
+
+	less := OrderLess(query.SortBy, query.SortOrder)
+	sort.SliceStable(matches, func(i, j int) bool { return less(&matches[i], &matches[j]) })
+
+	if offset >= len(matches) {
+		return nil, false
+	}
+	end := offset + limit
+	if end >= len(matches) {
+		return matches[offset:], false
+	}
+	return matches[offset:end], true
+}
+
+// OrderLess orders SAP orders like the $orderby of a search with sortBy and sortOrder, so orders
+// searched in several destinations can be merged into one page
+func OrderLess(sortBy, sortOrder string) func(a, b *models.SAPOrder) bool {
+	key := func(o *models.SAPOrder) string { return o.MaintenanceOrder }
+	switch sortBy {
+	case "plannedStart":
+		key = func(o *models.SAPOrder) string { return o.MaintOrdBasicStartDateTime }
+	case "plannedEnd":
+		key = func(o *models.SAPOrder) string { return o.MaintOrdBasicEndDateTime }
+	case "priority":
+		key = func(o *models.SAPOrder) string { return o.Priority }
+	case "status":
+		key = func(o *models.SAPOrder) string { return o.OrderStatus }
+	}
+	if sortOrder == "desc" {
+		return func(a, b *models.SAPOrder) bool { return key(a) > key(b) }
+	}
+	return func(a, b *models.SAPOrder) bool { return key(a) < key(b) }
+}
+
+// matchesMockOrder applies the equality filters that the simulator orders carry
+func matchesMockOrder(order *models.SAPOrder, query *models.MaintenanceOrderQuery) bool {
+	return (query.EquipmentID == "" || order.Equipment == query.EquipmentID) &&
+		(query.FunctionalLocation == "" || order.FunctionalLocation == query.FunctionalLocation) &&
+		(query.Plant == "" || order.Plant == query.Plant) &&
+		(query.Priority == "" || order.Priority == query.Priority) &&
+		(query.Status == "" || order.OrderStatus == query.Status) &&
+		(query.OrderType == "" || order.MaintenanceOrderType == query.OrderType)
+}
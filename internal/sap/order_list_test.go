@@ -0,0 +1,69 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBuildOrderQueryParams(t *testing.T) {
+	from := time.Date(2025, 8, 21, 8, 0, 0, 0, time.UTC)
+	query := &models.MaintenanceOrderQuery{
+		EquipmentID:      "10000045",
+		Plant:            "1000",
+		PlannedStartFrom: &from,
+		SortBy:           "plannedStart",
+		SortOrder:        "desc",
+	}
+
+	params := BuildOrderQueryParams(query, 20, 11)
+
+	expectedFilter := "Equipment eq '10000045' and Plant eq '1000' and MaintOrdBasicStartDateTime ge datetimeoffset'2025-08-21T08:00:00Z'"
+	if got := params.Get("$filter"); got != expectedFilter {
+		t.Errorf("Expected filter %q, got %q", expectedFilter, got)
+	}
+	if got := params.Get("$orderby"); got != "MaintOrdBasicStartDateTime desc" {
+		t.Errorf("Expected orderby MaintOrdBasicStartDateTime desc, got %q", got)
+	}
+	if params.Get("$top") != "11" || params.Get("$skip") != "20" {
+		t.Errorf("Expected $top=11 and $skip=20, got %s and %s", params.Get("$top"), params.Get("$skip"))
+	}
+}
+
+func TestListOrdersFollowsNextLinks(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp models.SAPOrderListResponse
+		if r.URL.Query().Get("$skiptoken") == "" {
+			resp.D.Results = []models.SAPOrder{{MaintenanceOrder: "400000001"}, {MaintenanceOrder: "400000002"}}
+			resp.D.Next = server.URL + "/API_MAINTENANCE_ORDER/A_MaintenanceOrder?$skiptoken=2"
+		} else {
+			resp.D.Results = []models.SAPOrder{{MaintenanceOrder: "400000003"}, {MaintenanceOrder: "400000004"}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, Timeout: 5}, logger)
+
+	orders, hasMore, err := client.ListOrders(context.Background(), &models.MaintenanceOrderQuery{}, 0, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(orders) != 3 || orders[2].MaintenanceOrder != "400000003" {
+		t.Errorf("Expected 3 orders across both pages, got %+v", orders)
+	}
+	if !hasMore {
+		t.Error("Expected more orders to be reported")
+	}
+}
@@ -0,0 +1,227 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConflictError is returned when SAP rejects a write because the entity was
+// changed after its ETag was read (HTTP 412 Precondition Failed)
+type ConflictError struct {
+	OrderID     string
+	CurrentETag string
+	Current     *models.SAPOrderResponse
+}
+
+// Error implements the error interface
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("SAP order %s was modified concurrently (current ETag %s)", e.OrderID, e.CurrentETag)
+}
+
+// ErrETagRequired is returned for a change that does not name the version it is based on
+var ErrETagRequired = errors.New("an ETag is required to change an SAP entity")
+
+// AnyETag matches every version of an entity. It is only used for changes the adaptor makes on
+// its own behalf, such as notes on an open notification, never for changes sent by a client.
+const AnyETag = "*"
+
+// IsConflict reports whether err is a concurrent modification conflict
+func IsConflict(err error) bool {
+	var conflictErr *ConflictError
+	return errors.As(err, &conflictErr)
+}
+
+// orderPath returns the entity path of a maintenance order
+func orderPath(orderID string) string {
+	return "/API_MAINTENANCE_ORDER/A_MaintenanceOrder('" + odataKey(orderID) + "')"
+}
+
+// operationPath returns the entity path of a maintenance order operation
+func operationPath(orderID, operationID string) string {
+	return fmt.Sprintf("/API_MAINTENANCE_ORDER/A_MaintenanceOrderOperation(MaintenanceOrder='%s',MaintenanceOrderOperation='%s')",
+		odataKey(orderID), odataKey(models.NormalizeOperationID(operationID)))
+}
+
+// GetETag reads the current ETag of an SAP entity
+func (c *Client) GetETag(ctx context.Context, path string) (string, error) {
+	if c.simulatorMode {
+		return c.mockETag(path), nil
+	}
+
+	headers, err := c.doRequestWithHeaders(ctx, http.MethodGet, path, nil, nil, http.StatusOK, nil)
+	if err != nil {
+		return "", err
+	}
+	return headers.Get("ETag"), nil
+}
+
+// GetOrderETag reads the current ETag of a maintenance order
+func (c *Client) GetOrderETag(ctx context.Context, orderID string) (string, error) {
+	return c.GetETag(ctx, c.orderEntityPath(orderID))
+}
+
+// UpdateOrder changes header fields of a maintenance order. etag is sent as If-Match; without it
+// ErrETagRequired is returned. The new ETag is returned on success.
+func (c *Client) UpdateOrder(ctx context.Context, orderID string, req *models.SAPOrderUpdateRequest, etag string) (string, error) {
+	c.logger.WithFields(logrus.Fields{
+		"orderId":       orderID,
+		"etag":          etag,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Updating SAP maintenance order")
+
+	newETag, err := c.patchEntity(ctx, c.orderEntityPath(orderID), c.orderUpdateBody(req), etag)
+	if err != nil {
+		return "", c.resolveConflict(ctx, orderID, err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId": orderID,
+		"etag":    newETag,
+	}).Info("SAP maintenance order updated successfully")
+
+	return newETag, nil
+}
+
+// UpdateOrderOperation changes an operation of a maintenance order. etag is sent as If-Match;
+// without it ErrETagRequired is returned. The new ETag is returned on success.
+func (c *Client) UpdateOrderOperation(ctx context.Context, orderID, operationID string, req *models.SAPOrderOperationUpdateRequest, etag string) (string, error) {
+	c.logger.WithFields(logrus.Fields{
+		"orderId":       orderID,
+		"operationId":   operationID,
+		"etag":          etag,
+		"simulatorMode": c.simulatorMode,
+	}).Info("Updating SAP maintenance order operation")
+
+	newETag, err := c.patchEntity(ctx, c.operationEntityPath(orderID, operationID), c.operationUpdateBody(req), etag)
+	if err != nil {
+		return "", c.resolveConflict(ctx, orderID, err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId":     orderID,
+		"operationId": operationID,
+		"etag":        newETag,
+	}).Info("SAP maintenance order operation updated successfully")
+
+	return newETag, nil
+}
+
+// patchEntity sends a PATCH with If-Match for the given entity and returns the new ETag
+func (c *Client) patchEntity(ctx context.Context, path string, body interface{}, etag string) (string, error) {
+	if etag == "" {
+		return "", ErrETagRequired
+	}
+
+	// If in simulator mode, check the ETag against the simulator state
+	if c.simulatorMode {
+		c.logger.Info("Running in simulator mode - applying mock update")
+		return c.patchMockEntity(path, etag)
+	}
+
+	headers, err := c.doRequestWithHeaders(ctx, http.MethodPatch, path, map[string]string{"If-Match": etag}, body, http.StatusNoContent, nil)
+	if err != nil {
+		return "", err
+	}
+	if newETag := headers.Get("ETag"); newETag != "" {
+		return newETag, nil
+	}
+	return c.GetETag(ctx, path)
+}
+
+// resolveConflict turns an SAP 412 response into a ConflictError carrying the current order version
+func (c *Client) resolveConflict(ctx context.Context, orderID string, err error) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusPreconditionFailed {
+		return err
+	}
+
+	conflict := &ConflictError{OrderID: orderID}
+	if current, getErr := c.GetOrder(ctx, orderID); getErr == nil {
+		conflict.Current = current
+	}
+	if etag, etagErr := c.GetETag(ctx, c.orderEntityPath(orderID)); etagErr == nil {
+		conflict.CurrentETag = etag
+	}
+	return conflict
+}
+
+// mockETag returns the simulator ETag of an entity, creating one on first access
+func (c *Client) mockETag(path string) string {
+	c.mockMu.Lock()
+	defer c.mockMu.Unlock()
+
+	etag, ok := c.mockETags[path]
+	if !ok {
+		etag = fmt.Sprintf("W/\"%s\"", strconv.FormatInt(time.Now().UnixNano(), 36))
+		c.mockETags[path] = etag
+	}
+	return etag
+}
+
+// patchMockEntity applies an update to the simulator state, failing with 412 on a stale ETag
+func (c *Client) patchMockEntity(path, etag string) (string, error) {
+	current := c.mockETag(path)
+
+	c.mockMu.Lock()
+	defer c.mockMu.Unlock()
+
+	if etag != current && etag != "*" {
+		return "", &APIError{StatusCode: http.StatusPreconditionFailed, Body: "ETag mismatch"}
+	}
+
+	newETag := fmt.Sprintf("W/\"%s\"", strconv.FormatInt(time.Now().UnixNano(), 36))
+	c.mockETags[path] = newETag
+	return newETag, nil
+}
+
+// ConvertMaintenanceOrderEventToOrderUpdate maps the non-empty header fields of an event to an SAP
+// order update. It returns the names of the changed fields; equipment, plant and order type cannot be
+// changed on an existing order and are ignored.
+func ConvertMaintenanceOrderEventToOrderUpdate(event *models.MaintenanceOrderEvent) (*models.SAPOrderUpdateRequest, []string) {
+	req := &models.SAPOrderUpdateRequest{}
+	var changed []string
+
+	if event.Description != "" {
+		req.Description = event.Description
+		changed = append(changed, "description")
+	}
+	if event.FunctionalLocation != "" {
+		req.FunctionalLocation = event.FunctionalLocation
+		changed = append(changed, "functionalLocation")
+	}
+	if event.Priority != "" {
+		req.Priority = event.Priority
+		changed = append(changed, "priority")
+	}
+	if event.PlannedStartTime != nil {
+		req.MaintOrdBasicStartDateTime = event.PlannedStartTime.Format(time.RFC3339)
+		changed = append(changed, "plannedStartTime")
+	}
+	if event.PlannedEndTime != nil {
+		req.MaintOrdBasicEndDateTime = event.PlannedEndTime.Format(time.RFC3339)
+		changed = append(changed, "plannedEndTime")
+	}
+
+	return req, changed
+}
+
+// ConvertMaintenanceOperationToUpdate maps the non-empty fields of an operation to an SAP operation update
+func ConvertMaintenanceOperationToUpdate(op *models.MaintenanceOperation) *models.SAPOrderOperationUpdateRequest {
+	req := &models.SAPOrderOperationUpdateRequest{
+		OperationText:         op.Text,
+		WorkCenter:            op.WorkCenter,
+		OperationDurationUnit: op.DurationUnit,
+	}
+	if op.Duration > 0 {
+		req.OperationStandardDuration = strconv.FormatFloat(op.Duration, 'f', -1, 64)
+	}
+	return req
+}
@@ -0,0 +1,94 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestUpdateOrderSendsIfMatchAndMapsConflicts(t *testing.T) {
+	var mu sync.Mutex
+	etags := map[string]string{
+		"/API_MAINTENANCE_ORDER/A_MaintenanceOrder('4000''01')": `W/"1"`,
+		"/API_MAINTENANCE_ORDER/A_MaintenanceOrderOperation(MaintenanceOrder='4000''01',MaintenanceOrderOperation='0010')": `W/"1"`,
+	}
+	version := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		current, ok := etags[r.URL.Path]
+		if !ok {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", current)
+			json.NewEncoder(w).Encode(models.SAPOrderResponse{D: models.SAPOrder{MaintenanceOrder: "4000'01", Description: "Changed elsewhere"}})
+		case http.MethodPatch:
+			if r.Header.Get("If-Match") != current {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			version++
+			etags[r.URL.Path] = `W/"` + strings.Repeat("x", version) + `"`
+			w.Header().Set("ETag", etags[r.URL.Path])
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, Timeout: 5, SkipMetadataValidation: true}, logger)
+	ctx := context.Background()
+	update := &models.SAPOrderUpdateRequest{Description: "Replace bearing"}
+
+	// A change without ETag is refused before anything is sent
+	if _, err := client.UpdateOrder(ctx, "4000'01", update, ""); !errors.Is(err, ErrETagRequired) {
+		t.Fatalf("Expected ErrETagRequired, got %v", err)
+	}
+
+	// The quote in the key is doubled and the new ETag is read from the response
+	newETag, err := client.UpdateOrder(ctx, "4000'01", update, `W/"1"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if newETag != `W/"xx"` {
+		t.Errorf("Expected the ETag of the response, got %s", newETag)
+	}
+
+	// The ETag read before the change is stale now
+	_, err = client.UpdateOrder(ctx, "4000'01", update, `W/"1"`)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a ConflictError, got %v", err)
+	}
+	if conflict.CurrentETag != newETag || conflict.Current == nil || conflict.Current.D.Description != "Changed elsewhere" {
+		t.Errorf("Expected the current order and ETag, got %+v", conflict)
+	}
+
+	// Operations are changed with their own ETag
+	opETag, err := client.UpdateOrderOperation(ctx, "4000'01", "10", &models.SAPOrderOperationUpdateRequest{OperationText: "Inspect"}, `W/"1"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.UpdateOrderOperation(ctx, "4000'01", "10", &models.SAPOrderOperationUpdateRequest{OperationText: "Inspect"}, `W/"1"`); !IsConflict(err) {
+		t.Errorf("Expected a conflict for the stale operation ETag, got %v", err)
+	}
+	if opETag == `W/"1"` {
+		t.Error("Expected a new operation ETag")
+	}
+}
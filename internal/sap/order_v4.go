@@ -0,0 +1,287 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ODataV2 and ODataV4 are the protocols of the maintenance order service a destination can use
+	ODataV2 = "v2"
+	ODataV4 = "v4"
+
+	// orderServiceV4Path is the OData v4 maintenance order service of newer S/4HANA releases
+	orderServiceV4Path = "/api_maintenanceorder/srvd_a2x/sap/maintenanceorder/0001"
+
+	// orderExpandV4 reads operations and components with the order
+	orderExpandV4 = "_MaintenanceOrderOperation($expand=_MaintenanceOrderComponent)"
+)
+
+// orderPropertiesV4 maps order properties whose name differs in the v4 service
+var orderPropertiesV4 = map[string]string{
+	"Description": "MaintenanceOrderDesc",
+	"Plant":       "MaintenancePlant",
+	"Priority":    "MaintPriority",
+	"OrderStatus": "MaintenanceOrderSystemStatus",
+}
+
+// validateODataVersion checks the configured protocol of a destination
+func validateODataVersion(version string) error {
+	switch version {
+	case "", ODataV2, ODataV4:
+		return nil
+	default:
+		return fmt.Errorf("unsupported OData version %q (use %s or %s)", version, ODataV2, ODataV4)
+	}
+}
+
+// odataV4 reports whether maintenance orders are accessed through the OData v4 service
+func (c *Client) odataV4() bool {
+	return c.config.ODataVersion == ODataV4
+}
+
+// orderService returns the service path of the maintenance order service
+func (c *Client) orderService() string {
+	if c.odataV4() {
+		return orderServiceV4Path
+	}
+	return orderServicePath
+}
+
+// orderEntityPath returns the entity path of a maintenance order for the configured protocol
+func (c *Client) orderEntityPath(orderID string) string {
+	if c.odataV4() {
+		return orderServiceV4Path + "/MaintenanceOrder('" + odataKey(orderID) + "')"
+	}
+	return orderPath(orderID)
+}
+
+// operationEntityPath returns the entity path of an operation for the configured protocol
+func (c *Client) operationEntityPath(orderID, operationID string) string {
+	if c.odataV4() {
+		return fmt.Sprintf("%s/MaintenanceOrderOperation(MaintenanceOrder='%s',MaintenanceOrderOperation='%s')",
+			orderServiceV4Path, odataKey(orderID), odataKey(models.NormalizeOperationID(operationID)))
+	}
+	return operationPath(orderID, operationID)
+}
+
+// orderUpdateBody returns the PATCH payload of an order update for the configured protocol
+func (c *Client) orderUpdateBody(req *models.SAPOrderUpdateRequest) interface{} {
+	if c.odataV4() {
+		return ConvertSAPOrderUpdateToV4(req)
+	}
+	return req
+}
+
+// operationUpdateBody returns the PATCH payload of an operation update for the configured protocol
+func (c *Client) operationUpdateBody(req *models.SAPOrderOperationUpdateRequest) interface{} {
+	if c.odataV4() {
+		return ConvertSAPOperationUpdateToV4(req)
+	}
+	return req
+}
+
+// createOrderV4 creates a maintenance order with its operations through the v4 service
+func (c *Client) createOrderV4(ctx context.Context, req *models.SAPOrderRequest) (*models.SAPOrderResponse, error) {
+	var order models.SAPV4Order
+	if err := c.doRequest(ctx, http.MethodPost, orderServiceV4Path+"/MaintenanceOrder", ConvertSAPOrderRequestToV4(req), http.StatusCreated, &order); err != nil {
+		return nil, err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId": order.MaintenanceOrder,
+		"status":  order.MaintenanceOrderSystemStatus,
+	}).Info("SAP maintenance order created successfully (OData v4)")
+
+	return &models.SAPOrderResponse{D: *ConvertSAPV4Order(&order)}, nil
+}
+
+// getOrderV4 reads a maintenance order with operations and components through the v4 service
+func (c *Client) getOrderV4(ctx context.Context, orderID string) (*models.SAPOrderResponse, error) {
+	params := url.Values{}
+	params.Set("$expand", orderExpandV4)
+
+	var order models.SAPV4Order
+	if err := c.doRequest(ctx, http.MethodGet, c.orderEntityPath(orderID)+"?"+params.Encode(), nil, http.StatusOK, &order); err != nil {
+		return nil, err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"orderId": order.MaintenanceOrder,
+		"status":  order.MaintenanceOrderSystemStatus,
+	}).Info("SAP maintenance order retrieved successfully (OData v4)")
+
+	return &models.SAPOrderResponse{D: *ConvertSAPV4Order(&order)}, nil
+}
+
+// listOrdersV4 searches maintenance orders through the v4 service, following @odata.nextLink
+// until the page is full
+func (c *Client) listOrdersV4(ctx context.Context, query *models.MaintenanceOrderQuery, offset, limit int) ([]models.SAPOrder, bool, error) {
+	params := buildOrderQueryParams(query, offset, limit+1, true)
+	path := orderServiceV4Path + "/MaintenanceOrder?" + params.Encode()
+
+	var orders []models.SAPOrder
+	for links := 0; path != "" && len(orders) <= limit; links++ {
+		if links > maxNextLinks {
+			return nil, false, fmt.Errorf("SAP order search exceeded %d paging links", maxNextLinks)
+		}
+
+		var listResp models.SAPV4OrderListResponse
+		if err := c.doRequest(ctx, http.MethodGet, path, nil, http.StatusOK, &listResp); err != nil {
+			return nil, false, err
+		}
+		for i := range listResp.Value {
+			orders = append(orders, *ConvertSAPV4Order(&listResp.Value[i]))
+		}
+		path = nextLinkPath(orderServiceV4Path, listResp.NextLink)
+	}
+
+	hasMore := len(orders) > limit
+	if hasMore {
+		orders = orders[:limit]
+	}
+	return orders, hasMore, nil
+}
+
+// createOrderOperationV4 adds an operation to an order through the v4 navigation property
+func (c *Client) createOrderOperationV4(ctx context.Context, req *models.SAPOrderOperation) (*models.SAPOrderOperationResponse, error) {
+	var operation models.SAPV4OrderOperation
+	path := c.orderEntityPath(req.MaintenanceOrder) + "/_MaintenanceOrderOperation"
+	if err := c.doRequest(ctx, http.MethodPost, path, convertSAPOperationToV4(req), http.StatusCreated, &operation); err != nil {
+		return nil, err
+	}
+	return convertSAPV4Operation(&operation), nil
+}
+
+// ConvertSAPOrderRequestToV4 converts an order deep insert to the v4 payload
+func ConvertSAPOrderRequestToV4(req *models.SAPOrderRequest) *models.SAPV4Order {
+	order := &models.SAPV4Order{
+		MaintenanceOrderType:       req.MaintenanceOrderType,
+		MaintenanceOrderDesc:       req.Description,
+		Equipment:                  req.Equipment,
+		FunctionalLocation:         req.FunctionalLocation,
+		MaintenancePlant:           req.Plant,
+		MaintenancePlanningPlant:   req.MaintenancePlanningPlant,
+		MainWorkCenter:             req.MainWorkCenter,
+		MaintPriority:              req.Priority,
+		MaintOrdBasicStartDateTime: req.MaintOrdBasicStartDateTime,
+		MaintOrdBasicEndDateTime:   req.MaintOrdBasicEndDateTime,
+		MaintenanceNotification:    req.MaintenanceNotification,
+		TaskListType:               req.TaskListType,
+		TaskListGroup:              req.TaskListGroup,
+		TaskListGroupCounter:       req.TaskListGroupCounter,
+		Extensions:                 req.Extensions,
+	}
+	for i := range req.ToMaintenanceOrderOperation {
+		order.Operations = append(order.Operations, *convertSAPOperationToV4(&req.ToMaintenanceOrderOperation[i]))
+	}
+	return order
+}
+
+// convertSAPOperationToV4 converts an operation and its components to the v4 payload
+func convertSAPOperationToV4(op *models.SAPOrderOperation) *models.SAPV4OrderOperation {
+	operation := &models.SAPV4OrderOperation{
+		MaintenanceOrder:          op.MaintenanceOrder,
+		MaintenanceOrderOperation: op.MaintenanceOrderOperation,
+		OperationDescription:      op.OperationText,
+		WorkCenter:                op.WorkCenter,
+		MaintenancePlant:          op.Plant,
+		OperationControlKey:       op.OperationControlKey,
+		OperationStandardDuration: json.Number(op.OperationStandardDuration),
+		OperationDurationUnit:     op.OperationDurationUnit,
+	}
+	for _, comp := range op.ToMaintenanceOrderComponent {
+		operation.Components = append(operation.Components, models.SAPV4OrderComponent{
+			Material:         comp.Material,
+			RequiredQuantity: json.Number(comp.RequirementQuantityInBaseUnit),
+			BaseUnit:         comp.BaseUnit,
+			MaintenancePlant: comp.Plant,
+			StorageLocation:  comp.StorageLocation,
+		})
+	}
+	return operation
+}
+
+// ConvertSAPOrderUpdateToV4 converts an order header update to the v4 payload
+func ConvertSAPOrderUpdateToV4(req *models.SAPOrderUpdateRequest) *models.SAPV4Order {
+	return &models.SAPV4Order{
+		MaintenanceOrderDesc:       req.Description,
+		FunctionalLocation:         req.FunctionalLocation,
+		MaintPriority:              req.Priority,
+		MaintOrdBasicStartDateTime: req.MaintOrdBasicStartDateTime,
+		MaintOrdBasicEndDateTime:   req.MaintOrdBasicEndDateTime,
+	}
+}
+
+// ConvertSAPOperationUpdateToV4 converts an operation update to the v4 payload
+func ConvertSAPOperationUpdateToV4(req *models.SAPOrderOperationUpdateRequest) *models.SAPV4OrderOperation {
+	return &models.SAPV4OrderOperation{
+		OperationDescription:      req.OperationText,
+		WorkCenter:                req.WorkCenter,
+		OperationStandardDuration: json.Number(req.OperationStandardDuration),
+		OperationDurationUnit:     req.OperationDurationUnit,
+	}
+}
+
+// ConvertSAPV4Order converts a v4 order to the order entity the adaptor works with, so the
+// status conversion is shared by both protocols
+func ConvertSAPV4Order(order *models.SAPV4Order) *models.SAPOrder {
+	converted := &models.SAPOrder{
+		MaintenanceOrder:           order.MaintenanceOrder,
+		MaintenanceOrderType:       order.MaintenanceOrderType,
+		Description:                order.MaintenanceOrderDesc,
+		Equipment:                  order.Equipment,
+		FunctionalLocation:         order.FunctionalLocation,
+		Plant:                      order.MaintenancePlant,
+		Priority:                   order.MaintPriority,
+		OrderStatus:                order.MaintenanceOrderSystemStatus,
+		MaintOrdBasicStartDateTime: order.MaintOrdBasicStartDateTime,
+		MaintOrdBasicEndDateTime:   order.MaintOrdBasicEndDateTime,
+		MaintenanceNotification:    order.MaintenanceNotification,
+		Extensions:                 order.Extensions,
+	}
+	converted.Metadata.ETag = order.ETag
+	for i := range order.Operations {
+		converted.ToMaintenanceOrderOperation.Results = append(converted.ToMaintenanceOrderOperation.Results, *convertSAPV4Operation(&order.Operations[i]))
+	}
+	return converted
+}
+
+// convertSAPV4Operation converts a v4 operation and its components
+func convertSAPV4Operation(op *models.SAPV4OrderOperation) *models.SAPOrderOperationResponse {
+	operation := &models.SAPOrderOperationResponse{
+		MaintenanceOrder:          op.MaintenanceOrder,
+		MaintenanceOrderOperation: op.MaintenanceOrderOperation,
+		OperationText:             op.OperationDescription,
+		WorkCenter:                op.WorkCenter,
+		OperationControlKey:       op.OperationControlKey,
+		OperationStandardDuration: op.OperationStandardDuration.String(),
+		OperationDurationUnit:     op.OperationDurationUnit,
+		OperationStatus:           op.OperationSystemStatus,
+		ActualWorkQuantity:        op.ActualWorkQuantity.String(),
+		WorkQuantityUnit:          op.WorkQuantityUnit,
+	}
+	operation.Metadata.ETag = op.ETag
+	for _, comp := range op.Components {
+		operation.ToMaintenanceOrderComponent.Results = append(operation.ToMaintenanceOrderComponent.Results, models.SAPOrderComponentResponse{
+			MaintenanceOrder:              op.MaintenanceOrder,
+			MaintenanceOrderOperation:     op.MaintenanceOrderOperation,
+			MaintenanceOrderComponent:     comp.MaintenanceOrderComponent,
+			Material:                      comp.Material,
+			RequirementQuantityInBaseUnit: comp.RequiredQuantity.String(),
+			WithdrawnQuantity:             comp.WithdrawnQuantity.String(),
+			BaseUnit:                      comp.BaseUnit,
+			StorageLocation:               comp.StorageLocation,
+			Reservation:                   comp.Reservation,
+			ReservationItem:               comp.ReservationItem,
+		})
+	}
+	return operation
+}
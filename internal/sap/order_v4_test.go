@@ -0,0 +1,184 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestODataV4OrderRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == orderServiceV4Path+"/MaintenanceOrder":
+			body, _ := io.ReadAll(r.Body)
+			for _, expected := range []string{`"MaintenanceOrderDesc":"Replace seal"`, `"MaintenancePlant":"1000"`, `"_MaintenanceOrderOperation":[`, `"OperationStandardDuration":4`} {
+				if !strings.Contains(string(body), expected) {
+					t.Errorf("Expected %s in v4 payload, got %s", expected, body)
+				}
+			}
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"@odata.etag":"W/\"1\"","MaintenanceOrder":"4711","MaintenanceOrderSystemStatus":"CRTD"}`)
+		case r.Method == http.MethodGet && r.URL.Path == orderServiceV4Path+"/MaintenanceOrder('4711')":
+			if r.URL.Query().Get("$expand") != orderExpandV4 {
+				t.Errorf("Expected v4 $expand, got %q", r.URL.Query().Get("$expand"))
+			}
+			io.WriteString(w, `{"MaintenanceOrder":"4711","MaintenanceOrderDesc":"Replace seal","MaintenancePlant":"1000",
+				"MaintenanceOrderSystemStatus":"REL","_MaintenanceOrderOperation":[{"MaintenanceOrder":"4711",
+				"MaintenanceOrderOperation":"0010","OperationDescription":"Disassemble","ActualWorkQuantity":2.5,
+				"_MaintenanceOrderComponent":[{"Material":"SEAL-KIT-100","RequiredQuantity":1,"WithdrawnQuantity":1}]}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == orderServiceV4Path+"/MaintenanceOrder":
+			if r.URL.Query().Get("$skiptoken") == "" {
+				if filter := r.URL.Query().Get("$filter"); filter != "MaintenancePlant eq '1000'" {
+					t.Errorf("Expected v4 property names in filter, got %q", filter)
+				}
+				io.WriteString(w, `{"value":[{"MaintenanceOrder":"4711"}],"@odata.nextLink":"MaintenanceOrder?$skiptoken=1"}`)
+				return
+			}
+			io.WriteString(w, `{"value":[{"MaintenanceOrder":"4712"}]}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, ODataVersion: ODataV4, SkipMetadataValidation: true}, logrus.New())
+	ctx := context.Background()
+
+	created, err := client.CreateOrder(ctx, &models.SAPOrderRequest{
+		Description: "Replace seal",
+		Plant:       "1000",
+		ToMaintenanceOrderOperation: []models.SAPOrderOperation{
+			{OperationText: "Disassemble", OperationStandardDuration: "4"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.D.MaintenanceOrder != "4711" || created.D.OrderStatus != "CRTD" {
+		t.Errorf("Unexpected created order: %+v", created.D)
+	}
+
+	order, err := client.GetOrder(ctx, "4711")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	status := ConvertSAPOrderResponseToStatus(order)
+	if status.Status != "REL" || status.Description != "Replace seal" || status.Plant != "1000" {
+		t.Errorf("Unexpected order status: %+v", status)
+	}
+	if len(status.Operations) != 1 || status.Operations[0].ActualWorkQuantity != 2.5 {
+		t.Errorf("Unexpected operations: %+v", status.Operations)
+	}
+	if len(status.Components) != 1 || status.Components[0].WithdrawnQuantity != 1 {
+		t.Errorf("Unexpected components: %+v", status.Components)
+	}
+
+	orders, hasMore, err := client.ListOrders(ctx, &models.MaintenanceOrderQuery{Plant: "1000"}, 0, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(orders) != 2 || hasMore {
+		t.Errorf("Expected 2 orders across nextLink pages, got %d (hasMore %v)", len(orders), hasMore)
+	}
+}
+
+func TestODataV4UpdateOrderWithOperationsSendsContentIDs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != orderServiceV4Path+"/$batch" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("OData-Version") != "4.0" {
+			t.Errorf("Expected OData-Version 4.0, got %q", r.Header.Get("OData-Version"))
+		}
+
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		changeset, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			t.Fatalf("Expected changeset part, got %v", err)
+		}
+		_, changesetParams, _ := mime.ParseMediaType(changeset.Header.Get("Content-Type"))
+		inner := multipart.NewReader(changeset, changesetParams["boundary"])
+		expected := []struct{ contentID, request, ifMatch, payload string }{
+			{"1", "PATCH MaintenanceOrder('4711') HTTP/1.1", `If-Match: W/"1"`, `"MaintenanceOrderDesc":"Replace seal"`},
+			{"2", "PATCH MaintenanceOrderOperation(MaintenanceOrder='4711',MaintenanceOrderOperation='0010') HTTP/1.1", `If-Match: W/"op1"`, `"WorkCenter":"MECH-01"`},
+		}
+		for _, want := range expected {
+			part, err := inner.NextPart()
+			if err != nil {
+				t.Fatalf("Expected changeset request, got %v", err)
+			}
+			if part.Header.Get("Content-ID") != want.contentID {
+				t.Errorf("Expected Content-ID %s, got %q", want.contentID, part.Header.Get("Content-ID"))
+			}
+			body, _ := io.ReadAll(part)
+			for _, fragment := range []string{want.request, want.ifMatch, want.payload} {
+				if !strings.Contains(string(body), fragment) {
+					t.Errorf("Expected %s in changeset request:\n%s", fragment, body)
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=batchresp")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "--batchresp\r\n"+
+			"Content-Type: multipart/mixed; boundary=changesetresp\r\n\r\n"+
+			"--changesetresp\r\n"+
+			"Content-Type: application/http\r\nContent-Transfer-Encoding: binary\r\nContent-ID: 1\r\n\r\n"+
+			"HTTP/1.1 204 No Content\r\nETag: W/\"2\"\r\n\r\n\r\n"+
+			"--changesetresp\r\n"+
+			"Content-Type: application/http\r\nContent-Transfer-Encoding: binary\r\nContent-ID: 2\r\n\r\n"+
+			"HTTP/1.1 204 No Content\r\nETag: W/\"op2\"\r\n\r\n\r\n"+
+			"--changesetresp--\r\n"+
+			"--batchresp--\r\n")
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, ODataVersion: ODataV4, Timeout: 5, SkipMetadataValidation: true}, logger)
+
+	etag, operationETags, err := client.UpdateOrderWithOperations(context.Background(), "4711",
+		&models.SAPOrderUpdateRequest{Description: "Replace seal"},
+		[]OperationUpdate{{OperationID: "10", ETag: `W/"op1"`, Request: &models.SAPOrderOperationUpdateRequest{WorkCenter: "MECH-01"}}},
+		`W/"1"`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if etag != `W/"2"` || operationETags["10"] != `W/"op2"` {
+		t.Errorf("Expected new ETags from the changeset, got %s and %v", etag, operationETags)
+	}
+}
+
+func TestUnsupportedODataVersionFailsRequests(t *testing.T) {
+	client := NewClient(config.SAPConfig{BaseURL: "https://sap.example.com", ODataVersion: "v3"}, logrus.New())
+
+	_, err := client.GetOrder(context.Background(), "4711")
+	if err == nil || !strings.Contains(err.Error(), "unsupported OData version") {
+		t.Errorf("Expected unsupported OData version error, got %v", err)
+	}
+}
+
+func TestConvertSAPOperationUpdateToV4OmitsUnchangedFields(t *testing.T) {
+	payload, err := json.Marshal(ConvertSAPOperationUpdateToV4(&models.SAPOrderOperationUpdateRequest{WorkCenter: "MECH-01"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(payload) != `{"WorkCenter":"MECH-01"}` {
+		t.Errorf("Expected only the work center, got %s", payload)
+	}
+}
@@ -0,0 +1,88 @@
+package sap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// newRequest creates a request for a service path such as /API_MAINTENANCE_ORDER/A_MaintenanceOrder.
+// The service is resolved against the gateway root of the destination, and the sap-client,
+// sap-language, static query parameters, static headers and credentials of the destination are added.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	target, err := c.requestURL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range c.config.Headers {
+		httpReq.Header.Set(name, value)
+	}
+	if c.config.Username != "" {
+		httpReq.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	return httpReq, nil
+}
+
+// requestURL builds the absolute URL of a service path
+func (c *Client) requestURL(path string) (string, error) {
+	resourcePath, rawQuery, _ := strings.Cut(path, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("invalid query in request path %s: %w", path, err)
+	}
+
+	for _, param := range c.config.QueryParams {
+		query.Set(param.Name, param.Value)
+	}
+	if c.config.SAPClient != "" {
+		query.Set("sap-client", c.config.SAPClient)
+	}
+	if c.config.SAPLanguage != "" {
+		query.Set("sap-language", c.config.SAPLanguage)
+	}
+
+	target := strings.TrimSuffix(c.config.BaseURL, "/") + c.servicePath(resourcePath)
+	if len(query) > 0 {
+		target += "?" + encodeQuery(query)
+	}
+	return target, nil
+}
+
+// encodeQuery encodes a query like url.Values.Encode, but OData system query options ($filter,
+// $expand, ...) keep their literal $ for readability in SAP traces. Values stay escaped.
+func encodeQuery(query url.Values) string {
+	pairs := strings.Split(query.Encode(), "&")
+	for i, pair := range pairs {
+		if strings.HasPrefix(pair, "%24") {
+			pairs[i] = "$" + strings.TrimPrefix(pair, "%24")
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// servicePath maps /SERVICE/resource to the configured path of SERVICE
+func (c *Client) servicePath(resourcePath string) string {
+	service, resource, _ := strings.Cut(strings.TrimPrefix(resourcePath, "/"), "/")
+	if resource != "" {
+		resource = "/" + resource
+	}
+
+	// Viper lowercases map keys, so service names are matched case-insensitively
+	for name, configured := range c.config.ServicePaths {
+		if strings.EqualFold(name, service) {
+			return "/" + strings.Trim(configured, "/") + resource
+		}
+	}
+	if root := strings.Trim(c.config.ServiceRoot, "/"); root != "" {
+		return "/" + root + "/" + service + resource
+	}
+	return "/" + service + resource
+}
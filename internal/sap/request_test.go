@@ -0,0 +1,126 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRequestURLAppliesDestinationSettings(t *testing.T) {
+	client := NewClient(config.SAPConfig{
+		BaseURL:      "https://sap.example.com/",
+		ServiceRoot:  "/sap/opu/odata/sap",
+		ServicePaths: map[string]string{"api_maintenance_notification": "/sap/opu/odata/sap/API_MAINTENANCE_NOTIFICATION;v=0002"},
+		SAPClient:    "100",
+		SAPLanguage:  "EN",
+		QueryParams:  []config.QueryParam{{Name: "saml2", Value: "disabled"}},
+	}, logrus.New())
+
+	tests := []struct {
+		path, expected string
+	}{
+		{
+			"/API_MAINTENANCE_ORDER/A_MaintenanceOrder('4711')?$expand=to_MaintenanceOrderOperation",
+			"https://sap.example.com/sap/opu/odata/sap/API_MAINTENANCE_ORDER/A_MaintenanceOrder('4711')?$expand=to_MaintenanceOrderOperation&saml2=disabled&sap-client=100&sap-language=EN",
+		},
+		{
+			"/API_MAINTENANCE_NOTIFICATION/A_MaintenanceNotification",
+			"https://sap.example.com/sap/opu/odata/sap/API_MAINTENANCE_NOTIFICATION;v=0002/A_MaintenanceNotification?saml2=disabled&sap-client=100&sap-language=EN",
+		},
+	}
+	for _, tt := range tests {
+		got, err := client.requestURL(tt.path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got != tt.expected {
+			t.Errorf("requestURL(%q)\n got  %s\n want %s", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestRequestURLKeepsQueryNamesAndEscapesValues(t *testing.T) {
+	client := NewClient(config.SAPConfig{
+		BaseURL:     "https://sap.example.com",
+		QueryParams: []config.QueryParam{{Name: "sap-Trace", Value: "On"}},
+	}, logrus.New())
+
+	got, err := client.requestURL("/API_MAINTENANCE_ORDER/A_MaintenanceOrder?$filter=" + url.QueryEscape("Description eq 'Pay $50'"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "https://sap.example.com/API_MAINTENANCE_ORDER/A_MaintenanceOrder?$filter=Description+eq+%27Pay+%2450%27&sap-Trace=On"
+	if got != expected {
+		t.Errorf("requestURL\n got  %s\n want %s", got, expected)
+	}
+}
+
+func TestEveryRequestCarriesDestinationSettings(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sap-client") != "100" || r.URL.Query().Get("sap-language") != "EN" {
+			t.Errorf("Missing logon parameters on %s", r.URL)
+		}
+		if r.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("Missing static header on %s", r.URL)
+		}
+		if !strings.HasPrefix(r.URL.Path, "/sap/opu/odata/sap/API_MAINTENANCE_ORDER/") {
+			t.Errorf("Expected gateway service root, got %s", r.URL.Path)
+		}
+
+		var resp models.SAPOrderListResponse
+		if r.URL.Query().Get("$skiptoken") == "" {
+			resp.D.Results = []models.SAPOrder{{MaintenanceOrder: "400000001"}}
+			resp.D.Next = server.URL + "/sap/opu/odata/sap/API_MAINTENANCE_ORDER/A_MaintenanceOrder?$skiptoken=1&sap-client=100"
+		} else {
+			resp.D.Results = []models.SAPOrder{{MaintenanceOrder: "400000002"}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{
+		BaseURL:     server.URL,
+		ServiceRoot: "/sap/opu/odata/sap",
+		SAPClient:   "100",
+		SAPLanguage: "EN",
+		Headers:     map[string]string{"X-Tenant": "acme"},
+		Timeout:     5,
+	}, logger)
+
+	orders, _, err := client.ListOrders(context.Background(), &models.MaintenanceOrderQuery{}, 0, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(orders) != 2 {
+		t.Errorf("Expected both pages to be read, got %+v", orders)
+	}
+}
+
+func TestGetOrderReportsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"/IWBEP/CM_MGW_RT/020","message":{"value":"Resource not found"}}}`))
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{BaseURL: server.URL, SkipMetadataValidation: true}, logger)
+
+	_, err := client.GetOrder(context.Background(), "4711")
+	if !IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
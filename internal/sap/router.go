@@ -0,0 +1,121 @@
+package sap
+
+import (
+	"strings"
+
+	"sap-adaptor/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultDestination is the name of the destination configured in the sap block
+const DefaultDestination = "default"
+
+// Router selects the SAP destination (system) for a plant or company code
+type Router struct {
+	defaultClient *Client
+	routed        []*Client // Destinations with routing rules, in configuration order
+	clients       map[string]*Client
+	logger        *logrus.Logger
+}
+
+// NewRouter creates a client per destination. Settings a destination leaves unset are taken
+// from the default destination.
+func NewRouter(defaultCfg config.SAPConfig, destinations []config.SAPConfig, logger *logrus.Logger) *Router {
+	r := &Router{
+		defaultClient: NewClient(defaultCfg, logger),
+		clients:       make(map[string]*Client),
+		logger:        logger,
+	}
+	r.clients[r.defaultClient.Name()] = r.defaultClient
+
+	for _, destination := range destinations {
+		if _, exists := r.clients[destination.Name]; exists || destination.Name == "" {
+			logger.WithField("destination", destination.Name).Error("Skipping SAP destination without a unique name")
+			continue
+		}
+		client := NewClient(destination.WithDefaults(defaultCfg), logger)
+		r.clients[destination.Name] = client
+		r.routed = append(r.routed, client)
+
+		logger.WithFields(logrus.Fields{
+			"destination":  destination.Name,
+			"plants":       destination.Plants,
+			"companyCodes": destination.CompanyCodes,
+		}).Info("Configured SAP destination")
+	}
+
+	return r
+}
+
+// Default returns the default destination
+func (r *Router) Default() *Client {
+	return r.defaultClient
+}
+
+// Client returns a destination by name
+func (r *Router) Client(name string) (*Client, bool) {
+	client, ok := r.clients[name]
+	return client, ok
+}
+
+// Clients returns all destinations, the default destination first
+func (r *Router) Clients() []*Client {
+	return append([]*Client{r.defaultClient}, r.routed...)
+}
+
+// Route returns the first destination whose plants or company codes match, or the default
+// destination when none does
+func (r *Router) Route(plant, companyCode string) *Client {
+	for _, client := range r.routed {
+		if matchesDestination(client.config, plant, companyCode) {
+			return client
+		}
+	}
+	return r.defaultClient
+}
+
+// matchesDestination applies the routing rules of a destination
+func matchesDestination(cfg config.SAPConfig, plant, companyCode string) bool {
+	if plant != "" && MatchesPlant(cfg.Plants, plant) {
+		return true
+	}
+	if companyCode != "" {
+		for _, code := range cfg.CompanyCodes {
+			if code == companyCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchesPlant reports whether a plant matches any of the given plants or plant ranges
+func MatchesPlant(rules []string, plant string) bool {
+	for _, rule := range rules {
+		if matchesPlantRule(rule, plant) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPlantRule matches a plant against a single plant or an inclusive range such as 1000-1999.
+// Plants are compared as strings of equal length.
+func matchesPlantRule(rule, plant string) bool {
+	from, to, isRange := strings.Cut(rule, "-")
+	if !isRange {
+		return rule == plant
+	}
+	return len(plant) == len(from) && len(plant) == len(to) && from <= plant && plant <= to
+}
+
+// Name returns the destination name of the client
+func (c *Client) Name() string {
+	return c.config.Name
+}
+
+// Workflow returns the workflow modes of the destination
+func (c *Client) Workflow() config.WorkflowConfig {
+	return c.config.Workflow
+}
@@ -0,0 +1,46 @@
+package sap
+
+import (
+	"testing"
+
+	"sap-adaptor/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRouterRoutesByPlantAndCompanyCode(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	router := NewRouter(
+		config.SAPConfig{BaseURL: "simulator", Timeout: 30, SAPClient: "100"},
+		[]config.SAPConfig{
+			{Name: "acquired", BaseURL: "simulator", Plants: []string{"2000-9999"}, CompanyCodes: []string{"2100"}},
+		},
+		logger,
+	)
+
+	tests := []struct {
+		plant, companyCode, expected string
+	}{
+		{"1000", "", DefaultDestination},
+		{"1999", "", DefaultDestination},
+		{"2000", "", "acquired"},
+		{"20000", "", DefaultDestination},
+		{"1500", "2100", "acquired"},
+		{"", "", DefaultDestination},
+	}
+	for _, tt := range tests {
+		if got := router.Route(tt.plant, tt.companyCode).Name(); got != tt.expected {
+			t.Errorf("Route(%q, %q) = %s, expected %s", tt.plant, tt.companyCode, got, tt.expected)
+		}
+	}
+
+	acquired, ok := router.Client("acquired")
+	if !ok {
+		t.Fatal("Expected destination acquired to exist")
+	}
+	if acquired.config.Timeout != 30 || acquired.config.SAPClient != "100" {
+		t.Errorf("Expected unset settings to be inherited, got %+v", acquired.config)
+	}
+}
@@ -0,0 +1,251 @@
+package sap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tlsVersions maps configured TLS versions to their crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newHTTPClient builds the HTTP client of a destination with its TLS and proxy settings
+func newHTTPClient(cfg config.SAPConfig, logger *logrus.Logger) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg.TLS, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.ProxyURL)
+		}
+		if cfg.ProxyUsername != "" {
+			proxyURL.User = url.UserPassword(cfg.ProxyUsername, cfg.ProxyPassword)
+		}
+		// Credentials in the proxy URL are sent as Proxy-Authorization on CONNECT
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &http.Client{
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		Transport: transport,
+	}, nil
+}
+
+// newTLSConfig creates the TLS configuration. Client certificates and the CA bundle are read
+// through reloaders, so rotated files are picked up by the next connection.
+func newTLSConfig(cfg config.TLSConfig, logger *logrus.Logger) (*tls.Config, error) {
+	minVersion, ok := tlsVersions[cfg.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS minimum version %q", cfg.MinVersion)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: minVersion,
+		ServerName: cfg.ServerName,
+	}
+
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("TLS client certificate and key must be configured together")
+	}
+	if cfg.CertFile != "" {
+		certs := &certReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile, logger: logger}
+		if _, err := certs.certificate(); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.certificate()
+		}
+	}
+
+	if cfg.CAFile != "" {
+		roots := &caReloader{caFile: cfg.CAFile, logger: logger}
+		if _, err := roots.pool(); err != nil {
+			return nil, err
+		}
+		// The standard verification uses a fixed root pool; verify against the current
+		// bundle instead so a rotated CA file takes effect without a restart
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyServerCertificate(cs, roots, cfg.ServerName)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// verifyServerCertificate performs the chain and host name verification that
+// InsecureSkipVerify turned off, using the current CA bundle
+func verifyServerCertificate(cs tls.ConnectionState, roots *caReloader, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("SAP server presented no certificate")
+	}
+	pool, err := roots.pool()
+	if err != nil {
+		return err
+	}
+
+	if serverName == "" {
+		serverName = cs.ServerName
+	}
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// fileVersion identifies the content of a file by its modification time and size
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// statFiles returns the versions of the given files
+func statFiles(paths ...string) ([]fileVersion, error) {
+	versions := make([]fileVersion, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, fileVersion{modTime: info.ModTime(), size: info.Size()})
+	}
+	return versions, nil
+}
+
+func sameVersions(a, b []fileVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// certReloader serves the client certificate, reloading it when the files change
+type certReloader struct {
+	certFile, keyFile string
+	logger            *logrus.Logger
+
+	mu       sync.Mutex
+	versions []fileVersion
+	cert     *tls.Certificate
+}
+
+func (r *certReloader) certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, err := statFiles(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert != nil {
+			r.logger.WithError(err).Warn("TLS client certificate not readable, using previously loaded certificate")
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("failed to read TLS client certificate: %w", err)
+	}
+	if r.cert != nil && sameVersions(versions, r.versions) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// During rotation the certificate and key may briefly not match
+		if r.cert != nil {
+			r.logger.WithError(err).Warn("Failed to reload TLS client certificate, using previously loaded certificate")
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.versions = versions
+	r.logger.WithField("certFile", r.certFile).Info("Loaded TLS client certificate")
+	return r.cert, nil
+}
+
+// caReloader serves the CA bundle, reloading it when the file changes
+type caReloader struct {
+	caFile string
+	logger *logrus.Logger
+
+	mu       sync.Mutex
+	versions []fileVersion
+	roots    *x509.CertPool
+}
+
+func (r *caReloader) pool() (*x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, err := statFiles(r.caFile)
+	if err != nil {
+		if r.roots != nil {
+			r.logger.WithError(err).Warn("CA bundle not readable, using previously loaded bundle")
+			return r.roots, nil
+		}
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	if r.roots != nil && sameVersions(versions, r.versions) {
+		return r.roots, nil
+	}
+
+	pem, err := os.ReadFile(r.caFile)
+	if err != nil {
+		if r.roots != nil {
+			r.logger.WithError(err).Warn("CA bundle not readable, using previously loaded bundle")
+			return r.roots, nil
+		}
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		if r.roots != nil {
+			r.logger.Warn("CA bundle contains no certificates, using previously loaded bundle")
+			return r.roots, nil
+		}
+		return nil, fmt.Errorf("CA bundle %s contains no certificates", r.caFile)
+	}
+
+	r.roots = roots
+	r.versions = versions
+	r.logger.WithField("caFile", r.caFile).Info("Loaded CA bundle")
+	return r.roots, nil
+}
+
+// errorTransport fails every request with a configuration error
+type errorTransport struct {
+	err error
+}
+
+func (t errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
@@ -0,0 +1,184 @@
+package sap
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testCert is a certificate issued for a test, with its PEM encodings
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func issueTestCert(t *testing.T, template *x509.Certificate, issuer *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, signer := template, key
+	if issuer != nil {
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutualTLSReloadsRotatedClientCertificate(t *testing.T) {
+	ca := issueTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Internal CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	server := issueTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "sap.internal"},
+		DNSNames:    []string{"sap.internal"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	clientCert := func(cn string) *testCert {
+		return issueTestCert(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: cn},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, ca)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"cn": r.TLS.PeerCertificates[0].Subject.CommonName})
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.cert.Raw}, PrivateKey: server.key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), filepath.Join(dir, "ca.pem")
+	first := clientCert("client-1")
+	writeTestFile(t, certFile, first.certPEM, time.Now())
+	writeTestFile(t, keyFile, first.keyPEM, time.Now())
+	writeTestFile(t, caFile, ca.certPEM, time.Now())
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := NewClient(config.SAPConfig{
+		BaseURL: ts.URL,
+		Timeout: 5,
+		TLS: config.TLSConfig{
+			CertFile:   certFile,
+			KeyFile:    keyFile,
+			CAFile:     caFile,
+			MinVersion: "1.2",
+			ServerName: "sap.internal",
+		},
+	}, logger)
+
+	var resp struct {
+		CN string `json:"cn"`
+	}
+	if err := client.doRequest(context.Background(), http.MethodGet, "/API_TEST/Ping", nil, http.StatusOK, &resp); err != nil {
+		t.Fatalf("Expected mutual TLS request to succeed, got %v", err)
+	}
+	if resp.CN != "client-1" {
+		t.Fatalf("Expected client-1 certificate, got %s", resp.CN)
+	}
+
+	// Rotate the certificate; the next connection presents the new one
+	second := clientCert("client-2")
+	later := time.Now().Add(time.Minute)
+	writeTestFile(t, certFile, second.certPEM, later)
+	writeTestFile(t, keyFile, second.keyPEM, later)
+	client.httpClient.CloseIdleConnections()
+
+	if err := client.doRequest(context.Background(), http.MethodGet, "/API_TEST/Ping", nil, http.StatusOK, &resp); err != nil {
+		t.Fatalf("Expected request after rotation to succeed, got %v", err)
+	}
+	if resp.CN != "client-2" {
+		t.Errorf("Expected rotated client-2 certificate, got %s", resp.CN)
+	}
+}
+
+func TestInvalidConnectionSettingsFailRequests(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	client := NewClient(config.SAPConfig{
+		BaseURL: "https://sap.example.com",
+		TLS:     config.TLSConfig{MinVersion: "1.0"},
+	}, logger)
+
+	err := client.doRequest(context.Background(), http.MethodGet, "/API_TEST/Ping", nil, http.StatusOK, nil)
+	if err == nil {
+		t.Fatal("Expected configuration error")
+	}
+}
+
+func TestProxyCredentials(t *testing.T) {
+	httpClient, err := newHTTPClient(config.SAPConfig{
+		ProxyURL:      "http://proxy.example.com:3128",
+		ProxyUsername: "svc-sap",
+		ProxyPassword: "secret",
+	}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://sap.example.com/API_TEST", nil)
+	proxyURL, err := httpClient.Transport.(*http.Transport).Proxy(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if proxyURL.Host != "proxy.example.com:3128" || proxyURL.User.String() != "svc-sap:secret" {
+		t.Errorf("Expected proxy with credentials, got %s", proxyURL.Redacted())
+	}
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StatusBuffered is returned for an event waiting in the aggregation buffer
+const StatusBuffered = "BUFFERED"
+
+// Aggregated event states
+const (
+	AggregationBuffered  = "BUFFERED"
+	AggregationProcessed = "PROCESSED"
+	AggregationFailed    = "FAILED"
+	AggregationWithdrawn = "WITHDRAWN" // Withdrawn by the Digital Twin before the flush
+)
+
+// ErrAggregatedEventNotFound is returned for an unknown or no longer kept aggregation ID
+var ErrAggregatedEventNotFound = errors.New("aggregated event not found")
+
+const (
+	// flushTimeout bounds the SAP calls of one flush, which has no caller waiting on it
+	flushTimeout = 5 * time.Minute
+	// maxAggregatedEvents is the number of flushed events whose outcome is kept in memory
+	maxAggregatedEvents = 1000
+)
+
+// EventAggregator buffers events per equipment and merges the events that arrive within the window
+// after the first one into one order. The events are processed when a timer flushes the buffer;
+// the outcome of each event is kept under its aggregation ID.
+type EventAggregator struct {
+	window       time.Duration
+	split        bool
+	flushTimeout time.Duration
+	process      func(ctx context.Context, event *models.MaintenanceOrderEvent) (*models.MaintenanceOrderResponse, error)
+	logger       *logrus.Logger
+
+	mu      sync.Mutex
+	next    int
+	buffers map[string]*eventBuffer
+	events  map[string]*models.AggregatedEvent
+	flushed []string // IDs of flushed events, oldest first
+}
+
+// eventBuffer holds the events of one equipment waiting for the flush, with their aggregation IDs
+type eventBuffer struct {
+	events []models.MaintenanceOrderEvent
+	ids    []string
+}
+
+// NewEventAggregator creates an event aggregator that hands merged events to process
+func NewEventAggregator(cfg config.AggregationConfig, process func(context.Context, *models.MaintenanceOrderEvent) (*models.MaintenanceOrderResponse, error), logger *logrus.Logger) *EventAggregator {
+	return &EventAggregator{
+		window:       time.Duration(cfg.WindowSeconds) * time.Second,
+		split:        cfg.SplitByWorkCenter,
+		flushTimeout: flushTimeout,
+		process:      process,
+		logger:       logger,
+		buffers:      make(map[string]*eventBuffer),
+		events:       make(map[string]*models.AggregatedEvent),
+	}
+}
+
+// Submit buffers an event and returns it as buffered. The first event of an equipment starts the
+// window; the order an event went into is read with Get once the buffer is flushed.
+func (a *EventAggregator) Submit(event *models.MaintenanceOrderEvent) models.AggregatedEvent {
+	key := event.Plant + "/" + event.EquipmentID
+
+	a.mu.Lock()
+	buffer, ok := a.buffers[key]
+	if !ok {
+		buffer = &eventBuffer{}
+		a.buffers[key] = buffer
+		time.AfterFunc(a.window, func() { a.flush(key) })
+	}
+	a.next++
+	now := time.Now()
+	entry := &models.AggregatedEvent{
+		ID:          fmt.Sprintf("AGG-%06d", a.next),
+		Status:      AggregationBuffered,
+		EventID:     event.EventID,
+		EquipmentID: event.EquipmentID,
+		Plant:       event.Plant,
+		BufferedAt:  now,
+		FlushAt:     now.Add(a.window),
+	}
+	if len(buffer.ids) > 0 {
+		entry.FlushAt = a.events[buffer.ids[0]].FlushAt
+	}
+	a.events[entry.ID] = entry
+	buffer.events = append(buffer.events, *event)
+	buffer.ids = append(buffer.ids, entry.ID)
+	buffered := len(buffer.events)
+	submitted := *entry
+	a.mu.Unlock()
+
+	a.logger.WithFields(logrus.Fields{
+		"aggregationId": submitted.ID,
+		"equipmentId":   event.EquipmentID,
+		"plant":         event.Plant,
+		"buffered":      buffered,
+		"flushAt":       submitted.FlushAt,
+	}).Info("Maintenance order event buffered for aggregation")
+
+	return submitted
+}
+
+// Get returns a copy of a buffered or flushed event
+func (a *EventAggregator) Get(id string) (*models.AggregatedEvent, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.events[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAggregatedEventNotFound, id)
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+// Withdraw removes a buffered event with a Digital Twin event ID from its buffer, so it is not
+// merged into the order. It returns false if no such event is buffered.
+func (a *EventAggregator) Withdraw(eventID string) (*models.AggregatedEvent, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, buffer := range a.buffers {
+		for i, event := range buffer.events {
+			if event.EventID != eventID {
+				continue
+			}
+			entry := a.events[buffer.ids[i]]
+			entry.Status = AggregationWithdrawn
+			a.flushed = append(a.flushed, entry.ID)
+			// The emptied buffer stays until its timer fires, so later events keep the window
+			buffer.events = append(buffer.events[:i], buffer.events[i+1:]...)
+			buffer.ids = append(buffer.ids[:i], buffer.ids[i+1:]...)
+			copied := *entry
+			return &copied, true
+		}
+	}
+	return nil, false
+}
+
+// flush merges and processes the buffered events of an equipment and records their outcome
+func (a *EventAggregator) flush(key string) {
+	a.mu.Lock()
+	buffer := a.buffers[key]
+	delete(a.buffers, key)
+	a.mu.Unlock()
+	if buffer == nil || len(buffer.events) == 0 {
+		return
+	}
+
+	merged, eventGroups := MergeEvents(buffer.events, a.split)
+	a.logger.WithFields(logrus.Fields{
+		"equipment": key,
+		"events":    len(buffer.events),
+		"orders":    len(merged),
+	}).Info("Flushing aggregated maintenance order events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.flushTimeout)
+	defer cancel()
+
+	responses := make([]*models.MaintenanceOrderResponse, len(merged))
+	errs := make([]error, len(merged))
+	var orderIDs []string
+	for i := range merged {
+		responses[i], errs[i] = a.process(ctx, &merged[i])
+		if errs[i] != nil {
+			a.logger.WithError(errs[i]).WithField("equipment", key).Error("Failed to process aggregated maintenance order event")
+			continue
+		}
+		orderIDs = append(orderIDs, responses[i].OrderID)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, id := range buffer.ids {
+		entry := a.events[id]
+		group := eventGroups[i]
+		if errs[group] != nil {
+			entry.Status = AggregationFailed
+			entry.Error = errs[group].Error()
+		} else {
+			response := *responses[group]
+			response.AggregatedEvents = len(buffer.events)
+			for _, orderID := range orderIDs {
+				if orderID != response.OrderID {
+					response.RelatedOrders = append(response.RelatedOrders, orderID)
+				}
+			}
+			entry.Status = AggregationProcessed
+			entry.Result = &response
+		}
+		a.flushed = append(a.flushed, id)
+	}
+	for len(a.flushed) > maxAggregatedEvents {
+		delete(a.events, a.flushed[0])
+		a.flushed = a.flushed[1:]
+	}
+}
+
+// operationKey identifies equal operations sent by several events
+type operationKey struct {
+	text       string
+	workCenter string
+	controlKey string
+	duration   float64
+}
+
+// mergedComponent is a component with the index of its operation in the merged event, -1 for none
+type mergedComponent struct {
+	component models.MaintenanceComponent
+	operation int
+}
+
+// MergeEvents merges a burst of events for one equipment. The header comes from the most urgent
+// event, so the highest priority wins; operations are unioned, equal operations kept once, and
+// renumbered. With split, one event per operation work center is returned; only the first carries
+// the fault items, so the others are not taken for repeats of it. The second result gives the
+// index of the merged event each input event went into.
+func MergeEvents(events []models.MaintenanceOrderEvent, split bool) ([]models.MaintenanceOrderEvent, []int) {
+	urgent := 0
+	for i := range events {
+		if MoreUrgent(events[i].Priority, events[urgent].Priority) ||
+			events[i].Priority == events[urgent].Priority && riskScore(&events[i]) > riskScore(&events[urgent]) {
+			urgent = i
+		}
+	}
+
+	merged := events[urgent]
+	merged.Operations = nil
+	merged.Components = nil
+	merged.Items = nil
+	merged.Extensions = nil
+	merged.MergedEventIDs = nil
+
+	var operations []models.MaintenanceOperation
+	var components []mergedComponent
+	firstOperation := make([]int, len(events))
+	seen := make(map[operationKey]int)
+	var descriptions []string
+	for i := range events {
+		event := &events[i]
+		if i != urgent {
+			descriptions = append(descriptions, "- "+event.Description)
+			if event.EventID != "" {
+				merged.MergedEventIDs = append(merged.MergedEventIDs, event.EventID)
+			}
+		}
+		if event.RiskScore != nil && (merged.RiskScore == nil || *event.RiskScore > *merged.RiskScore) {
+			merged.RiskScore = event.RiskScore
+		}
+		merged.Items = append(merged.Items, event.Items...)
+		for name, value := range event.Extensions {
+			if _, ok := events[urgent].Extensions[name]; ok && i != urgent {
+				continue
+			}
+			if merged.Extensions == nil {
+				merged.Extensions = make(map[string]models.ExtensionValue)
+			}
+			merged.Extensions[name] = value
+		}
+
+		// Operations of this event by their ID, for its components
+		byID := make(map[string]int)
+		firstOperation[i] = -1
+		for j, op := range event.Operations {
+			key := operationKey{strings.ToLower(op.Text), strings.ToUpper(op.WorkCenter), op.ControlKey, op.Duration}
+			index, ok := seen[key]
+			if !ok {
+				index = len(operations)
+				seen[key] = index
+				op.OperationID = ""
+				operations = append(operations, op)
+			}
+			if j == 0 {
+				firstOperation[i] = index
+			}
+			if event.Operations[j].OperationID != "" {
+				byID[models.NormalizeOperationID(event.Operations[j].OperationID)] = index
+			}
+		}
+		for _, comp := range event.Components {
+			index := firstOperation[i]
+			if target, ok := byID[models.NormalizeOperationID(comp.OperationID)]; ok && comp.OperationID != "" {
+				index = target
+			}
+			components = append(components, mergedComponent{comp, index})
+		}
+	}
+	if len(descriptions) > 0 {
+		text := "Aggregated events:\n" + strings.Join(descriptions, "\n")
+		if merged.LongText != "" {
+			text = merged.LongText + "\n\n" + text
+		}
+		merged.LongText = text
+	}
+
+	// Group the operations by work center
+	groupOf := make([]int, len(operations))
+	var workCenters []string
+	if split {
+		for i, op := range operations {
+			group := -1
+			for g, workCenter := range workCenters {
+				if strings.EqualFold(workCenter, op.WorkCenter) {
+					group = g
+				}
+			}
+			if group < 0 {
+				group = len(workCenters)
+				workCenters = append(workCenters, op.WorkCenter)
+			}
+			groupOf[i] = group
+		}
+	}
+	if len(workCenters) < 2 {
+		workCenters = []string{""}
+		groupOf = make([]int, len(operations))
+	}
+
+	result := make([]models.MaintenanceOrderEvent, len(workCenters))
+	numbers := make([]string, len(operations))
+	for g := range result {
+		result[g] = merged
+		result[g].Operations = nil
+		result[g].Components = nil
+		if len(workCenters) > 1 {
+			if workCenters[g] != "" {
+				result[g].MainWorkCenter = workCenters[g]
+			}
+			if g > 0 {
+				result[g].Items = nil
+				result[g].FaultClass = ""
+			}
+		}
+	}
+	for i, op := range operations {
+		group := &result[groupOf[i]]
+		numbers[i] = models.OperationNumberForIndex("", len(group.Operations))
+		op.OperationID = numbers[i]
+		group.Operations = append(group.Operations, op)
+	}
+	for _, comp := range components {
+		group := 0
+		comp.component.OperationID = ""
+		if comp.operation >= 0 {
+			group = groupOf[comp.operation]
+			comp.component.OperationID = numbers[comp.operation]
+		}
+		result[group].Components = append(result[group].Components, comp.component)
+	}
+
+	eventGroups := make([]int, len(events))
+	for i, first := range firstOperation {
+		if first >= 0 {
+			eventGroups[i] = groupOf[first]
+		}
+	}
+	return result, eventGroups
+}
+
+// riskScore returns the risk score of an event, -1 if it has none
+func riskScore(event *models.MaintenanceOrderEvent) float64 {
+	if event.RiskScore == nil {
+		return -1
+	}
+	return *event.RiskScore
+}
+
+// EnableAggregation merges bursts of events per equipment before they are processed.
+// A window of zero leaves aggregation off.
+func (s *MaintenanceService) EnableAggregation(cfg config.AggregationConfig) {
+	if cfg.WindowSeconds <= 0 {
+		return
+	}
+	s.aggregator = NewEventAggregator(cfg, s.ProcessMaintenanceOrderEvent, s.logger)
+	s.logger.WithFields(logrus.Fields{
+		"windowSeconds":     cfg.WindowSeconds,
+		"splitByWorkCenter": cfg.SplitByWorkCenter,
+	}).Info("Event aggregation enabled")
+}
+
+// SubmitMaintenanceOrderEvent processes an event, or buffers it to be merged with other events of
+// the same equipment when aggregation is enabled. Events that need approval are parked instead.
+func (s *MaintenanceService) SubmitMaintenanceOrderEvent(ctx context.Context, event *models.MaintenanceOrderEvent) (*models.MaintenanceOrderResponse, error) {
+	// The event is prepared first, so invalid events are rejected right away, and the approval
+	// rules and merging see the priority derived from severity, criticality, templates and rules
+	if err := s.prepareEvent(ctx, s.destinations.Route(event.Plant, event.CompanyCode), event); err != nil {
+		return nil, err
+	}
+	if response := s.parkForApproval(event); response != nil {
+		return response, nil
+	}
+	if s.aggregator == nil {
+		return s.ProcessMaintenanceOrderEvent(ctx, event)
+	}
+
+	buffered := s.aggregator.Submit(event)
+	return &models.MaintenanceOrderResponse{
+		AggregationID: buffered.ID,
+		Status:        StatusBuffered,
+		Message:       "Buffered for aggregation until " + buffered.FlushAt.UTC().Format(time.RFC3339),
+		CreatedAt:     buffered.BufferedAt,
+	}, nil
+}
+
+// GetAggregatedEvent returns a buffered event, or the order it went into once it was flushed
+func (s *MaintenanceService) GetAggregatedEvent(id string) (*models.AggregatedEvent, error) {
+	if s.aggregator == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAggregatedEventNotFound, id)
+	}
+	return s.aggregator.Get(id)
+}
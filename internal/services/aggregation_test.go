@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMergeEventsUnionsOperations(t *testing.T) {
+	events := []models.MaintenanceOrderEvent{
+		{
+			EquipmentID: "10000045", Plant: "1000", Description: "Vibration", Priority: "3",
+			Operations: []models.MaintenanceOperation{{OperationID: "0010", Text: "Inspect", WorkCenter: "MECH-01"}},
+		},
+		{
+			EquipmentID: "10000045", Plant: "1000", Description: "Temperature", Priority: "1",
+			Operations: []models.MaintenanceOperation{
+				{Text: "Inspect", WorkCenter: "MECH-01"},
+				{OperationID: "0020", Text: "Check wiring", WorkCenter: "ELEC-01"},
+			},
+			Components: []models.MaintenanceComponent{{Material: "FUSE", Quantity: 2, OperationID: "0020"}},
+		},
+	}
+
+	merged, groups := MergeEvents(events, false)
+	if len(merged) != 1 || groups[0] != 0 || groups[1] != 0 {
+		t.Fatalf("Expected one merged event, got %d", len(merged))
+	}
+	event := merged[0]
+	if event.Priority != "1" || event.Description != "Temperature" {
+		t.Errorf("Expected the most urgent event to win, got %s/%s", event.Priority, event.Description)
+	}
+	if len(event.Operations) != 2 || event.Operations[1].OperationID != "0020" || event.Operations[1].WorkCenter != "ELEC-01" {
+		t.Errorf("Expected the union of operations, got %+v", event.Operations)
+	}
+	if len(event.Components) != 1 || event.Components[0].OperationID != "0020" {
+		t.Errorf("Expected the component on the wiring operation, got %+v", event.Components)
+	}
+
+	merged, groups = MergeEvents(events, true)
+	if len(merged) != 2 || groups[0] != 0 || groups[1] != 0 {
+		t.Fatalf("Expected one order per work center, got %d (%v)", len(merged), groups)
+	}
+	electrical := merged[1]
+	if electrical.MainWorkCenter != "ELEC-01" || len(electrical.Operations) != 1 || electrical.Operations[0].OperationID != "0010" ||
+		len(electrical.Components) != 1 || electrical.Components[0].OperationID != "0010" {
+		t.Errorf("Unexpected electrical order %+v", electrical)
+	}
+}
+
+func TestEventAggregatorFlushesAfterWindow(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var mu sync.Mutex
+	var processed []models.MaintenanceOrderEvent
+	process := func(_ context.Context, event *models.MaintenanceOrderEvent) (*models.MaintenanceOrderResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		processed = append(processed, *event)
+		return &models.MaintenanceOrderResponse{OrderID: fmt.Sprintf("40000%04d", len(processed))}, nil
+	}
+	aggregator := NewEventAggregator(config.AggregationConfig{WindowSeconds: 1}, process, logger)
+	aggregator.window = 50 * time.Millisecond
+
+	ids := make([]string, 3)
+	for i := range ids {
+		event := &models.MaintenanceOrderEvent{
+			EquipmentID: "10000045",
+			Plant:       "1000",
+			Description: fmt.Sprintf("Event %d", i),
+			Operations:  []models.MaintenanceOperation{{Text: fmt.Sprintf("Operation %d", i)}},
+		}
+		buffered := aggregator.Submit(event)
+		if buffered.Status != AggregationBuffered {
+			t.Fatalf("Expected the event to be buffered, got %+v", buffered)
+		}
+		ids[i] = buffered.ID
+	}
+
+	// Submit returns right away; the outcome is read once the window has passed
+	deadline := time.Now().Add(2 * time.Second)
+	for _, id := range ids {
+		entry, err := aggregator.Get(id)
+		for err == nil && entry.Status == AggregationBuffered && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			entry, err = aggregator.Get(id)
+		}
+		if err != nil || entry.Status != AggregationProcessed || entry.Result.OrderID != "400000001" || entry.Result.AggregatedEvents != 3 {
+			t.Errorf("Expected every event to report the merged order, got %+v (%v)", entry, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(processed) != 1 || len(processed[0].Operations) != 3 {
+		t.Fatalf("Expected one event with three operations, got %+v", processed)
+	}
+}
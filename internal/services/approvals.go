@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Approval states
+const (
+	ApprovalPending   = "PENDING"
+	ApprovalApproved  = "APPROVED"
+	ApprovalRejected  = "REJECTED"
+	ApprovalExpired   = "EXPIRED"
+	ApprovalFailed    = "FAILED"    // Approved, but the event could not be processed
+	ApprovalWithdrawn = "WITHDRAWN" // Withdrawn by the Digital Twin before a decision
+)
+
+// StatusPendingApproval is returned for an event parked until it is approved
+const StatusPendingApproval = "PENDING_APPROVAL"
+
+var (
+	// ErrApprovalNotFound is returned for an unknown approval ID
+	ErrApprovalNotFound = errors.New("approval not found")
+	// ErrApprovalDecided is returned when an approval is no longer pending
+	ErrApprovalDecided = errors.New("approval is no longer pending")
+)
+
+const (
+	defaultApprovalTimeout = 24 * time.Hour
+	// maxApprovals is the number of approvals kept in memory; decided ones are dropped first
+	maxApprovals = 1000
+)
+
+// ApprovalQueue parks events that match the approval rules until a reliability engineer approves
+// or rejects them. Pending approvals expire after the timeout; expiry is applied whenever the queue
+// is read or decided, there is no background sweep. The queue is in memory only and keeps at most
+// maxApprovals entries, dropping the oldest decided ones; pending approvals are never dropped.
+type ApprovalQueue struct {
+	cfg     config.ApprovalConfig
+	timeout time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	next      int
+	approvals []*models.Approval // Oldest first
+}
+
+// NewApprovalQueue creates an empty approval queue
+func NewApprovalQueue(cfg config.ApprovalConfig) *ApprovalQueue {
+	timeout := defaultApprovalTimeout
+	if cfg.TimeoutHours > 0 {
+		timeout = time.Duration(cfg.TimeoutHours) * time.Hour
+	}
+	return &ApprovalQueue{cfg: cfg, timeout: timeout, now: time.Now}
+}
+
+// Reasons returns the rules an event matches, empty if it can go to SAP without approval
+func (q *ApprovalQueue) Reasons(event *models.MaintenanceOrderEvent) []string {
+	priority := event.Priority
+	var reasons []string
+	if sap.MatchesPlant(q.cfg.Plants, event.Plant) {
+		reasons = append(reasons, "plant "+event.Plant)
+	}
+	for _, p := range q.cfg.Priorities {
+		if priority != "" && strings.EqualFold(p, priority) {
+			reasons = append(reasons, "priority "+priority)
+			break
+		}
+	}
+	if q.cfg.ShutdownRequired && event.ShutdownRequired {
+		reasons = append(reasons, "shutdown required")
+	}
+	return reasons
+}
+
+// Park adds a pending approval for an event
+func (q *ApprovalQueue) Park(event *models.MaintenanceOrderEvent, reasons []string) models.Approval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.next++
+	now := q.now()
+	approval := &models.Approval{
+		ID:          fmt.Sprintf("APR-%06d", q.next),
+		Status:      ApprovalPending,
+		Reasons:     reasons,
+		Event:       *event,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(q.timeout),
+	}
+	q.approvals = append(q.approvals, approval)
+	q.trimLocked()
+	return *approval
+}
+
+// List returns the approvals with a status, or all approvals, newest first
+func (q *ApprovalQueue) List(status string) []models.Approval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	approvals := make([]models.Approval, 0)
+	for i := len(q.approvals) - 1; i >= 0; i-- {
+		if status == "" || strings.EqualFold(q.approvals[i].Status, status) {
+			approvals = append(approvals, *q.approvals[i])
+		}
+	}
+	return approvals
+}
+
+// Get returns a copy of an approval
+func (q *ApprovalQueue) Get(id string) (*models.Approval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	approval := q.findLocked(id)
+	if approval == nil {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, id)
+	}
+	copied := *approval
+	return &copied, nil
+}
+
+// Decide approves or rejects a pending approval
+func (q *ApprovalQueue) Decide(id, status string, decision *models.ApprovalDecision) (*models.Approval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	approval := q.findLocked(id)
+	if approval == nil {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, id)
+	}
+	if approval.Status != ApprovalPending {
+		return nil, fmt.Errorf("%w: %s is %s", ErrApprovalDecided, id, approval.Status)
+	}
+
+	now := q.now()
+	approval.Status = status
+	approval.DecidedAt = &now
+	approval.DecidedBy = decision.DecidedBy
+	approval.Comment = decision.Comment
+	copied := *approval
+	return &copied, nil
+}
+
+// Withdraw closes the pending approval of a Digital Twin event ID, so it can no longer be
+// approved. It returns false if no approval of the event is pending.
+func (q *ApprovalQueue) Withdraw(eventID, reason string) (*models.Approval, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked()
+
+	for _, approval := range q.approvals {
+		if approval.Status != ApprovalPending || approval.Event.EventID != eventID {
+			continue
+		}
+		now := q.now()
+		approval.Status = ApprovalWithdrawn
+		approval.DecidedAt = &now
+		approval.Comment = reason
+		copied := *approval
+		return &copied, true
+	}
+	return nil, false
+}
+
+// Complete records the outcome of processing an approved event
+func (q *ApprovalQueue) Complete(id string, response *models.MaintenanceOrderResponse, err error) *models.Approval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	approval := q.findLocked(id)
+	if approval == nil {
+		return nil
+	}
+	approval.Result = response
+	if err != nil {
+		approval.Status = ApprovalFailed
+		approval.Error = err.Error()
+	}
+	copied := *approval
+	return &copied
+}
+
+// findLocked returns the approval with an ID; q.mu must be held
+func (q *ApprovalQueue) findLocked(id string) *models.Approval {
+	for _, approval := range q.approvals {
+		if approval.ID == id {
+			return approval
+		}
+	}
+	return nil
+}
+
+// expireLocked marks pending approvals past their expiry as expired; q.mu must be held
+func (q *ApprovalQueue) expireLocked() {
+	now := q.now()
+	for _, approval := range q.approvals {
+		if approval.Status == ApprovalPending && !now.Before(approval.ExpiresAt) {
+			approval.Status = ApprovalExpired
+		}
+	}
+}
+
+// trimLocked drops the oldest decided approvals when the queue is full; q.mu must be held
+func (q *ApprovalQueue) trimLocked() {
+	q.expireLocked()
+	excess := len(q.approvals) - maxApprovals
+	if excess <= 0 {
+		return
+	}
+	kept := q.approvals[:0]
+	for _, approval := range q.approvals {
+		if excess > 0 && approval.Status != ApprovalPending {
+			excess--
+			continue
+		}
+		kept = append(kept, approval)
+	}
+	q.approvals = kept
+}
+
+// EnableApprovals parks events matching the approval rules until they are approved.
+// Without plants, priorities or shutdownRequired the gate stays off.
+func (s *MaintenanceService) EnableApprovals(cfg config.ApprovalConfig) {
+	if len(cfg.Plants) == 0 && len(cfg.Priorities) == 0 && !cfg.ShutdownRequired {
+		return
+	}
+	s.approvals = NewApprovalQueue(cfg)
+	s.logger.WithFields(logrus.Fields{
+		"plants":           cfg.Plants,
+		"priorities":       cfg.Priorities,
+		"shutdownRequired": cfg.ShutdownRequired,
+		"timeout":          s.approvals.timeout,
+	}).Info("Approval gate enabled")
+}
+
+// parkForApproval parks an event that needs approval and returns the pending response, or nil if
+// the event can be processed right away. The event must be prepared, so the priority rule sees
+// the priority it will be sent with.
+func (s *MaintenanceService) parkForApproval(event *models.MaintenanceOrderEvent) *models.MaintenanceOrderResponse {
+	if s.approvals == nil {
+		return nil
+	}
+
+	reasons := s.approvals.Reasons(event)
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	approval := s.approvals.Park(event, reasons)
+	s.logger.WithFields(logrus.Fields{
+		"approvalId":  approval.ID,
+		"equipmentId": event.EquipmentID,
+		"plant":       event.Plant,
+		"reasons":     reasons,
+		"expiresAt":   approval.ExpiresAt,
+	}).Info("Maintenance order event parked for approval")
+
+	return &models.MaintenanceOrderResponse{
+		ApprovalID: approval.ID,
+		Status:     StatusPendingApproval,
+		Message:    "Waiting for approval: " + strings.Join(reasons, ", "),
+		CreatedAt:  approval.RequestedAt,
+	}
+}
+
+// ListApprovals returns the approvals with a status, or all approvals, newest first
+func (s *MaintenanceService) ListApprovals(status string) []models.Approval {
+	if s.approvals == nil {
+		return make([]models.Approval, 0)
+	}
+	return s.approvals.List(status)
+}
+
+// GetApproval returns an approval
+func (s *MaintenanceService) GetApproval(id string) (*models.Approval, error) {
+	if s.approvals == nil {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, id)
+	}
+	return s.approvals.Get(id)
+}
+
+// ApproveEvent approves a parked event and sends it through ProcessMaintenanceOrderEvent. If
+// processing fails the approval is marked failed and returned together with the error.
+func (s *MaintenanceService) ApproveEvent(ctx context.Context, id string, decision *models.ApprovalDecision) (*models.Approval, error) {
+	if s.approvals == nil {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, id)
+	}
+	approval, err := s.approvals.Decide(id, ApprovalApproved, decision)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(logrus.Fields{
+		"approvalId": id,
+		"decidedBy":  decision.DecidedBy,
+	}).Info("Maintenance order event approved")
+
+	event := approval.Event
+	response, err := s.ProcessMaintenanceOrderEvent(ctx, &event)
+	approval = s.approvals.Complete(id, response, err)
+	if err != nil {
+		return approval, err
+	}
+	return approval, nil
+}
+
+// RejectEvent rejects a parked event; nothing is sent to SAP
+func (s *MaintenanceService) RejectEvent(id string, decision *models.ApprovalDecision) (*models.Approval, error) {
+	if s.approvals == nil {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, id)
+	}
+	approval, err := s.approvals.Decide(id, ApprovalRejected, decision)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(logrus.Fields{
+		"approvalId": id,
+		"decidedBy":  decision.DecidedBy,
+		"comment":    decision.Comment,
+	}).Info("Maintenance order event rejected")
+	return approval, nil
+}
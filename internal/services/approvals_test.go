@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestApprovalGate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := NewMaintenanceService(sap.NewRouter(config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60}, nil, logger), logger)
+	service.EnableApprovals(config.ApprovalConfig{Plants: []string{"3000-3999"}, Priorities: []string{"1"}, ShutdownRequired: true})
+
+	// Events matching no rule are processed right away
+	response, err := service.SubmitMaintenanceOrderEvent(context.Background(), &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "1000", Description: "Replace seal", Priority: "3"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.ApprovalID != "" || response.OrderID == "" {
+		t.Errorf("Expected an order without approval, got %+v", response)
+	}
+
+	// Priority 1 with a shutdown is parked with both reasons
+	event := &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "1000", Description: "Replace bearing", Priority: "1", ShutdownRequired: true}
+	response, err = service.SubmitMaintenanceOrderEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Status != StatusPendingApproval || response.OrderID != "" {
+		t.Fatalf("Expected the event to be parked, got %+v", response)
+	}
+	pending := service.ListApprovals(ApprovalPending)
+	if len(pending) != 1 || len(pending[0].Reasons) != 2 {
+		t.Fatalf("Expected one pending approval with two reasons, got %+v", pending)
+	}
+
+	approval, err := service.ApproveEvent(context.Background(), response.ApprovalID, &models.ApprovalDecision{DecidedBy: "jdoe"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if approval.Status != ApprovalApproved || approval.Result == nil || approval.Result.OrderID == "" {
+		t.Errorf("Expected the approved event to create an order, got %+v", approval)
+	}
+	if _, err := service.RejectEvent(response.ApprovalID, &models.ApprovalDecision{Comment: "Too late"}); !errors.Is(err, ErrApprovalDecided) {
+		t.Errorf("Expected ErrApprovalDecided, got %v", err)
+	}
+
+	// Pending approvals expire after the timeout
+	response, _ = service.SubmitMaintenanceOrderEvent(context.Background(), &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "3100", Description: "Inspect pump"})
+	service.approvals.now = func() time.Time { return time.Now().Add(defaultApprovalTimeout) }
+	if _, err := service.ApproveEvent(context.Background(), response.ApprovalID, &models.ApprovalDecision{}); !errors.Is(err, ErrApprovalDecided) {
+		t.Errorf("Expected the approval to have expired, got %v", err)
+	}
+	if expired := service.ListApprovals(ApprovalExpired); len(expired) != 1 || expired[0].ID != response.ApprovalID {
+		t.Errorf("Expected the approval to be listed as expired, got %+v", expired)
+	}
+}
+
+func TestApprovalGateUsesEnrichedPriority(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cfg := config.SAPConfig{
+		SimulatorMode:      true,
+		MasterDataCacheTTL: 60,
+		Priority: config.PriorityConfig{
+			Matrices: []config.PriorityMatrix{{Matrix: map[string]map[string]string{"high": {"a": "1", "default": "3"}}}},
+		},
+	}
+	service := NewMaintenanceService(sap.NewRouter(cfg, nil, logger), logger)
+	service.EnableApprovals(config.ApprovalConfig{Priorities: []string{"1"}})
+
+	// The criticality comes from the equipment master (A for equipment ending in 45), so the
+	// event only reaches priority 1 after enrichment
+	response, err := service.SubmitMaintenanceOrderEvent(context.Background(), &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "1000", Description: "Replace bearing", Severity: "high"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Status != StatusPendingApproval {
+		t.Fatalf("Expected the event to be parked for priority 1, got %+v", response)
+	}
+	approval, _ := service.GetApproval(response.ApprovalID)
+	if approval.Event.Priority != "1" || approval.Event.Criticality != "A" {
+		t.Errorf("Expected the parked event to carry the enriched priority, got %+v", approval.Event)
+	}
+
+	// Equipment with criticality B stays at priority 3 and goes to SAP right away
+	response, err = service.SubmitMaintenanceOrderEvent(context.Background(), &models.MaintenanceOrderEvent{EquipmentID: "10000046", Plant: "1000", Description: "Replace bearing", Severity: "high"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.ApprovalID != "" || response.OrderID == "" {
+		t.Errorf("Expected an order without approval, got %+v", response)
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache is a concurrency-safe cache whose entries expire after a fixed time
+type ttlCache[T any] struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cacheEntry[T]
+}
+
+type cacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// newTTLCache creates a cache. A ttl of zero disables caching.
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry[T]),
+	}
+}
+
+// Get returns a cached value that has not expired
+func (c *ttlCache[T]) Get(key string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set stores a value for the cache TTL
+func (c *ttlCache[T]) Set(key string, value T) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry[T]{value: value, expiresAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrOrderNotCancellable is returned for orders that have progressed too far to be withdrawn
+	ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
+	// ErrEventNotFound is returned when an event has no order, and is not buffered, waiting for
+	// approval or waiting for SAP to create its order
+	ErrEventNotFound = errors.New("event has no order and is not buffered, pending approval or awaiting an order")
+	// ErrOrderCancelled ends the monitoring of a withdrawn order
+	ErrOrderCancelled = errors.New("order was cancelled")
+)
+
+// Actions taken on a withdrawn order, and the SAP status it is tracked with afterwards
+const (
+	CancelActionDeletionFlag          = "deletion-flag"
+	CancelActionLocked                = "locked"
+	CancelActionNotificationCompleted = "notification-completed" // Notification-only event before SAP created the order
+	CancelActionWithdrawn             = "withdrawn"              // Event that never reached SAP
+
+	statusDeletionFlag          = "DLFL"
+	statusLocked                = "LKD"
+	statusNotificationCompleted = "NOCO"
+	statusWithdrawn             = "WITHDRAWN"
+)
+
+const defaultCancelReason = "Anomaly withdrawn by the Digital Twin"
+
+// OrderSubscriber is notified when an order, or the notification of an event SAP has not created
+// the order for yet, is withdrawn
+type OrderSubscriber func(cancellation *models.OrderCancellation)
+
+// orderMonitors holds the cancel functions of the running order status monitors
+type orderMonitors struct {
+	mu      sync.Mutex
+	next    int
+	cancels map[string]map[int]context.CancelCauseFunc
+}
+
+// add registers a monitor of an order and returns the function that removes it again
+func (m *orderMonitors) add(orderID string, cancel context.CancelCauseFunc) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancels == nil {
+		m.cancels = make(map[string]map[int]context.CancelCauseFunc)
+	}
+	if m.cancels[orderID] == nil {
+		m.cancels[orderID] = make(map[int]context.CancelCauseFunc)
+	}
+	id := m.next
+	m.next++
+	m.cancels[orderID][id] = cancel
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.cancels[orderID], id)
+		if len(m.cancels[orderID]) == 0 {
+			delete(m.cancels, orderID)
+		}
+	}
+}
+
+// stop cancels the monitors of an order and returns how many were running
+func (m *orderMonitors) stop(orderID string, cause error) int {
+	m.mu.Lock()
+	cancels := m.cancels[orderID]
+	delete(m.cancels, orderID)
+	m.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel(cause)
+	}
+	return len(cancels)
+}
+
+// orderSubscribers holds the functions notified of withdrawn orders
+type orderSubscribers struct {
+	mu   sync.RWMutex
+	list []OrderSubscriber
+}
+
+// Subscribe registers a function that is called for every withdrawn order
+func (s *MaintenanceService) Subscribe(subscriber OrderSubscriber) {
+	s.subscribers.mu.Lock()
+	defer s.subscribers.mu.Unlock()
+	s.subscribers.list = append(s.subscribers.list, subscriber)
+}
+
+// publish notifies the subscribers of a withdrawn order
+func (s *MaintenanceService) publish(cancellation *models.OrderCancellation) {
+	s.subscribers.mu.RLock()
+	subscribers := append([]OrderSubscriber(nil), s.subscribers.list...)
+	s.subscribers.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		copied := *cancellation
+		subscriber(&copied)
+	}
+}
+
+// CancelMaintenanceOrderByEvent withdraws the order created for a Digital Twin event. An event
+// that has no order yet is withdrawn where it waits: it is taken out of the aggregation buffer, its
+// pending approval is closed, or, in notification-only mode, its notification is completed and the
+// wait for the order stops. Other events are refused with ErrEventNotFound.
+func (s *MaintenanceService) CancelMaintenanceOrderByEvent(ctx context.Context, eventID, reason string) (*models.OrderCancellation, error) {
+	if order, ok := s.tracker.FindByEvent(eventID); ok {
+		return s.CancelMaintenanceOrder(ctx, order.OrderID, reason)
+	}
+	if reason == "" {
+		reason = defaultCancelReason
+	}
+
+	if waiting, ok := s.awaiting.find(eventID); ok {
+		return s.withdrawAwaitingOrder(ctx, waiting, reason)
+	}
+
+	cancellation := &models.OrderCancellation{
+		EventIDs:    []string{eventID},
+		Action:      CancelActionWithdrawn,
+		Status:      statusWithdrawn,
+		Reason:      reason,
+		CancelledAt: time.Now(),
+	}
+	if s.aggregator != nil {
+		if buffered, ok := s.aggregator.Withdraw(eventID); ok {
+			cancellation.AggregationID = buffered.ID
+			cancellation.PreviousStatus = StatusBuffered
+			s.logger.WithFields(logrus.Fields{
+				"eventId":       eventID,
+				"aggregationId": buffered.ID,
+			}).Info("Maintenance order event withdrawn from the aggregation buffer")
+			return cancellation, nil
+		}
+	}
+	if s.approvals != nil {
+		if approval, ok := s.approvals.Withdraw(eventID, reason); ok {
+			cancellation.ApprovalID = approval.ID
+			cancellation.PreviousStatus = StatusPendingApproval
+			s.logger.WithFields(logrus.Fields{
+				"eventId":    eventID,
+				"approvalId": approval.ID,
+			}).Info("Maintenance order event withdrawn while waiting for approval")
+			return cancellation, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+}
+
+// withdrawAwaitingOrder completes the notification of a notification-only event whose order SAP
+// has not created yet and stops waiting for it. If SAP attached an order in the meantime, that
+// order is cancelled instead.
+func (s *MaintenanceService) withdrawAwaitingOrder(ctx context.Context, waiting *awaitingOrder, reason string) (*models.OrderCancellation, error) {
+	sapClient := waiting.sapClient
+	notification, err := sapClient.GetNotification(ctx, waiting.notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification from SAP: %w", err)
+	}
+
+	if notification.MaintenanceOrder != "" {
+		cancellation, err := s.CancelMaintenanceOrder(ctx, notification.MaintenanceOrder, reason)
+		if err != nil {
+			return nil, err
+		}
+		waiting.stop(ErrOrderCancelled)
+		if len(cancellation.EventIDs) == 0 {
+			cancellation.EventIDs = waiting.eventIDs
+		}
+		return cancellation, nil
+	}
+
+	if err := s.completeWithoutAction(ctx, sapClient, waiting.notificationID, reason); err != nil {
+		return nil, err
+	}
+	waiting.stop(ErrOrderCancelled)
+
+	cancellation := &models.OrderCancellation{
+		NotificationID: waiting.notificationID,
+		EventIDs:       waiting.eventIDs,
+		Destination:    sapClient.Name(),
+		PreviousStatus: StatusAwaitingOrder,
+		Action:         CancelActionNotificationCompleted,
+		Status:         statusNotificationCompleted,
+		Reason:         reason,
+		CancelledAt:    time.Now(),
+	}
+	s.publish(cancellation)
+
+	s.logger.WithFields(logrus.Fields{
+		"notificationId": waiting.notificationID,
+		"destination":    sapClient.Name(),
+	}).Info("Notification of withdrawn event completed before SAP created the order")
+
+	return cancellation, nil
+}
+
+// CancelMaintenanceOrder withdraws an order the Digital Twin no longer needs. An order that is
+// still created gets the deletion flag; a released order without confirmed work is locked. The
+// notification is completed with a no-action reason, monitoring stops and subscribers are notified.
+// Orders with confirmed work or completed orders are refused with ErrOrderNotCancellable.
+func (s *MaintenanceService) CancelMaintenanceOrder(ctx context.Context, orderID, reason string) (*models.OrderCancellation, error) {
+	sapClient := s.clientForOrder(orderID)
+	if reason == "" {
+		reason = defaultCancelReason
+	}
+
+	logger := s.logger.WithFields(logrus.Fields{
+		"orderId":     orderID,
+		"destination": sapClient.Name(),
+	})
+	logger.Info("Cancelling maintenance order")
+
+	orderResp, err := sapClient.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order from SAP: %w", err)
+	}
+	order := &orderResp.D
+
+	action, status := CancelActionDeletionFlag, statusDeletionFlag
+	switch order.OrderStatus {
+	case "CRTD":
+	case "TECO", "CLSD", statusDeletionFlag, statusLocked:
+		return nil, fmt.Errorf("%w: order %s has status %s", ErrOrderNotCancellable, orderID, order.OrderStatus)
+	default:
+		confirmations, err := sapClient.GetOrderConfirmations(ctx, orderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order confirmations: %w", err)
+		}
+		confirmed := 0
+		for _, confirmation := range confirmations {
+			if !confirmation.IsReversed {
+				confirmed++
+			}
+		}
+		if confirmed > 0 {
+			return nil, fmt.Errorf("%w: order %s is released and has %d confirmations", ErrOrderNotCancellable, orderID, confirmed)
+		}
+		action, status = CancelActionLocked, statusLocked
+	}
+
+	if action == CancelActionDeletionFlag {
+		err = sapClient.SetOrderDeletionFlag(ctx, orderID)
+	} else {
+		err = sapClient.LockOrder(ctx, orderID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel order in SAP: %w", err)
+	}
+
+	cancellation := &models.OrderCancellation{
+		OrderID:        orderID,
+		NotificationID: order.MaintenanceNotification,
+		Destination:    sapClient.Name(),
+		PreviousStatus: order.OrderStatus,
+		Action:         action,
+		Status:         status,
+		Reason:         reason,
+		CancelledAt:    time.Now(),
+	}
+	if tracked, ok := s.tracker.Get(orderID); ok {
+		cancellation.EventIDs = tracked.EventIDs
+		if cancellation.NotificationID == "" {
+			cancellation.NotificationID = tracked.NotificationID
+		}
+	}
+
+	if cancellation.NotificationID != "" {
+		if err := s.completeWithoutAction(ctx, sapClient, cancellation.NotificationID, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	s.tracker.UpdateStatus(orderID, status)
+	if stopped := s.monitors.stop(orderID, ErrOrderCancelled); stopped > 0 {
+		logger.WithField("monitors", stopped).Info("Stopped monitoring of cancelled order")
+	}
+	s.publish(cancellation)
+
+	logger.WithFields(logrus.Fields{
+		"action":         action,
+		"notificationId": cancellation.NotificationID,
+	}).Info("Maintenance order cancelled successfully")
+
+	return cancellation, nil
+}
+
+// completeWithoutAction records the no-action reason on a notification and completes it
+func (s *MaintenanceService) completeWithoutAction(ctx context.Context, sapClient *sap.Client, notificationID, reason string) error {
+	note := fmt.Sprintf("No action required: %s (withdrawn at %s)", reason, time.Now().UTC().Format("2006-01-02 15:04:05 UTC"))
+	if _, err := sapClient.UpdateNotification(ctx, notificationID, &models.SAPNotificationUpdateRequest{NotificationText: note}, sap.AnyETag); err != nil {
+		return fmt.Errorf("failed to add no-action reason to SAP notification %s: %w", notificationID, err)
+	}
+	if err := sapClient.CompleteNotification(ctx, notificationID); err != nil {
+		return fmt.Errorf("failed to complete SAP notification %s: %w", notificationID, err)
+	}
+	return nil
+}
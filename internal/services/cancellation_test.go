@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCancelMaintenanceOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := NewMaintenanceService(sap.NewRouter(config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60}, nil, logger), logger)
+	client := service.destinations.Default()
+
+	var published []*models.OrderCancellation
+	service.Subscribe(func(cancellation *models.OrderCancellation) {
+		published = append(published, cancellation)
+	})
+
+	// A created order is flagged for deletion and its monitor stops
+	service.tracker.Track("400000120", "200000120", client.Name(), "CRTD", &models.MaintenanceOrderEvent{EventID: "DT-1", EquipmentID: "10000045", Plant: "1000"})
+	monitored := make(chan error, 1)
+	go func() {
+		monitored <- service.MonitorOrderStatus(context.Background(), "400000120", nil)
+	}()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		service.monitors.mu.Lock()
+		running := len(service.monitors.cancels["400000120"])
+		service.monitors.mu.Unlock()
+		if running > 0 || time.Now().After(deadline) {
+			break
+		}
+	}
+
+	cancellation, err := service.CancelMaintenanceOrderByEvent(context.Background(), "DT-1", "False positive")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancellation.Action != CancelActionDeletionFlag || cancellation.OrderID != "400000120" || cancellation.Reason != "False positive" {
+		t.Errorf("Expected the deletion flag on the order, got %+v", cancellation)
+	}
+	if tracked, _ := service.tracker.Get("400000120"); tracked.Status != statusDeletionFlag {
+		t.Errorf("Expected tracked status %s, got %s", statusDeletionFlag, tracked.Status)
+	}
+	select {
+	case err := <-monitored:
+		if !errors.Is(err, ErrOrderCancelled) {
+			t.Errorf("Expected the monitor to stop with ErrOrderCancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the monitor to stop")
+	}
+	if len(published) != 1 || published[0].EventIDs[0] != "DT-1" {
+		t.Errorf("Expected one cancellation for the subscriber, got %+v", published)
+	}
+
+	// A released order without confirmations is locked
+	cancellation, err = service.CancelMaintenanceOrder(context.Background(), "400000123", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancellation.Action != CancelActionLocked || cancellation.Reason != defaultCancelReason {
+		t.Errorf("Expected the order to be locked, got %+v", cancellation)
+	}
+
+	// Confirmed work refuses the cancellation
+	if _, err := client.CreateConfirmation(context.Background(), &models.SAPConfirmationRequest{MaintenanceOrder: "400000124", MaintenanceOrderOperation: "0010", ActualWorkQuantity: "1.5"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.CancelMaintenanceOrder(context.Background(), "400000124", ""); !errors.Is(err, ErrOrderNotCancellable) {
+		t.Errorf("Expected ErrOrderNotCancellable, got %v", err)
+	}
+	if _, err := service.CancelMaintenanceOrder(context.Background(), "400000126", ""); !errors.Is(err, ErrOrderNotCancellable) {
+		t.Errorf("Expected ErrOrderNotCancellable for a completed order, got %v", err)
+	}
+	if _, err := service.CancelMaintenanceOrderByEvent(context.Background(), "DT-unknown", ""); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+}
+
+func TestCancelMaintenanceOrderByEventBeforeOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := NewMaintenanceService(sap.NewRouter(config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60}, nil, logger), logger)
+	service.EnableAggregation(config.AggregationConfig{WindowSeconds: 60})
+	service.EnableApprovals(config.ApprovalConfig{ShutdownRequired: true})
+	ctx := context.Background()
+
+	var published []*models.OrderCancellation
+	service.Subscribe(func(cancellation *models.OrderCancellation) {
+		published = append(published, cancellation)
+	})
+
+	// A buffered event is taken out of the aggregation buffer
+	response, err := service.SubmitMaintenanceOrderEvent(ctx, &models.MaintenanceOrderEvent{EventID: "DT-2", EquipmentID: "10000045", Plant: "1000", Description: "Replace seal"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cancellation, err := service.CancelMaintenanceOrderByEvent(ctx, "DT-2", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancellation.Action != CancelActionWithdrawn || cancellation.AggregationID != response.AggregationID || cancellation.PreviousStatus != StatusBuffered {
+		t.Errorf("Expected the buffered event to be withdrawn, got %+v", cancellation)
+	}
+	if buffered, _ := service.GetAggregatedEvent(response.AggregationID); buffered.Status != AggregationWithdrawn {
+		t.Errorf("Expected aggregated event status %s, got %s", AggregationWithdrawn, buffered.Status)
+	}
+
+	// A parked event can no longer be approved
+	response, err = service.SubmitMaintenanceOrderEvent(ctx, &models.MaintenanceOrderEvent{EventID: "DT-3", EquipmentID: "10000045", Plant: "1000", Description: "Replace bearing", ShutdownRequired: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cancellation, err = service.CancelMaintenanceOrderByEvent(ctx, "DT-3", "Sensor drift")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancellation.ApprovalID != response.ApprovalID || cancellation.PreviousStatus != StatusPendingApproval {
+		t.Errorf("Expected the pending approval to be withdrawn, got %+v", cancellation)
+	}
+	if _, err := service.ApproveEvent(ctx, response.ApprovalID, &models.ApprovalDecision{}); !errors.Is(err, ErrApprovalDecided) {
+		t.Errorf("Expected ErrApprovalDecided, got %v", err)
+	}
+
+	// A notification-only event gets its notification completed and the wait stops
+	service.awaitOrder(service.destinations.Default(), "200000120", &models.MaintenanceOrderEvent{EventID: "DT-4"})
+	cancellation, err = service.CancelMaintenanceOrderByEvent(ctx, "DT-4", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancellation.Action != CancelActionNotificationCompleted || cancellation.NotificationID != "200000120" || cancellation.OrderID != "" {
+		t.Errorf("Expected the notification to be completed, got %+v", cancellation)
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if _, waiting := service.awaiting.find("DT-4"); !waiting {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the wait for the order to stop")
+		}
+	}
+	if len(published) != 1 || published[0].NotificationID != "200000120" {
+		t.Errorf("Expected the completed notification for the subscriber, got %+v", published)
+	}
+
+	// Withdrawn events are gone
+	if _, err := service.CancelMaintenanceOrderByEvent(ctx, "DT-2", ""); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Duplicate policies: what is done with an event that repeats the fault of an open notification
+const (
+	DuplicateAttach   = "attach"   // Return the open notification and order, nothing is written to SAP
+	DuplicateNote     = "note"     // Add the event to the long text of the open notification
+	DuplicateEscalate = "escalate" // Raise the priority of the open notification and order if the event is more urgent
+)
+
+// maxDuplicateDecisions is the number of decisions kept in memory
+const maxDuplicateDecisions = 1000
+
+// DuplicateLog keeps the most recent duplicate decisions
+type DuplicateLog struct {
+	mu        sync.RWMutex
+	decisions []models.DuplicateDecision
+}
+
+// NewDuplicateLog creates an empty duplicate decision log
+func NewDuplicateLog() *DuplicateLog {
+	return &DuplicateLog{}
+}
+
+// Record adds a decision, dropping the oldest one when the log is full
+func (l *DuplicateLog) Record(decision models.DuplicateDecision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.decisions = append(l.decisions, decision)
+	if len(l.decisions) > maxDuplicateDecisions {
+		l.decisions = l.decisions[len(l.decisions)-maxDuplicateDecisions:]
+	}
+}
+
+// List returns the decisions for an equipment, or all decisions, newest first
+func (l *DuplicateLog) List(equipmentID string) []models.DuplicateDecision {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	decisions := make([]models.DuplicateDecision, 0)
+	for i := len(l.decisions) - 1; i >= 0; i-- {
+		if equipmentID == "" || l.decisions[i].EquipmentID == equipmentID {
+			decisions = append(decisions, l.decisions[i])
+		}
+	}
+	return decisions
+}
+
+// faultCodes returns the damage codes of a notification request, as code group/code
+func faultCodes(items []models.SAPNotificationItem) []string {
+	var codes []string
+	for _, item := range items {
+		if item.MaintNotificationDamageCode != "" {
+			codes = append(codes, item.MaintNotifDamageCodeGroup+"/"+item.MaintNotificationDamageCode)
+		}
+	}
+	return codes
+}
+
+// FindDuplicate returns the open notification with one of the fault codes and the code it matched.
+// An open notification that already has an order is preferred, then the newest one.
+func FindDuplicate(notifications []models.SAPNotification, codes []string) (*models.SAPNotification, string) {
+	var match *models.SAPNotification
+	matchedCode := ""
+	for i := range notifications {
+		notification := &notifications[i]
+		if notification.ToItem == nil {
+			continue
+		}
+		for _, code := range faultCodes(notification.ToItem.Results) {
+			if !containsFold(codes, code) {
+				continue
+			}
+			if match == nil || preferDuplicate(notification, match) {
+				match, matchedCode = notification, code
+			}
+			break
+		}
+	}
+	return match, matchedCode
+}
+
+// preferDuplicate reports whether notification a is a better match than b: only a has an order,
+// or both are alike and a is newer
+func preferDuplicate(a, b *models.SAPNotification) bool {
+	if (a.MaintenanceOrder != "") != (b.MaintenanceOrder != "") {
+		return a.MaintenanceOrder != ""
+	}
+	return a.Notification > b.Notification
+}
+
+// containsFold reports whether values contain value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// MoreUrgent reports whether priority a is more urgent than b. SAP priorities count up from 1
+// (very high); other values are compared as text.
+func MoreUrgent(a, b string) bool {
+	if a == "" {
+		return false
+	}
+	if b == "" {
+		return true
+	}
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return a < b
+}
+
+// handleDuplicate checks for an open notification with the fault of the event before a new one is
+// created. It returns nil if the event is not a duplicate; otherwise the configured policy has been
+// applied to the open notification and the decision recorded.
+func (s *MaintenanceService) handleDuplicate(ctx context.Context, sapClient *sap.Client, event *models.MaintenanceOrderEvent, notificationReq *models.SAPNotificationRequest) (*models.DuplicateDecision, error) {
+	settings := sapClient.DuplicateDetection()
+	codes := faultCodes(notificationReq.ToItem)
+	if settings.WindowHours <= 0 || len(codes) == 0 {
+		return nil, nil
+	}
+
+	since := time.Now().Add(-time.Duration(settings.WindowHours) * time.Hour)
+	notifications, err := sapClient.FindOpenNotifications(ctx, event.EquipmentID, since)
+	if err != nil {
+		// A failed check must not hold back maintenance work
+		s.logger.WithError(err).WithField("equipmentId", event.EquipmentID).Warn("Duplicate check failed, creating a new notification")
+		return nil, nil
+	}
+	existing, code := FindDuplicate(notifications, codes)
+	if existing == nil {
+		return nil, nil
+	}
+
+	policy := strings.ToLower(settings.Policy)
+	if policy == "" {
+		policy = DuplicateAttach
+	}
+	decision := &models.DuplicateDecision{
+		EquipmentID:      event.EquipmentID,
+		FaultCode:        code,
+		Destination:      sapClient.Name(),
+		NotificationID:   existing.Notification,
+		OrderID:          existing.MaintenanceOrder,
+		Policy:           policy,
+		Action:           "attached",
+		PreviousPriority: existing.Priority,
+		Priority:         existing.Priority,
+		Description:      event.Description,
+		DecidedAt:        time.Now(),
+	}
+
+	switch policy {
+	case DuplicateNote:
+		note := fmt.Sprintf("Repeated fault reported by Digital Twin at %s: %s",
+			decision.DecidedAt.UTC().Format("2006-01-02 15:04:05 UTC"), event.Description)
+		if longText := sap.BuildNotificationLongText(event); longText != "" {
+			note += "\n\n" + longText
+		}
+		if _, err := sapClient.UpdateNotification(ctx, existing.Notification, &models.SAPNotificationUpdateRequest{NotificationText: note}, sap.AnyETag); err != nil {
+			return nil, fmt.Errorf("failed to add note to SAP notification %s: %w", existing.Notification, err)
+		}
+		decision.Action = "noted"
+	case DuplicateEscalate:
+		if !MoreUrgent(event.Priority, existing.Priority) {
+			break
+		}
+		if _, err := sapClient.UpdateNotification(ctx, existing.Notification, &models.SAPNotificationUpdateRequest{Priority: event.Priority}, sap.AnyETag); err != nil {
+			return nil, fmt.Errorf("failed to escalate SAP notification %s: %w", existing.Notification, err)
+		}
+		if existing.MaintenanceOrder != "" {
+			if _, err := sapClient.UpdateOrder(ctx, existing.MaintenanceOrder, &models.SAPOrderUpdateRequest{Priority: event.Priority}, sap.AnyETag); err != nil {
+				return nil, fmt.Errorf("failed to escalate SAP order %s: %w", existing.MaintenanceOrder, err)
+			}
+			s.tracker.UpdatePriority(existing.MaintenanceOrder, event.Priority)
+		}
+		decision.Action = "escalated"
+		decision.Priority = event.Priority
+	}
+
+	s.duplicates.Record(*decision)
+	s.logger.WithFields(logrus.Fields{
+		"equipmentId":    decision.EquipmentID,
+		"faultCode":      decision.FaultCode,
+		"notificationId": decision.NotificationID,
+		"orderId":        decision.OrderID,
+		"policy":         decision.Policy,
+		"action":         decision.Action,
+		"priority":       decision.Priority,
+	}).Info("Event repeats the fault of an open notification")
+
+	return decision, nil
+}
+
+// ListDuplicateDecisions returns the recorded duplicate decisions, newest first
+func (s *MaintenanceService) ListDuplicateDecisions(equipmentID string) []models.DuplicateDecision {
+	return s.duplicates.List(equipmentID)
+}
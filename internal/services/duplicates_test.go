@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRepeatedFaultEscalatesOpenNotification(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cfg := config.SAPConfig{
+		SimulatorMode:      true,
+		MasterDataCacheTTL: 60,
+		FaultClasses: map[string]config.FaultClassCatalog{
+			"bearing_wear": {DamageCodeGroup: "PM-DMG", DamageCode: "WEAR"},
+		},
+		Duplicates: config.DuplicateDetection{WindowHours: 72, Policy: "escalate"},
+	}
+	service := NewMaintenanceService(sap.NewRouter(cfg, nil, logger), logger)
+
+	event := &models.MaintenanceOrderEvent{
+		EquipmentID: "10000045",
+		Plant:       "1000",
+		Description: "Bearing vibration above limit",
+		Priority:    "1",
+		FaultClass:  "bearing_wear",
+	}
+	response, err := service.ProcessMaintenanceOrderEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The simulator has open notifications with this damage code; the newest one with an order wins
+	decision := response.Duplicate
+	if decision == nil {
+		t.Fatalf("Expected the event to be detected as duplicate, got %+v", response)
+	}
+	if decision.NotificationID != "200000105" || decision.OrderID != "400000105" || response.OrderID != "400000105" {
+		t.Errorf("Expected notification 200000105 with order 400000105, got %+v", decision)
+	}
+	if decision.Action != "escalated" || decision.PreviousPriority != "3" || decision.Priority != "1" || decision.FaultCode != "PM-DMG/WEAR" {
+		t.Errorf("Unexpected decision %+v", decision)
+	}
+	if recorded := service.ListDuplicateDecisions("10000045"); len(recorded) != 1 || recorded[0] != *decision {
+		t.Errorf("Expected the decision to be recorded, got %+v", recorded)
+	}
+
+	// Another fault creates a new notification
+	event.FaultClass = ""
+	response, err = service.ProcessMaintenanceOrderEvent(context.Background(), event)
+	if err != nil || response.Duplicate != nil {
+		t.Errorf("Expected a new order for an event without fault code, got %+v (%v)", response, err)
+	}
+}
+
+func TestMoreUrgent(t *testing.T) {
+	if !MoreUrgent("1", "3") || MoreUrgent("3", "1") || MoreUrgent("2", "2") || MoreUrgent("", "3") || !MoreUrgent("2", "") {
+		t.Error("Unexpected priority comparison")
+	}
+}
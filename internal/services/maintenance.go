@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"sap-adaptor/internal/models"
@@ -11,53 +13,238 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	// ErrOperationExists is returned when an operation number is already used on the order
+	ErrOperationExists = errors.New("operation already exists on order")
+	// ErrConfirmationNotFound is returned when a confirmation does not exist for the order and operation
+	ErrConfirmationNotFound = errors.New("confirmation not found for order operation")
+)
+
 // MaintenanceService handles maintenance order business logic
 type MaintenanceService struct {
-	sapClient *sap.Client
-	logger    *logrus.Logger
+	destinations *sap.Router
+	tracker      *OrderTracker
+	masterData   map[string]*MasterDataValidator // Per SAP destination
+	rules        map[string]*RulesEngine         // Per SAP destination
+	priority     map[string]*PriorityCalculator  // Per SAP destination
+	templates    *TemplateRegistry
+	duplicates   *DuplicateLog
+	aggregator   *EventAggregator // Nil unless aggregation is enabled
+	approvals    *ApprovalQueue   // Nil unless the approval gate is enabled
+	monitors     orderMonitors
+	awaiting     awaitingOrders // Notification-only events waiting for their order
+	subscribers  orderSubscribers
+	metadata     *MetadataMonitor
+	logger       *logrus.Logger
 }
 
 // NewMaintenanceService creates a new maintenance service
-func NewMaintenanceService(sapClient *sap.Client, logger *logrus.Logger) *MaintenanceService {
+func NewMaintenanceService(destinations *sap.Router, logger *logrus.Logger) *MaintenanceService {
+	masterData := make(map[string]*MasterDataValidator)
+	rules := make(map[string]*RulesEngine)
+	priority := make(map[string]*PriorityCalculator)
+	for _, client := range destinations.Clients() {
+		masterData[client.Name()] = NewMasterDataValidator(client, logger)
+		rules[client.Name()] = NewRulesEngine(client.DeterminationRules())
+		priority[client.Name()] = NewPriorityCalculator(client.PriorityConfig())
+	}
+
 	return &MaintenanceService{
-		sapClient: sapClient,
-		logger:    logger,
+		destinations: destinations,
+		tracker:      NewOrderTracker(),
+		masterData:   masterData,
+		rules:        rules,
+		priority:     priority,
+		templates:    NewTemplateRegistry(),
+		duplicates:   NewDuplicateLog(),
+		metadata:     NewMetadataMonitor(destinations, logger),
+		logger:       logger,
+	}
+}
+
+// CheckSAPMetadata compares the fields sent to SAP with the $metadata of every destination
+func (s *MaintenanceService) CheckSAPMetadata(ctx context.Context) {
+	s.metadata.Check(ctx)
+}
+
+// SAPMetadataStatus returns the result of the last $metadata check per destination
+func (s *MaintenanceService) SAPMetadataStatus() []models.SAPMetadataStatus {
+	return s.metadata.Statuses()
+}
+
+// clientForOrder returns the SAP destination an order was created in. Orders that were not
+// created through the adaptor are looked up in the default destination.
+func (s *MaintenanceService) clientForOrder(orderID string) *sap.Client {
+	if order, ok := s.tracker.Get(orderID); ok {
+		if client, ok := s.destinations.Client(order.Destination); ok {
+			return client
+		}
 	}
+	return s.destinations.Default()
+}
+
+// clientForNotification returns the SAP destination of a notification created through the
+// adaptor, or the default destination
+func (s *MaintenanceService) clientForNotification(notificationID string) *sap.Client {
+	if order, ok := s.tracker.FindByNotification(notificationID); ok {
+		if client, ok := s.destinations.Client(order.Destination); ok {
+			return client
+		}
+	}
+	return s.destinations.Default()
 }
 
 // ProcessMaintenanceOrderEvent processes a maintenance order event following the SAP integration workflow
 func (s *MaintenanceService) ProcessMaintenanceOrderEvent(ctx context.Context, event *models.MaintenanceOrderEvent) (*models.MaintenanceOrderResponse, error) {
+	sapClient := s.destinations.Route(event.Plant, event.CompanyCode)
+
 	s.logger.WithFields(logrus.Fields{
 		"equipmentId": event.EquipmentID,
 		"plant":       event.Plant,
 		"description": event.Description,
+		"destination": sapClient.Name(),
 	}).Info("Processing maintenance order event")
 
-	// Step 1: Create SAP Maintenance Notification
-	s.logger.Info("Step 1: Creating SAP maintenance notification")
-	notificationReq := sap.ConvertMaintenanceOrderEventToNotificationRequest(event)
-	notificationResp, err := s.sapClient.CreateNotification(ctx, notificationReq)
+	if err := s.prepareEvent(ctx, sapClient, event); err != nil {
+		return nil, err
+	}
+
+	notificationExtensions, orderExtensions, err := sapClient.ConvertEventExtensions(event.Extensions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create SAP notification: %w", err)
+		return nil, err
 	}
 
-	notificationID := notificationResp.D.Notification
-	s.logger.WithField("notificationId", notificationID).Info("SAP notification created successfully")
+	mode := s.workflowMode(sapClient, event)
+	notificationID := ""
+	if mode != WorkflowOrderOnly {
+		// Step 1: Create SAP Maintenance Notification
+		s.logger.Info("Step 1: Creating SAP maintenance notification")
+		notificationReq := sap.ConvertMaintenanceOrderEventToNotificationRequest(event, sapClient.FaultClassCatalogs(), sapClient.FieldMapping())
+		notificationReq.Extensions = notificationExtensions
+
+		// A repeated fault on the equipment is handled on the open notification instead. Orders
+		// carry no damage code, so there is no such check in order-only mode.
+		duplicate, err := s.handleDuplicate(ctx, sapClient, event, notificationReq)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate != nil {
+			return &models.MaintenanceOrderResponse{
+				OrderID:        duplicate.OrderID,
+				NotificationID: duplicate.NotificationID,
+				Destination:    sapClient.Name(),
+				Status:         "DUPLICATE",
+				Message:        "Fault already reported in open notification " + duplicate.NotificationID + " (" + duplicate.Action + ")",
+				CreatedAt:      duplicate.DecidedAt,
+				Duplicate:      duplicate,
+				WorkflowMode:   mode,
+			}, nil
+		}
+
+		notificationResp, err := sapClient.CreateNotification(ctx, notificationReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SAP notification: %w", err)
+		}
+
+		notificationID = notificationResp.D.Notification
+		s.logger.WithField("notificationId", notificationID).Info("SAP notification created successfully")
+
+		// SAP creates the order from the notification; it is tracked once it is attached
+		if mode == WorkflowNotificationOnly {
+			s.awaitOrder(sapClient, notificationID, event)
+			return &models.MaintenanceOrderResponse{
+				NotificationID: notificationID,
+				Destination:    sapClient.Name(),
+				Status:         StatusAwaitingOrder,
+				Message:        "Maintenance notification created, waiting for SAP to create the order",
+				CreatedAt:      time.Now(),
+				WorkflowMode:   mode,
+			}, nil
+		}
+	}
 
-	// Step 2: Create SAP Maintenance Order with notification reference
+	// Step 2: Create SAP Maintenance Order, with the notification reference in full mode
 	s.logger.Info("Step 2: Creating SAP maintenance order")
-	orderReq := sap.ConvertMaintenanceOrderEventToOrderRequest(event, notificationID)
-	orderResp, err := s.sapClient.CreateOrder(ctx, orderReq)
+	orderReq := sap.ConvertMaintenanceOrderEventToOrderRequest(event, notificationID, sapClient.FieldMapping())
+	orderReq.Extensions = orderExtensions
+	orderResp, err := sapClient.CreateOrder(ctx, orderReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create SAP order: %w", err)
+		err = fmt.Errorf("failed to create SAP order: %w", err)
+		if notificationID != "" {
+			err = s.closeOrphanedNotification(ctx, sapClient, notificationID, err)
+		}
+		return nil, err
 	}
 
 	orderID := orderResp.D.MaintenanceOrder
 	s.logger.WithField("orderId", orderID).Info("SAP maintenance order created successfully")
 
-	// Step 3: Verify order was created successfully
+	// Step 3: Verify the order and start tracking it
+	response, err := s.trackOrder(ctx, sapClient, event, orderID, notificationID)
+	if err != nil {
+		return nil, err
+	}
+	response.Message = "Maintenance order created successfully"
+	response.WorkflowMode = mode
+	return response, nil
+}
+
+// closeOrphanedNotification completes the notification of an event whose order could not be
+// created. Notification and order belong to separate OData services, so they cannot be created in
+// one $batch changeset; without this the fault would stay open in SAP without an order. The
+// returned error is cause, extended if the notification could not be completed.
+func (s *MaintenanceService) closeOrphanedNotification(ctx context.Context, sapClient *sap.Client, notificationID string, cause error) error {
+	// The caller may have gone away; the notification is completed regardless
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+
+	logger := s.logger.WithField("notificationId", notificationID)
+	if err := s.completeWithoutAction(ctx, sapClient, notificationID, "Order creation failed: "+cause.Error()); err != nil {
+		logger.WithError(err).Error("Failed to complete notification of failed order creation, it is left open")
+		return fmt.Errorf("%w (notification %s is left open: %v)", cause, notificationID, err)
+	}
+	logger.Warn("Order creation failed, notification completed")
+	return fmt.Errorf("%w (notification %s was completed)", cause, notificationID)
+}
+
+// prepareEvent validates an event against the master data and completes it before anything is
+// written to SAP: template operations, priority and SLA dates, order and notification types and
+// control keys. Values already set are kept, so a prepared event can be prepared again.
+func (s *MaintenanceService) prepareEvent(ctx context.Context, sapClient *sap.Client, event *models.MaintenanceOrderEvent) error {
+	// Validate and enrich master data
+	if err := s.masterData[sapClient.Name()].ValidateEvent(ctx, event); err != nil {
+		return err
+	}
+
+	// Fill operations and components from the task list template of the failure mode
+	if err := s.applyTemplate(ctx, sapClient, event); err != nil {
+		return err
+	}
+
+	// Derive the priority from severity and criticality, and missing dates from its SLA
+	if severity := s.priority[sapClient.Name()].Apply(event, time.Now()); severity != "" {
+		s.logger.WithFields(logrus.Fields{
+			"severity":    severity,
+			"criticality": event.Criticality,
+			"priority":    event.Priority,
+		}).Info("Priority derived from severity and criticality")
+	}
+
+	// Determine order type, notification type and control keys the event leaves open
+	decisions := s.rules[sapClient.Name()].Apply(event)
+	s.logger.WithFields(logrus.Fields{
+		"orderType":        event.MaintenanceOrderType,
+		"orderTypeSource":  decisions.OrderType.Source,
+		"notificationType": event.NotificationType,
+	}).Info("Determination rules applied")
+	return nil
+}
+
+// trackOrder verifies an order created for an event and keeps it in the local tracking store.
+// Every workflow mode ends here, so all orders share the same tracking and completion handling.
+func (s *MaintenanceService) trackOrder(ctx context.Context, sapClient *sap.Client, event *models.MaintenanceOrderEvent, orderID, notificationID string) (*models.MaintenanceOrderResponse, error) {
 	s.logger.Info("Step 3: Verifying order creation")
-	verifyResp, err := s.sapClient.GetOrder(ctx, orderID)
+	verifyResp, err := sapClient.GetOrder(ctx, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify order creation: %w", err)
 	}
@@ -72,30 +259,64 @@ func (s *MaintenanceService) ProcessMaintenanceOrderEvent(ctx context.Context, e
 		"status":         verifyResp.D.OrderStatus,
 	}).Info("Order verification completed successfully")
 
-	// Return success response
-	response := &models.MaintenanceOrderResponse{
+	// Keep the order in the local tracking store
+	s.tracker.Track(orderID, notificationID, sapClient.Name(), verifyResp.D.OrderStatus, event)
+
+	return &models.MaintenanceOrderResponse{
 		OrderID:        orderID,
 		NotificationID: notificationID,
+		Destination:    sapClient.Name(),
 		Status:         verifyResp.D.OrderStatus,
-		Message:        "Maintenance order created successfully",
 		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// EvaluateRules shows the order type, notification type and control keys an event would get,
+// after master data enrichment, without writing anything to SAP
+func (s *MaintenanceService) EvaluateRules(ctx context.Context, event *models.MaintenanceOrderEvent) (*models.RuleEvaluation, error) {
+	sapClient := s.destinations.Route(event.Plant, event.CompanyCode)
+
+	if err := s.masterData[sapClient.Name()].ValidateEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	if err := s.applyTemplate(ctx, sapClient, event); err != nil {
+		return nil, err
 	}
 
-	return response, nil
+	evaluation := s.rules[sapClient.Name()].Evaluate(event)
+	evaluation.Destination = sapClient.Name()
+	return evaluation, nil
 }
 
 // GetMaintenanceOrderStatus retrieves the current status of a maintenance order
 func (s *MaintenanceService) GetMaintenanceOrderStatus(ctx context.Context, orderID string) (*models.MaintenanceOrderStatus, error) {
+	sapClient := s.clientForOrder(orderID)
+
 	s.logger.WithField("orderId", orderID).Info("Retrieving maintenance order status")
 
 	// Get order from SAP
-	orderResp, err := s.sapClient.GetOrder(ctx, orderID)
+	orderResp, err := sapClient.GetOrder(ctx, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get order from SAP: %w", err)
 	}
 
 	// Convert to status model
 	status := sap.ConvertSAPOrderResponseToStatus(orderResp)
+	status.Destination = sapClient.Name()
+	status.Extensions = sapClient.ExtensionsFromOrder(&orderResp.D)
+
+	// Merge confirmed work into the operations
+	confirmations, err := sapClient.GetOrderConfirmations(ctx, orderID)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"orderId": orderID,
+			"error":   err,
+		}).Warn("Failed to get order confirmations, returning status without confirmed work")
+	} else {
+		sap.ApplyConfirmationsToStatus(status, confirmations)
+	}
+
+	s.tracker.UpdateStatus(status.OrderID, status.Status)
 
 	s.logger.WithFields(logrus.Fields{
 		"orderId": status.OrderID,
@@ -105,6 +326,162 @@ func (s *MaintenanceService) GetMaintenanceOrderStatus(ctx context.Context, orde
 	return status, nil
 }
 
+// ListMaintenanceOrders searches maintenance orders in SAP, or in the local tracking store when
+// the query asks for source "local"
+func (s *MaintenanceService) ListMaintenanceOrders(ctx context.Context, query *models.MaintenanceOrderQuery) (*models.MaintenanceOrderList, error) {
+	offset, err := query.Offset()
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = sap.DefaultOrderPageSize
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"source": query.Source,
+		"offset": offset,
+		"limit":  limit,
+	}).Info("Searching maintenance orders")
+
+	list := &models.MaintenanceOrderList{Orders: []models.MaintenanceOrderStatus{}}
+	var hasMore bool
+
+	if query.Source == "local" {
+		var tracked []models.TrackedOrder
+		tracked, hasMore = s.tracker.List(query, offset, limit)
+		for i := range tracked {
+			list.Orders = append(list.Orders, TrackedOrderToStatus(&tracked[i]))
+		}
+		list.Source = "local"
+	} else if clients := s.destinations.Clients(); query.Plant == "" && len(clients) > 1 {
+		list.Orders, hasMore, err = s.listOrdersAcrossDestinations(ctx, clients, query, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		list.Source = "sap"
+	} else {
+		var orders []models.SAPOrder
+		sapClient := s.destinations.Route(query.Plant, "")
+		orders, hasMore, err = sapClient.ListOrders(ctx, query, offset, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search orders in SAP: %w", err)
+		}
+		for i := range orders {
+			list.Orders = append(list.Orders, *searchedOrderStatus(sapClient, &orders[i]))
+		}
+		list.Source = "sap"
+	}
+
+	list.Count = len(list.Orders)
+	if hasMore {
+		list.NextCursor = models.EncodeOrderCursor(offset + list.Count)
+	}
+
+	return list, nil
+}
+
+// listOrdersAcrossDestinations searches every destination for a query without plant. Each
+// destination returns its first offset+limit+1 matches, which are merged in the requested order
+// and paged here, so deep pages cost more than with a plant filter.
+func (s *MaintenanceService) listOrdersAcrossDestinations(ctx context.Context, clients []*sap.Client, query *models.MaintenanceOrderQuery, offset, limit int) ([]models.MaintenanceOrderStatus, bool, error) {
+	type found struct {
+		client *sap.Client
+		order  models.SAPOrder
+	}
+	var merged []found
+	for _, sapClient := range clients {
+		orders, _, err := sapClient.ListOrders(ctx, query, 0, offset+limit+1)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to search orders in SAP destination %s: %w", sapClient.Name(), err)
+		}
+		for i := range orders {
+			merged = append(merged, found{client: sapClient, order: orders[i]})
+		}
+	}
+
+	less := sap.OrderLess(query.SortBy, query.SortOrder)
+	sort.SliceStable(merged, func(i, j int) bool { return less(&merged[i].order, &merged[j].order) })
+
+	statuses := []models.MaintenanceOrderStatus{}
+	for i := offset; i < len(merged) && len(statuses) < limit; i++ {
+		statuses = append(statuses, *searchedOrderStatus(merged[i].client, &merged[i].order))
+	}
+	return statuses, len(merged) > offset+limit, nil
+}
+
+// searchedOrderStatus converts an order found by a search in a destination
+func searchedOrderStatus(sapClient *sap.Client, order *models.SAPOrder) *models.MaintenanceOrderStatus {
+	status := sap.ConvertSAPOrderResponseToStatus(&models.SAPOrderResponse{D: *order})
+	status.Destination = sapClient.Name()
+	status.Extensions = sapClient.ExtensionsFromOrder(order)
+	return status
+}
+
+// GetNotificationStatus retrieves the current state of a maintenance notification
+func (s *MaintenanceService) GetNotificationStatus(ctx context.Context, notificationID string) (*models.MaintenanceNotificationStatus, error) {
+	sapClient := s.clientForNotification(notificationID)
+
+	s.logger.WithField("notificationId", notificationID).Info("Retrieving maintenance notification status")
+
+	notification, err := sapClient.GetNotification(ctx, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification from SAP: %w", err)
+	}
+
+	status := sap.ConvertSAPNotificationToStatus(notification)
+	status.Destination = sapClient.Name()
+	return status, nil
+}
+
+// ListNotifications lists the maintenance notifications of an equipment
+func (s *MaintenanceService) ListNotifications(ctx context.Context, query *models.MaintenanceNotificationQuery) (*models.MaintenanceNotificationList, error) {
+	offset, err := query.Offset()
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = sap.DefaultOrderPageSize
+	}
+
+	sapClient := s.destinations.Route(query.Plant, "")
+	notifications, hasMore, err := sapClient.ListNotifications(ctx, query, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications in SAP: %w", err)
+	}
+
+	list := &models.MaintenanceNotificationList{Notifications: []models.MaintenanceNotificationStatus{}}
+	for i := range notifications {
+		status := sap.ConvertSAPNotificationToStatus(&notifications[i])
+		status.Destination = sapClient.Name()
+		list.Notifications = append(list.Notifications, *status)
+	}
+	list.Count = len(list.Notifications)
+	if hasMore {
+		list.NextCursor = models.EncodeOrderCursor(offset + list.Count)
+	}
+
+	return list, nil
+}
+
+// UpdateNotification changes a maintenance notification in SAP and returns its new ETag
+func (s *MaintenanceService) UpdateNotification(ctx context.Context, notificationID string, update *models.MaintenanceNotificationUpdate, etag string) (string, error) {
+	sapClient := s.clientForNotification(notificationID)
+
+	s.logger.WithFields(logrus.Fields{
+		"notificationId": notificationID,
+		"etag":           etag,
+	}).Info("Updating maintenance notification")
+
+	newETag, err := sapClient.UpdateNotification(ctx, notificationID, sap.ConvertNotificationUpdateToSAP(update), etag)
+	if err != nil {
+		return "", fmt.Errorf("failed to update SAP notification: %w", err)
+	}
+
+	return newETag, nil
+}
+
 // HandleMaintenanceDoneEvent processes a maintenance done event from SAP
 func (s *MaintenanceService) HandleMaintenanceDoneEvent(ctx context.Context, event *models.MaintenanceDoneEvent) error {
 	s.logger.WithFields(logrus.Fields{
@@ -136,10 +513,230 @@ func (s *MaintenanceService) HandleMaintenanceDoneEvent(ctx context.Context, eve
 	return nil
 }
 
+// UpdateMaintenanceOrder applies the changed fields of an event to an existing order in SAP.
+// The given ETag of the order guards header and operation changes; operations are matched by their
+// operation ID.
+func (s *MaintenanceService) UpdateMaintenanceOrder(ctx context.Context, orderID string, event *models.MaintenanceOrderEvent, etag string) (*models.MaintenanceOrderUpdateResponse, error) {
+	sapClient := s.clientForOrder(orderID)
+
+	s.logger.WithFields(logrus.Fields{
+		"orderId":    orderID,
+		"etag":       etag,
+		"operations": len(event.Operations),
+	}).Info("Updating maintenance order")
+
+	response := &models.MaintenanceOrderUpdateResponse{
+		OrderID: orderID,
+		ETag:    etag,
+	}
+
+	// Header fields and operations are patched in one changeset, so a failed operation
+	// leaves the order unchanged
+	updateReq, changed := sap.ConvertMaintenanceOrderEventToOrderUpdate(event)
+	if len(changed) == 0 {
+		updateReq = nil
+	}
+	ops := make([]sap.OperationUpdate, 0, len(event.Operations))
+	for i := range event.Operations {
+		op := &event.Operations[i]
+		ops = append(ops, sap.OperationUpdate{
+			OperationID: models.NormalizeOperationID(op.OperationID),
+			ETag:        op.ETag,
+			Request:     sap.ConvertMaintenanceOperationToUpdate(op),
+		})
+	}
+
+	newETag, operationETags, err := sapClient.UpdateOrderWithOperations(ctx, orderID, updateReq, ops, etag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update SAP order: %w", err)
+	}
+	response.ETag = newETag
+	response.OperationETags = operationETags
+	response.UpdatedFields = changed
+	for _, op := range ops {
+		response.UpdatedOperations = append(response.UpdatedOperations, op.OperationID)
+	}
+
+	response.Message = "Maintenance order updated successfully"
+	response.UpdatedAt = time.Now()
+
+	s.logger.WithFields(logrus.Fields{
+		"orderId":           orderID,
+		"etag":              response.ETag,
+		"updatedFields":     response.UpdatedFields,
+		"updatedOperations": response.UpdatedOperations,
+	}).Info("Maintenance order updated successfully")
+
+	return response, nil
+}
+
+// AddOrderOperation adds an operation to an existing maintenance order, continuing the SAP operation numbering
+func (s *MaintenanceService) AddOrderOperation(ctx context.Context, orderID string, op *models.MaintenanceOperation) (*models.OperationStatus, error) {
+	sapClient := s.clientForOrder(orderID)
+
+	s.logger.WithFields(logrus.Fields{
+		"orderId":    orderID,
+		"workCenter": op.WorkCenter,
+	}).Info("Adding operation to maintenance order")
+
+	orderResp, err := sapClient.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order from SAP: %w", err)
+	}
+
+	var existing []string
+	for _, current := range orderResp.D.ToMaintenanceOrderOperation.Results {
+		existing = append(existing, current.MaintenanceOrderOperation)
+	}
+
+	// The control key is determined and the fields are mapped as for the operations of a new order
+	event := s.orderEvent(ctx, sapClient, &orderResp.D, op)
+	s.rules[sapClient.Name()].Apply(event)
+	operationReq := sap.ConvertMaintenanceOperationToSAP(orderID, event, &event.Operations[0], existing, sapClient.FieldMapping())
+	for _, id := range existing {
+		if models.NormalizeOperationID(id) == operationReq.MaintenanceOrderOperation {
+			return nil, fmt.Errorf("operation %s: %w", id, ErrOperationExists)
+		}
+	}
+
+	operationResp, err := sapClient.CreateOrderOperation(ctx, operationReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SAP order operation: %w", err)
+	}
+
+	return sap.ConvertSAPOperationToStatus(operationResp), nil
+}
+
+// orderEvent describes an existing order as an event with one operation, so the determination
+// rules and the field mapping can be applied to an operation added to it. The equipment class and
+// main work center come from the equipment master; if it cannot be read they stay empty.
+func (s *MaintenanceService) orderEvent(ctx context.Context, sapClient *sap.Client, order *models.SAPOrder, op *models.MaintenanceOperation) *models.MaintenanceOrderEvent {
+	event := &models.MaintenanceOrderEvent{
+		EquipmentID:          order.Equipment,
+		FunctionalLocation:   order.FunctionalLocation,
+		Plant:                order.Plant,
+		Description:          order.Description,
+		Priority:             order.Priority,
+		MaintenanceOrderType: order.MaintenanceOrderType,
+		Operations:           []models.MaintenanceOperation{*op},
+	}
+	if order.Equipment == "" {
+		return event
+	}
+
+	equipment, err := s.masterData[sapClient.Name()].Equipment(ctx, order.Equipment)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"orderId":     order.MaintenanceOrder,
+			"equipmentId": order.Equipment,
+			"error":       err,
+		}).Warn("Failed to read equipment master data, applying rules without it")
+		return event
+	}
+	event.EquipmentClass = equipment.TechnicalObjectType
+	event.MainWorkCenter = equipment.MainWorkCenter
+	event.PlanningPlant = equipment.MaintenancePlanningPlant
+	return event
+}
+
+// UpdateOrderOperation changes an existing operation of a maintenance order and returns its new ETag
+func (s *MaintenanceService) UpdateOrderOperation(ctx context.Context, orderID, operationID string, op *models.MaintenanceOperation, etag string) (string, error) {
+	sapClient := s.clientForOrder(orderID)
+
+	s.logger.WithFields(logrus.Fields{
+		"orderId":     orderID,
+		"operationId": operationID,
+	}).Info("Updating maintenance order operation")
+
+	newETag, err := sapClient.UpdateOrderOperation(ctx, orderID, operationID, sap.ConvertMaintenanceOperationToUpdate(op), etag)
+	if err != nil {
+		return "", fmt.Errorf("failed to update SAP order operation: %w", err)
+	}
+
+	return newETag, nil
+}
+
+// DeleteOrderOperation removes an operation from a maintenance order
+func (s *MaintenanceService) DeleteOrderOperation(ctx context.Context, orderID, operationID, etag string) error {
+	sapClient := s.clientForOrder(orderID)
+
+	s.logger.WithFields(logrus.Fields{
+		"orderId":     orderID,
+		"operationId": operationID,
+	}).Info("Deleting maintenance order operation")
+
+	if err := sapClient.DeleteOrderOperation(ctx, orderID, operationID, etag); err != nil {
+		return fmt.Errorf("failed to delete SAP order operation: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOperationConfirmation records a technician time confirmation for an order operation in SAP
+func (s *MaintenanceService) CreateOperationConfirmation(ctx context.Context, orderID, operationID string, req *models.OperationConfirmationRequest) (*models.OperationConfirmationResponse, error) {
+	sapClient := s.clientForOrder(orderID)
+
+	s.logger.WithFields(logrus.Fields{
+		"orderId":     orderID,
+		"operationId": operationID,
+		"actualWork":  req.ActualWork,
+		"final":       req.FinalConfirmation,
+	}).Info("Creating operation confirmation")
+
+	confirmationReq := sap.ConvertConfirmationRequestToSAP(orderID, operationID, req)
+	confirmationResp, err := sapClient.CreateConfirmation(ctx, confirmationReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SAP confirmation: %w", err)
+	}
+
+	return sap.ConvertSAPConfirmationToResponse(&confirmationResp.D), nil
+}
+
+// CancelOperationConfirmation reverses a time confirmation previously posted for an order operation
+func (s *MaintenanceService) CancelOperationConfirmation(ctx context.Context, orderID, operationID, confirmationID, counter string) (*models.OperationConfirmationResponse, error) {
+	sapClient := s.clientForOrder(orderID)
+
+	s.logger.WithFields(logrus.Fields{
+		"orderId":        orderID,
+		"operationId":    operationID,
+		"confirmationId": confirmationID,
+		"counter":        counter,
+	}).Info("Cancelling operation confirmation")
+
+	// Only confirmations of the addressed order and operation may be reversed
+	confirmations, err := sapClient.GetOrderConfirmations(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order confirmations: %w", err)
+	}
+	found := false
+	for _, confirmation := range confirmations {
+		if confirmation.MaintOrderConf == confirmationID && confirmation.MaintOrderConfCntrValue == counter &&
+			models.NormalizeOperationID(confirmation.MaintenanceOrderOperation) == models.NormalizeOperationID(operationID) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: confirmation %s/%s of order %s operation %s", ErrConfirmationNotFound, confirmationID, counter, orderID, operationID)
+	}
+
+	confirmationResp, err := sapClient.CancelConfirmation(ctx, confirmationID, counter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel SAP confirmation: %w", err)
+	}
+
+	return sap.ConvertSAPConfirmationToResponse(&confirmationResp.D), nil
+}
+
 // MonitorOrderStatus monitors an order until completion (for background processing)
 func (s *MaintenanceService) MonitorOrderStatus(ctx context.Context, orderID string, callback func(*models.MaintenanceOrderStatus) error) error {
 	s.logger.WithField("orderId", orderID).Info("Starting order status monitoring")
 
+	// Cancelling the order stops the monitor
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	defer s.monitors.add(orderID, cancel)()
+
 	ticker := time.NewTicker(30 * time.Second) // Check every 30 seconds
 	defer ticker.Stop()
 
@@ -147,7 +744,7 @@ func (s *MaintenanceService) MonitorOrderStatus(ctx context.Context, orderID str
 		select {
 		case <-ctx.Done():
 			s.logger.WithField("orderId", orderID).Info("Order monitoring cancelled")
-			return ctx.Err()
+			return context.Cause(ctx)
 		case <-ticker.C:
 			status, err := s.GetMaintenanceOrderStatus(ctx, orderID)
 			if err != nil {
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestListMaintenanceOrdersAcrossDestinations(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	router := sap.NewRouter(
+		config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60},
+		[]config.SAPConfig{{Name: "acquired", SimulatorMode: true, MasterDataCacheTTL: 60, Plants: []string{"2000-9999"}}},
+		logger,
+	)
+	service := NewMaintenanceService(router, logger)
+
+	// Without plant every destination is searched and the merged result is sorted and paged
+	query := &models.MaintenanceOrderQuery{SortBy: "priority", SortOrder: "desc", Limit: 15}
+	list, err := service.ListMaintenanceOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if list.Count != 15 || list.NextCursor == "" {
+		t.Fatalf("Expected a full page with a next cursor, got %d orders", list.Count)
+	}
+	destinations := map[string]bool{}
+	for i, order := range list.Orders {
+		destinations[order.Destination] = true
+		if i > 0 && order.Priority > list.Orders[i-1].Priority {
+			t.Errorf("Expected descending priorities, got %s after %s", order.Priority, list.Orders[i-1].Priority)
+		}
+	}
+	if !destinations[sap.DefaultDestination] || !destinations["acquired"] {
+		t.Errorf("Expected orders of both destinations, got %v", destinations)
+	}
+
+	query.Cursor = list.NextCursor
+	next, err := service.ListMaintenanceOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if next.Count != 15 || next.Orders[0].Priority > list.Orders[14].Priority {
+		t.Errorf("Expected the next page to continue the order, got %+v", next.Orders[0])
+	}
+
+	// A plant filter only searches its destination
+	list, err = service.ListMaintenanceOrders(context.Background(), &models.MaintenanceOrderQuery{Plant: "2000"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if list.Count == 0 || list.Orders[0].Destination != "acquired" {
+		t.Errorf("Expected orders of the acquired destination, got %+v", list.Orders)
+	}
+}
+
+func TestCancelOperationConfirmationChecksOrderAndOperation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := NewMaintenanceService(sap.NewRouter(config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60}, nil, logger), logger)
+	ctx := context.Background()
+
+	created, err := service.CreateOperationConfirmation(ctx, "400000123", "0010", &models.OperationConfirmationRequest{ActualWork: 1.5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created.CreatedAt == nil {
+		t.Error("Expected the posting date as creation time")
+	}
+
+	// The confirmation is not reversed through another order or operation
+	if _, err := service.CancelOperationConfirmation(ctx, "400000124", "0010", created.ConfirmationID, created.ConfirmationCounter); !errors.Is(err, ErrConfirmationNotFound) {
+		t.Errorf("Expected ErrConfirmationNotFound for another order, got %v", err)
+	}
+	if _, err := service.CancelOperationConfirmation(ctx, "400000123", "0020", created.ConfirmationID, created.ConfirmationCounter); !errors.Is(err, ErrConfirmationNotFound) {
+		t.Errorf("Expected ErrConfirmationNotFound for another operation, got %v", err)
+	}
+
+	cancelled, err := service.CancelOperationConfirmation(ctx, "400000123", "10", created.ConfirmationID, created.ConfirmationCounter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !cancelled.Cancelled || cancelled.OrderID != "400000123" {
+		t.Errorf("Expected the confirmation to be reversed, got %+v", cancelled)
+	}
+}
+
+func TestListNotificationsPagesWithCursor(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := NewMaintenanceService(sap.NewRouter(config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60}, nil, logger), logger)
+
+	query := &models.MaintenanceNotificationQuery{EquipmentID: "10000045", Limit: 4}
+	seen := map[string]bool{}
+	for page := 0; ; page++ {
+		list, err := service.ListNotifications(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, notification := range list.Notifications {
+			if seen[notification.NotificationID] || notification.EquipmentID != "10000045" {
+				t.Errorf("Unexpected notification %+v on page %d", notification, page)
+			}
+			seen[notification.NotificationID] = true
+		}
+		if list.NextCursor == "" {
+			break
+		}
+		query.Cursor = list.NextCursor
+	}
+	if len(seen) != 10 {
+		t.Errorf("Expected all 10 simulator notifications across the pages, got %d", len(seen))
+	}
+}
+
+func TestFailedOrderCreationCompletesNotification(t *testing.T) {
+	var completed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/API_EQUIPMENT/Equipment":
+			var resp models.SAPEquipmentListResponse
+			resp.D.Results = []models.SAPEquipment{{Equipment: "10000045", MaintenancePlant: "1000", MaintenancePlanningPlant: "1000", ABCIndicator: "A"}}
+			json.NewEncoder(w).Encode(resp)
+		case r.Method == http.MethodPost && r.URL.Path == "/API_MAINTENANCE_NOTIFICATION/A_MaintenanceNotification":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"d":{"Notification":"200000777"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/API_MAINTENANCE_ORDER/A_MaintenanceOrder":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":{"value":"Order type not allowed"}}}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/API_MAINTENANCE_NOTIFICATION/A_MaintenanceNotification('200000777')":
+			w.Header().Set("ETag", `W/"2"`)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/API_MAINTENANCE_NOTIFICATION/CompleteMaintNotification":
+			completed = append(completed, r.URL.Query().Get("Notification"))
+			w.Write([]byte(`{"d":{}}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cfg := config.SAPConfig{BaseURL: server.URL, Timeout: 5, MasterDataCacheTTL: 60, SkipMetadataValidation: true}
+	service := NewMaintenanceService(sap.NewRouter(cfg, nil, logger), logger)
+
+	_, err := service.ProcessMaintenanceOrderEvent(context.Background(), &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "1000", Description: "Replace seal"})
+	if err == nil || !strings.Contains(err.Error(), "notification 200000777 was completed") {
+		t.Fatalf("Expected the order error with the completed notification, got %v", err)
+	}
+	if len(completed) != 1 || completed[0] != "'200000777'" {
+		t.Errorf("Expected the orphaned notification to be completed, got %v", completed)
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MasterDataError is returned when an event references master data that does not exist
+// or cannot be used. It lists every rejected field.
+type MasterDataError struct {
+	Errors []models.FieldError
+}
+
+// Error implements the error interface
+func (e *MasterDataError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+	return "master data validation failed: " + strings.Join(messages, "; ")
+}
+
+// MasterDataValidator checks events against SAP master data and fills in missing fields
+type MasterDataValidator struct {
+	sapClient           *sap.Client
+	equipment           *ttlCache[models.SAPEquipment]
+	workCenters         *ttlCache[models.SAPWorkCenter]
+	functionalLocations *ttlCache[models.SAPFunctionalLocation]
+	logger              *logrus.Logger
+}
+
+// NewMasterDataValidator creates a validator that caches lookups for the client's master data TTL
+func NewMasterDataValidator(sapClient *sap.Client, logger *logrus.Logger) *MasterDataValidator {
+	ttl := sapClient.MasterDataCacheTTL()
+	return &MasterDataValidator{
+		sapClient:           sapClient,
+		equipment:           newTTLCache[models.SAPEquipment](ttl),
+		workCenters:         newTTLCache[models.SAPWorkCenter](ttl),
+		functionalLocations: newTTLCache[models.SAPFunctionalLocation](ttl),
+		logger:              logger,
+	}
+}
+
+// Equipment returns the master data of an equipment, from the cache when possible
+func (v *MasterDataValidator) Equipment(ctx context.Context, equipmentID string) (*models.SAPEquipment, error) {
+	if equipment, ok := v.equipment.Get(equipmentID); ok {
+		return &equipment, nil
+	}
+
+	equipment, err := v.sapClient.GetEquipment(ctx, equipmentID)
+	if err != nil {
+		return nil, err
+	}
+	v.equipment.Set(equipmentID, *equipment)
+	return equipment, nil
+}
+
+// WorkCenter returns a work center of a plant, from the cache when possible
+func (v *MasterDataValidator) WorkCenter(ctx context.Context, plant, workCenter string) (*models.SAPWorkCenter, error) {
+	key := plant + "/" + workCenter
+	if wc, ok := v.workCenters.Get(key); ok {
+		return &wc, nil
+	}
+
+	wc, err := v.sapClient.GetWorkCenter(ctx, plant, workCenter)
+	if err != nil {
+		return nil, err
+	}
+	v.workCenters.Set(key, *wc)
+	return wc, nil
+}
+
+// FunctionalLocation returns a functional location, from the cache when possible
+func (v *MasterDataValidator) FunctionalLocation(ctx context.Context, functionalLocation string) (*models.SAPFunctionalLocation, error) {
+	if fl, ok := v.functionalLocations.Get(functionalLocation); ok {
+		return &fl, nil
+	}
+
+	fl, err := v.sapClient.GetFunctionalLocation(ctx, functionalLocation)
+	if err != nil {
+		return nil, err
+	}
+	v.functionalLocations.Set(functionalLocation, *fl)
+	return fl, nil
+}
+
+// ValidateEvent checks the equipment, functional location and work centers of an event.
+// Unknown or unusable master data is reported field by field in a MasterDataError. A valid
+// equipment fills in the functional location, planning plant, main work center and
+// criticality that the event leaves empty.
+func (v *MasterDataValidator) ValidateEvent(ctx context.Context, event *models.MaintenanceOrderEvent) error {
+	var fieldErrs []models.FieldError
+
+	// Functional location and main work center given by the event are checked; values
+	// taken from the equipment master are trusted
+	if event.FunctionalLocation != "" {
+		fieldErr, err := v.validateFunctionalLocation(ctx, event.FunctionalLocation)
+		if err != nil {
+			return err
+		}
+		if fieldErr != nil {
+			fieldErrs = append(fieldErrs, *fieldErr)
+		}
+	}
+
+	checked := make(map[string]*models.FieldError)
+	checkWorkCenter := func(field, workCenter string) error {
+		if workCenter == "" {
+			return nil
+		}
+		fieldErr, seen := checked[workCenter]
+		if !seen {
+			var err error
+			if fieldErr, err = v.validateWorkCenter(ctx, event.Plant, workCenter); err != nil {
+				return err
+			}
+			checked[workCenter] = fieldErr
+		}
+		if fieldErr != nil {
+			fieldErrs = append(fieldErrs, models.FieldError{Field: field, Value: fieldErr.Value, Message: fieldErr.Message})
+		}
+		return nil
+	}
+	if err := checkWorkCenter("mainWorkCenter", event.MainWorkCenter); err != nil {
+		return err
+	}
+	for i, op := range event.Operations {
+		if err := checkWorkCenter(fmt.Sprintf("operations[%d].workCenter", i), op.WorkCenter); err != nil {
+			return err
+		}
+	}
+
+	equipmentErr, err := v.validateEquipment(ctx, event)
+	if err != nil {
+		return err
+	}
+	if equipmentErr != nil {
+		fieldErrs = append([]models.FieldError{*equipmentErr}, fieldErrs...)
+	}
+
+	if len(fieldErrs) > 0 {
+		return &MasterDataError{Errors: fieldErrs}
+	}
+	return nil
+}
+
+// validateEquipment rejects unknown or inactive equipment and enriches the event from its master data
+func (v *MasterDataValidator) validateEquipment(ctx context.Context, event *models.MaintenanceOrderEvent) (*models.FieldError, error) {
+	equipment, err := v.Equipment(ctx, event.EquipmentID)
+	if err != nil {
+		if sap.IsNotFound(err) {
+			return &models.FieldError{
+				Field:   "equipmentId",
+				Value:   event.EquipmentID,
+				Message: "equipment does not exist in SAP",
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to look up equipment %s: %w", event.EquipmentID, err)
+	}
+
+	if equipment.EquipmentIsInactive || equipment.EquipmentIsMarkedForDeletion {
+		return &models.FieldError{
+			Field:   "equipmentId",
+			Value:   event.EquipmentID,
+			Message: "equipment is inactive or marked for deletion",
+		}, nil
+	}
+
+	if event.FunctionalLocation == "" {
+		event.FunctionalLocation = equipment.FunctionalLocation
+	}
+	if event.PlanningPlant == "" {
+		event.PlanningPlant = equipment.MaintenancePlanningPlant
+	}
+	if event.MainWorkCenter == "" {
+		event.MainWorkCenter = equipment.MainWorkCenter
+	}
+	if event.Criticality == "" {
+		event.Criticality = equipment.ABCIndicator
+	}
+	if event.EquipmentClass == "" {
+		event.EquipmentClass = equipment.TechnicalObjectType
+	}
+
+	v.logger.WithFields(logrus.Fields{
+		"equipmentId":        event.EquipmentID,
+		"functionalLocation": event.FunctionalLocation,
+		"planningPlant":      event.PlanningPlant,
+		"mainWorkCenter":     event.MainWorkCenter,
+		"criticality":        event.Criticality,
+		"equipmentClass":     event.EquipmentClass,
+	}).Info("Equipment master data validated")
+
+	return nil, nil
+}
+
+// validateWorkCenter checks that a work center exists in the plant and is not locked
+func (v *MasterDataValidator) validateWorkCenter(ctx context.Context, plant, workCenter string) (*models.FieldError, error) {
+	wc, err := v.WorkCenter(ctx, plant, workCenter)
+	if err != nil {
+		if sap.IsNotFound(err) {
+			return &models.FieldError{
+				Value:   workCenter,
+				Message: fmt.Sprintf("work center does not exist in plant %s", plant),
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to look up work center %s: %w", workCenter, err)
+	}
+
+	if wc.WorkCenterIsLocked {
+		return &models.FieldError{Value: workCenter, Message: "work center is locked"}, nil
+	}
+	return nil, nil
+}
+
+// validateFunctionalLocation checks that a functional location exists and is not deleted
+func (v *MasterDataValidator) validateFunctionalLocation(ctx context.Context, functionalLocation string) (*models.FieldError, error) {
+	fl, err := v.FunctionalLocation(ctx, functionalLocation)
+	if err != nil {
+		if sap.IsNotFound(err) {
+			return &models.FieldError{
+				Field:   "functionalLocation",
+				Value:   functionalLocation,
+				Message: "functional location does not exist in SAP",
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to look up functional location %s: %w", functionalLocation, err)
+	}
+
+	if fl.FunctionalLocationIsMarkedForDeletion {
+		return &models.FieldError{
+			Field:   "functionalLocation",
+			Value:   functionalLocation,
+			Message: "functional location is marked for deletion",
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestMasterDataValidator() *MasterDataValidator {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return NewMasterDataValidator(sap.NewClient(config.SAPConfig{SimulatorMode: true, MasterDataCacheTTL: 60}, logger), logger)
+}
+
+func TestValidateEventEnrichesFromEquipment(t *testing.T) {
+	validator := newTestMasterDataValidator()
+	event := &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "1000", FunctionalLocation: "FL-OWN"}
+
+	if err := validator.ValidateEvent(context.Background(), event); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.FunctionalLocation != "FL-OWN" {
+		t.Errorf("Expected functional location from event to be kept, got %s", event.FunctionalLocation)
+	}
+	if event.PlanningPlant != "1000" || event.MainWorkCenter != "MECH-01" || event.Criticality != "A" {
+		t.Errorf("Expected enrichment from equipment master data, got %+v", event)
+	}
+}
+
+func TestValidateEventRejectsEquipment(t *testing.T) {
+	validator := newTestMasterDataValidator()
+
+	for _, equipmentID := range []string{"90000001", "10000099"} {
+		err := validator.ValidateEvent(context.Background(), &models.MaintenanceOrderEvent{EquipmentID: equipmentID, Plant: "1000"})
+
+		var masterDataErr *MasterDataError
+		if !errors.As(err, &masterDataErr) {
+			t.Fatalf("Expected MasterDataError for %s, got %v", equipmentID, err)
+		}
+		if masterDataErr.Errors[0].Field != "equipmentId" {
+			t.Errorf("Expected equipmentId field error, got %+v", masterDataErr.Errors)
+		}
+	}
+}
+
+func TestValidateEventReportsFieldErrors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	sapClient := sap.NewClient(config.SAPConfig{SimulatorMode: true, ReferenceDataFile: "../../reference-data.yaml"}, logger)
+	validator := NewMasterDataValidator(sapClient, logger)
+
+	event := &models.MaintenanceOrderEvent{
+		EquipmentID:        "10000045",
+		Plant:              "1000",
+		FunctionalLocation: "FL100-900-000",
+		Operations: []models.MaintenanceOperation{
+			{Text: "Inspect", WorkCenter: "PUMP-WC01"},
+			{Text: "Repair", WorkCenter: "OLD-WC01"},
+			{Text: "Test", WorkCenter: "NOPE-WC"},
+		},
+	}
+
+	err := validator.ValidateEvent(context.Background(), event)
+
+	var masterDataErr *MasterDataError
+	if !errors.As(err, &masterDataErr) {
+		t.Fatalf("Expected MasterDataError, got %v", err)
+	}
+	fields := map[string]bool{}
+	for _, fieldErr := range masterDataErr.Errors {
+		fields[fieldErr.Field] = true
+	}
+	for _, field := range []string{"functionalLocation", "operations[1].workCenter", "operations[2].workCenter"} {
+		if !fields[field] {
+			t.Errorf("Expected field error for %s, got %+v", field, masterDataErr.Errors)
+		}
+	}
+	if len(masterDataErr.Errors) != 3 {
+		t.Errorf("Expected 3 field errors, got %+v", masterDataErr.Errors)
+	}
+}
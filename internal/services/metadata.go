@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MetadataMonitor checks that the fields sent to each SAP destination exist in its $metadata
+// and keeps the result for the health endpoint
+type MetadataMonitor struct {
+	destinations *sap.Router
+	logger       *logrus.Logger
+
+	mu       sync.RWMutex
+	statuses map[string]models.SAPMetadataStatus
+}
+
+// NewMetadataMonitor creates a monitor with all destinations pending
+func NewMetadataMonitor(destinations *sap.Router, logger *logrus.Logger) *MetadataMonitor {
+	statuses := make(map[string]models.SAPMetadataStatus)
+	for _, client := range destinations.Clients() {
+		statuses[client.Name()] = models.SAPMetadataStatus{Destination: client.Name(), Status: "pending"}
+	}
+
+	return &MetadataMonitor{
+		destinations: destinations,
+		logger:       logger,
+		statuses:     statuses,
+	}
+}
+
+// Check reads the $metadata of every destination and records the drift
+func (m *MetadataMonitor) Check(ctx context.Context) {
+	for _, client := range m.destinations.Clients() {
+		now := time.Now()
+		status := models.SAPMetadataStatus{Destination: client.Name(), CheckedAt: &now}
+
+		if !client.MetadataValidationEnabled() {
+			status.Status = "skipped"
+			m.setStatus(status)
+			continue
+		}
+
+		drift, err := client.CheckMetadata(ctx)
+		switch {
+		case err != nil:
+			status.Status = "unavailable"
+			status.Error = err.Error()
+			m.logger.WithError(err).WithField("destination", client.Name()).Error("Failed to check SAP $metadata")
+		case len(drift) > 0:
+			status.Status = "drift"
+			status.Drift = drift
+			for _, d := range drift {
+				m.logger.WithFields(logrus.Fields{
+					"destination": client.Name(),
+					"service":     d.Service,
+					"entitySet":   d.EntitySet,
+					"field":       d.Field,
+				}).Warn("SAP $metadata drift: " + d.Message)
+			}
+		default:
+			status.Status = "ok"
+		}
+		m.setStatus(status)
+	}
+}
+
+// Statuses returns the check result of every destination, default destination first
+func (m *MetadataMonitor) Statuses() []models.SAPMetadataStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]models.SAPMetadataStatus, 0, len(m.statuses))
+	for _, client := range m.destinations.Clients() {
+		statuses = append(statuses, m.statuses[client.Name()])
+	}
+	return statuses
+}
+
+// setStatus records the check result of a destination
+func (m *MetadataMonitor) setStatus(status models.SAPMetadataStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statuses[status.Destination] = status
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+)
+
+// defaultCriticality is the matrix column for equipment without a listed ABC indicator
+const defaultCriticality = "default"
+
+// PriorityCalculator derives the SAP priority of an event from its severity or risk score and
+// the ABC criticality of the equipment, and fills missing planned dates from the SLA of the priority
+type PriorityCalculator struct {
+	cfg config.PriorityConfig
+}
+
+// NewPriorityCalculator creates a priority calculator
+func NewPriorityCalculator(cfg config.PriorityConfig) *PriorityCalculator {
+	levels := append([]config.RiskScoreLevel(nil), cfg.RiskScoreLevels...)
+	sort.SliceStable(levels, func(i, j int) bool { return levels[i].MinScore > levels[j].MinScore })
+	cfg.RiskScoreLevels = levels
+	return &PriorityCalculator{cfg: cfg}
+}
+
+// Severity returns the severity of an event: the severity it was sent with, or the level of its risk score
+func (p *PriorityCalculator) Severity(event *models.MaintenanceOrderEvent) string {
+	if event.Severity != "" {
+		return event.Severity
+	}
+	if event.RiskScore == nil {
+		return ""
+	}
+	for _, level := range p.cfg.RiskScoreLevels {
+		if *event.RiskScore >= level.MinScore {
+			return level.Severity
+		}
+	}
+	return ""
+}
+
+// Priority looks up the priority for the severity of an event and the criticality of its equipment.
+// It returns false if the event has no severity or the matrix of its plant has no entry for it.
+func (p *PriorityCalculator) Priority(event *models.MaintenanceOrderEvent) (string, bool) {
+	severity := p.Severity(event)
+	if severity == "" {
+		return "", false
+	}
+	matrix := p.matrix(event.Plant)
+	if matrix == nil {
+		return "", false
+	}
+	row, ok := lookupFold(matrix, severity)
+	if !ok {
+		return "", false
+	}
+	if priority, ok := lookupFold(row, event.Criticality); ok && event.Criticality != "" {
+		return priority, true
+	}
+	return lookupFold(row, defaultCriticality)
+}
+
+// Apply sets the derived priority and the SLA dates on the event. It returns the severity used,
+// empty if the priority of the event was kept.
+func (p *PriorityCalculator) Apply(event *models.MaintenanceOrderEvent, now time.Time) string {
+	severity := ""
+	if priority, ok := p.Priority(event); ok {
+		event.Priority = priority
+		severity = p.Severity(event)
+	}
+	p.applySLA(event, now)
+	return severity
+}
+
+// applySLA fills a missing planned start and end from the SLA of the event priority
+func (p *PriorityCalculator) applySLA(event *models.MaintenanceOrderEvent, now time.Time) {
+	if event.PlannedStartTime != nil && event.PlannedEndTime != nil {
+		return
+	}
+	for _, sla := range p.cfg.SLAs {
+		if sla.Priority != event.Priority {
+			continue
+		}
+		if event.PlannedStartTime == nil {
+			start := now.Add(time.Duration(sla.StartWithinHours) * time.Hour)
+			event.PlannedStartTime = &start
+		}
+		if event.PlannedEndTime == nil {
+			end := event.PlannedStartTime.Add(time.Duration(sla.EndWithinHours-sla.StartWithinHours) * time.Hour)
+			if end.Before(*event.PlannedStartTime) {
+				end = *event.PlannedStartTime
+			}
+			event.PlannedEndTime = &end
+		}
+		return
+	}
+}
+
+// matrix returns the matrix of a plant; a matrix without plants applies to all other plants
+func (p *PriorityCalculator) matrix(plant string) map[string]map[string]string {
+	var fallback map[string]map[string]string
+	for _, m := range p.cfg.Matrices {
+		if len(m.Plants) == 0 {
+			if fallback == nil {
+				fallback = m.Matrix
+			}
+			continue
+		}
+		if sap.MatchesPlant(m.Plants, plant) {
+			return m.Matrix
+		}
+	}
+	return fallback
+}
+
+// lookupFold finds a map entry ignoring case. Viper lower-cases map keys from config files.
+func lookupFold[V any](m map[string]V, key string) (V, bool) {
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+	for k, value := range m {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
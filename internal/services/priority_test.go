@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+)
+
+func newTestPriorityCalculator() *PriorityCalculator {
+	return NewPriorityCalculator(config.PriorityConfig{
+		RiskScoreLevels: []config.RiskScoreLevel{
+			{MinScore: 30, Severity: "medium"},
+			{MinScore: 80, Severity: "critical"},
+			{MinScore: 0, Severity: "low"},
+		},
+		Matrices: []config.PriorityMatrix{
+			{
+				Plants: []string{"2000-2999"},
+				Matrix: map[string]map[string]string{"critical": {"default": "2"}},
+			},
+			{
+				// Keys as viper delivers them: lower-cased
+				Matrix: map[string]map[string]string{
+					"critical": {"a": "1", "b": "1", "default": "2"},
+					"medium":   {"a": "2", "default": "3"},
+					"low":      {"default": "4"},
+				},
+			},
+		},
+		SLAs: []config.PrioritySLA{
+			{Priority: "1", StartWithinHours: 2, EndWithinHours: 24},
+			{Priority: "3", StartWithinHours: 48, EndWithinHours: 168},
+		},
+	})
+}
+
+func TestPriorityFromSeverityAndCriticality(t *testing.T) {
+	calculator := newTestPriorityCalculator()
+	score := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		event    models.MaintenanceOrderEvent
+		expected string
+	}{
+		{"severity and A", models.MaintenanceOrderEvent{Plant: "1000", Severity: "Critical", Criticality: "A"}, "1"},
+		{"risk score and C", models.MaintenanceOrderEvent{Plant: "1000", RiskScore: score(85), Criticality: "C"}, "2"},
+		{"medium risk", models.MaintenanceOrderEvent{Plant: "1000", RiskScore: score(45), Criticality: "A"}, "2"},
+		{"plant matrix", models.MaintenanceOrderEvent{Plant: "2100", Severity: "critical", Criticality: "A"}, "2"},
+		{"no severity keeps priority", models.MaintenanceOrderEvent{Plant: "1000", Priority: "urgent"}, "urgent"},
+		{"unknown severity keeps priority", models.MaintenanceOrderEvent{Plant: "2100", Severity: "low", Priority: "3"}, "3"},
+	}
+	for _, tt := range tests {
+		calculator.Apply(&tt.event, time.Now())
+		if tt.event.Priority != tt.expected {
+			t.Errorf("%s: expected priority %s, got %s", tt.name, tt.expected, tt.event.Priority)
+		}
+	}
+}
+
+func TestPrioritySLADates(t *testing.T) {
+	calculator := newTestPriorityCalculator()
+	now := time.Date(2025, 8, 21, 8, 0, 0, 0, time.UTC)
+
+	event := &models.MaintenanceOrderEvent{Plant: "1000", Severity: "critical", Criticality: "A"}
+	calculator.Apply(event, now)
+	if !event.PlannedStartTime.Equal(now.Add(2*time.Hour)) || !event.PlannedEndTime.Equal(now.Add(24*time.Hour)) {
+		t.Errorf("Expected SLA window of priority 1, got %v - %v", event.PlannedStartTime, event.PlannedEndTime)
+	}
+
+	start := now.Add(72 * time.Hour)
+	event = &models.MaintenanceOrderEvent{Plant: "1000", Priority: "3", PlannedStartTime: &start}
+	calculator.Apply(event, now)
+	if !event.PlannedStartTime.Equal(start) || !event.PlannedEndTime.Equal(start.Add(120*time.Hour)) {
+		t.Errorf("Expected planned start to be kept and end from SLA, got %v - %v", event.PlannedStartTime, event.PlannedEndTime)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"path"
+	"strings"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+)
+
+// Sources of a rule decision
+const (
+	DecisionFromEvent = "event"
+	DecisionFromRule  = "rule"
+	DecisionNone      = "none"
+)
+
+// RulesEngine determines order type, notification type and operation control keys from the
+// configured rules of an SAP destination. Values sent with the event are kept.
+type RulesEngine struct {
+	rules config.DeterminationRules
+}
+
+// NewRulesEngine creates a rules engine
+func NewRulesEngine(rules config.DeterminationRules) *RulesEngine {
+	return &RulesEngine{rules: rules}
+}
+
+// Evaluate returns the values the rules determine for an event without changing it
+func (e *RulesEngine) Evaluate(event *models.MaintenanceOrderEvent) *models.RuleEvaluation {
+	evaluation := &models.RuleEvaluation{
+		OrderType:        decide(event.MaintenanceOrderType, e.rules.OrderType, event, event.MainWorkCenter),
+		NotificationType: decide(event.NotificationType, e.rules.NotificationType, event, event.MainWorkCenter),
+	}
+	for i, op := range event.Operations {
+		workCenter := op.WorkCenter
+		if workCenter == "" {
+			workCenter = event.MainWorkCenter
+		}
+		evaluation.Operations = append(evaluation.Operations, models.OperationRuleDecision{
+			OperationID: models.OperationNumberForIndex(op.OperationID, i),
+			WorkCenter:  workCenter,
+			ControlKey:  decide(op.ControlKey, e.rules.ControlKey, event, workCenter),
+		})
+	}
+	return evaluation
+}
+
+// Apply sets the determined values on the event
+func (e *RulesEngine) Apply(event *models.MaintenanceOrderEvent) *models.RuleEvaluation {
+	evaluation := e.Evaluate(event)
+	event.MaintenanceOrderType = evaluation.OrderType.Value
+	event.NotificationType = evaluation.NotificationType.Value
+	for i := range event.Operations {
+		event.Operations[i].ControlKey = evaluation.Operations[i].ControlKey.Value
+	}
+	return evaluation
+}
+
+// decide keeps a value sent with the event, or returns the value of the first matching rule
+func decide(value string, rules []config.DeterminationRule, event *models.MaintenanceOrderEvent, workCenter string) models.RuleDecision {
+	if value != "" {
+		return models.RuleDecision{Value: value, Source: DecisionFromEvent}
+	}
+	for i, rule := range rules {
+		if matchesCondition(rule.Category, event.Category) &&
+			matchesCondition(rule.Plant, event.Plant) &&
+			matchesCondition(rule.EquipmentClass, event.EquipmentClass) &&
+			matchesCondition(rule.WorkCenter, workCenter) {
+			return models.RuleDecision{Value: rule.Value, Source: DecisionFromRule, Rule: i + 1}
+		}
+	}
+	return models.RuleDecision{Source: DecisionNone}
+}
+
+// matchesCondition compares a value with a condition such as "1000|2000" or "EXT-*", ignoring case.
+// An empty condition matches every value.
+func matchesCondition(condition, value string) bool {
+	if condition == "" {
+		return true
+	}
+	for _, pattern := range strings.Split(condition, "|") {
+		if ok, err := path.Match(strings.ToUpper(strings.TrimSpace(pattern)), strings.ToUpper(value)); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRulesEngineDeterminesTypes(t *testing.T) {
+	engine := NewRulesEngine(config.DeterminationRules{
+		OrderType: []config.DeterminationRule{
+			{Category: "predictive", Value: "PM03"},
+			{Category: "preventive", Value: "PM02"},
+			{Value: "PM01"},
+		},
+		NotificationType: []config.DeterminationRule{
+			{Category: "predictive", EquipmentClass: "PUMP|MOTOR", Value: "M3"},
+			{Value: "M2"},
+		},
+		ControlKey: []config.DeterminationRule{
+			{Plant: "1*", WorkCenter: "ext-*", Value: "PM03"},
+			{Value: "PM01"},
+		},
+	})
+
+	event := &models.MaintenanceOrderEvent{
+		Plant:          "1000",
+		Category:       "Predictive",
+		EquipmentClass: "PUMP",
+		MainWorkCenter: "MECH-01",
+		Operations: []models.MaintenanceOperation{
+			{Text: "Inspect"},
+			{Text: "Alignment", WorkCenter: "EXT-01"},
+			{Text: "Test run", ControlKey: "PM02"},
+		},
+	}
+
+	evaluation := engine.Apply(event)
+	if evaluation.OrderType != (models.RuleDecision{Value: "PM03", Source: DecisionFromRule, Rule: 1}) {
+		t.Errorf("Unexpected order type decision %+v", evaluation.OrderType)
+	}
+	if event.MaintenanceOrderType != "PM03" || event.NotificationType != "M3" {
+		t.Errorf("Expected PM03/M3 on the event, got %s/%s", event.MaintenanceOrderType, event.NotificationType)
+	}
+
+	expected := []models.RuleDecision{
+		{Value: "PM01", Source: DecisionFromRule, Rule: 2},
+		{Value: "PM03", Source: DecisionFromRule, Rule: 1},
+		{Value: "PM02", Source: DecisionFromEvent},
+	}
+	for i, op := range evaluation.Operations {
+		if op.ControlKey != expected[i] {
+			t.Errorf("Operation %s: expected %+v, got %+v", op.OperationID, expected[i], op.ControlKey)
+		}
+	}
+
+	// Without rules the event is left as it is
+	empty := NewRulesEngine(config.DeterminationRules{}).Evaluate(&models.MaintenanceOrderEvent{})
+	if empty.OrderType.Source != DecisionNone || empty.OrderType.Value != "" {
+		t.Errorf("Expected no decision without rules, got %+v", empty.OrderType)
+	}
+}
+
+func TestAddedOperationUsesDeterminationRules(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cfg := config.SAPConfig{
+		SimulatorMode:      true,
+		MasterDataCacheTTL: 60,
+		Rules: config.DeterminationRules{ControlKey: []config.DeterminationRule{
+			{EquipmentClass: "PUMP", WorkCenter: "EXT-*", Value: "PM03"},
+			{Value: "PM01"},
+		}},
+	}
+	service := NewMaintenanceService(sap.NewRouter(cfg, nil, logger), logger)
+
+	// The equipment class of the order comes from the equipment master
+	operation, err := service.AddOrderOperation(context.Background(), "400000120", &models.MaintenanceOperation{Text: "Alignment", WorkCenter: "EXT-01"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if operation.ControlKey != "PM03" || operation.WorkCenter != "EXT-01" {
+		t.Errorf("Expected control key PM03 from the rules, got %+v", operation)
+	}
+
+	// A control key sent with the operation is kept, the work center defaults to the main work center
+	operation, err = service.AddOrderOperation(context.Background(), "400000120", &models.MaintenanceOperation{OperationID: "0050", Text: "Test run", ControlKey: "PM02"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if operation.ControlKey != "PM02" || operation.WorkCenter != "MECH-01" {
+		t.Errorf("Expected control key PM02 on MECH-01, got %+v", operation)
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrTemplateNotFound is returned for an unknown template ID
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrTemplateExists is returned when a failure mode and equipment class already have a template
+	ErrTemplateExists = errors.New("a template for this failure mode and equipment class already exists")
+)
+
+// TemplateRegistry keeps the task list templates, keyed by failure mode and equipment class
+type TemplateRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]*models.OrderTemplate
+	nextID    int
+}
+
+// NewTemplateRegistry creates an empty template registry
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{templates: make(map[string]*models.OrderTemplate)}
+}
+
+// List returns copies of all templates, ordered by ID
+func (r *TemplateRegistry) List() []models.OrderTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	templates := make([]models.OrderTemplate, 0, len(r.templates))
+	for _, template := range r.templates {
+		templates = append(templates, *template)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates
+}
+
+// Get returns a copy of a template
+func (r *TemplateRegistry) Get(id string) (*models.OrderTemplate, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *template
+	return &copied, true
+}
+
+// Create stores a new template and assigns its ID
+func (r *TemplateRegistry) Create(template *models.OrderTemplate) (*models.OrderTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.findLocked(template.FailureMode, template.EquipmentClass, "") != nil {
+		return nil, ErrTemplateExists
+	}
+
+	r.nextID++
+	now := time.Now()
+	stored := *template
+	stored.ID = fmt.Sprintf("TPL-%04d", r.nextID)
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	r.templates[stored.ID] = &stored
+
+	copied := stored
+	return &copied, nil
+}
+
+// Update replaces a template
+func (r *TemplateRegistry) Update(id string, template *models.OrderTemplate) (*models.OrderTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.templates[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	if r.findLocked(template.FailureMode, template.EquipmentClass, id) != nil {
+		return nil, ErrTemplateExists
+	}
+
+	stored := *template
+	stored.ID = id
+	stored.CreatedAt = existing.CreatedAt
+	stored.UpdatedAt = time.Now()
+	r.templates[id] = &stored
+
+	copied := stored
+	return &copied, nil
+}
+
+// Delete removes a template
+func (r *TemplateRegistry) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[id]; !ok {
+		return ErrTemplateNotFound
+	}
+	delete(r.templates, id)
+	return nil
+}
+
+// Resolve returns the template an event references: the template with its templateId, or the
+// template of its failure mode, preferring one for its equipment class over one for every class.
+// It returns nil if the event references no template.
+func (r *TemplateRegistry) Resolve(event *models.MaintenanceOrderEvent) (*models.OrderTemplate, error) {
+	if event.TemplateID != "" {
+		template, ok := r.Get(event.TemplateID)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, event.TemplateID)
+		}
+		return template, nil
+	}
+	if event.FailureMode == "" {
+		return nil, nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	template := r.findLocked(event.FailureMode, event.EquipmentClass, "")
+	if template == nil && event.EquipmentClass != "" {
+		template = r.findLocked(event.FailureMode, "", "")
+	}
+	if template == nil {
+		return nil, nil
+	}
+	copied := *template
+	return &copied, nil
+}
+
+// findLocked finds the template of a failure mode and equipment class, ignoring case
+func (r *TemplateRegistry) findLocked(failureMode, equipmentClass, excludeID string) *models.OrderTemplate {
+	for id, template := range r.templates {
+		if id != excludeID &&
+			strings.EqualFold(template.FailureMode, failureMode) &&
+			strings.EqualFold(template.EquipmentClass, equipmentClass) {
+			return template
+		}
+	}
+	return nil
+}
+
+// ApplyTemplate fills an event without operations from a template: its operations and components,
+// or its SAP task list. An order type sent with the event is kept. It reports whether the event changed.
+func ApplyTemplate(event *models.MaintenanceOrderEvent, template *models.OrderTemplate) bool {
+	if len(event.Operations) > 0 || event.TaskList != nil {
+		return false
+	}
+
+	if template.TaskList != nil {
+		taskList := *template.TaskList
+		event.TaskList = &taskList
+	} else {
+		event.Operations = append([]models.MaintenanceOperation(nil), template.Operations...)
+		if len(event.Components) == 0 {
+			event.Components = append([]models.MaintenanceComponent(nil), template.Components...)
+		}
+	}
+	if event.MaintenanceOrderType == "" {
+		event.MaintenanceOrderType = template.MaintenanceOrderType
+	}
+	event.TemplateID = template.ID
+	return true
+}
+
+// ListTemplates returns all task list templates
+func (s *MaintenanceService) ListTemplates() []models.OrderTemplate {
+	return s.templates.List()
+}
+
+// GetTemplate returns a task list template
+func (s *MaintenanceService) GetTemplate(id string) (*models.OrderTemplate, error) {
+	template, ok := s.templates.Get(id)
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// CreateTemplate adds a task list template
+func (s *MaintenanceService) CreateTemplate(template *models.OrderTemplate) (*models.OrderTemplate, error) {
+	created, err := s.templates.Create(template)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithField("templateId", created.ID).Info("Task list template created")
+	return created, nil
+}
+
+// UpdateTemplate replaces a task list template
+func (s *MaintenanceService) UpdateTemplate(id string, template *models.OrderTemplate) (*models.OrderTemplate, error) {
+	updated, err := s.templates.Update(id, template)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithField("templateId", id).Info("Task list template updated")
+	return updated, nil
+}
+
+// DeleteTemplate removes a task list template
+func (s *MaintenanceService) DeleteTemplate(id string) error {
+	if err := s.templates.Delete(id); err != nil {
+		return err
+	}
+	s.logger.WithField("templateId", id).Info("Task list template deleted")
+	return nil
+}
+
+// applyTemplate applies the template an event references after its master data was enriched, as
+// the equipment class comes from the equipment. Work centers of the template are then validated too.
+func (s *MaintenanceService) applyTemplate(ctx context.Context, sapClient *sap.Client, event *models.MaintenanceOrderEvent) error {
+	template, err := s.templates.Resolve(event)
+	if err != nil || template == nil {
+		return err
+	}
+	if !ApplyTemplate(event, template) {
+		s.logger.WithField("templateId", template.ID).Info("Event has its own operations, task list template not applied")
+		return nil
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"templateId":     template.ID,
+		"failureMode":    template.FailureMode,
+		"equipmentClass": template.EquipmentClass,
+		"taskList":       template.TaskList != nil,
+	}).Info("Task list template applied")
+	return s.masterData[sapClient.Name()].ValidateEvent(ctx, event)
+}
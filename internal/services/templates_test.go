@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"sap-adaptor/internal/models"
+)
+
+func TestTemplateRegistryResolvesByFailureModeAndClass(t *testing.T) {
+	registry := NewTemplateRegistry()
+
+	generic, err := registry.Create(&models.OrderTemplate{
+		FailureMode: "BEARING_WEAR",
+		Operations:  []models.MaintenanceOperation{{Text: "Replace bearing", WorkCenter: "MECH-01", Duration: 4}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pump, err := registry.Create(&models.OrderTemplate{
+		FailureMode:          "BEARING_WEAR",
+		EquipmentClass:       "PUMP",
+		MaintenanceOrderType: "PM03",
+		TaskList:             &models.TaskListReference{TaskListType: "A", TaskListGroup: "PUMP-BRG", TaskListGroupCounter: "01"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := registry.Create(&models.OrderTemplate{FailureMode: "bearing_wear", EquipmentClass: "pump"}); !errors.Is(err, ErrTemplateExists) {
+		t.Errorf("Expected ErrTemplateExists, got %v", err)
+	}
+
+	// The template of the equipment class wins over the generic one
+	event := &models.MaintenanceOrderEvent{FailureMode: "bearing_wear", EquipmentClass: "PUMP"}
+	template, err := registry.Resolve(event)
+	if err != nil || template == nil || template.ID != pump.ID {
+		t.Fatalf("Expected template %s, got %+v (%v)", pump.ID, template, err)
+	}
+	if !ApplyTemplate(event, template) {
+		t.Fatal("Expected the template to be applied")
+	}
+	if event.TaskList == nil || event.TaskList.TaskListGroup != "PUMP-BRG" || event.MaintenanceOrderType != "PM03" || len(event.Operations) != 0 {
+		t.Errorf("Expected the task list and order type of the template, got %+v", event)
+	}
+
+	event = &models.MaintenanceOrderEvent{FailureMode: "BEARING_WEAR", EquipmentClass: "MOTOR"}
+	template, _ = registry.Resolve(event)
+	if template == nil || template.ID != generic.ID {
+		t.Fatalf("Expected generic template %s, got %+v", generic.ID, template)
+	}
+	ApplyTemplate(event, template)
+	if len(event.Operations) != 1 || event.Operations[0].WorkCenter != "MECH-01" {
+		t.Errorf("Expected the template operations, got %+v", event.Operations)
+	}
+
+	// Operations sent with the event are kept
+	event = &models.MaintenanceOrderEvent{TemplateID: generic.ID, Operations: []models.MaintenanceOperation{{Text: "Own"}}}
+	template, _ = registry.Resolve(event)
+	if ApplyTemplate(event, template) || event.Operations[0].Text != "Own" {
+		t.Errorf("Expected the event operations to be kept, got %+v", event.Operations)
+	}
+
+	if _, err := registry.Resolve(&models.MaintenanceOrderEvent{TemplateID: "TPL-9999"}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
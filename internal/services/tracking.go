@@ -0,0 +1,223 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/models"
+)
+
+// OrderTracker is the local tracking store for orders created through the adaptor
+type OrderTracker struct {
+	mu     sync.RWMutex
+	orders map[string]*models.TrackedOrder
+}
+
+// NewOrderTracker creates an empty order tracker
+func NewOrderTracker() *OrderTracker {
+	return &OrderTracker{
+		orders: make(map[string]*models.TrackedOrder),
+	}
+}
+
+// Track records an order created from a Digital Twin event in the given SAP destination
+func (t *OrderTracker) Track(orderID, notificationID, destination, status string, event *models.MaintenanceOrderEvent) *models.TrackedOrder {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	order := &models.TrackedOrder{
+		OrderID:            orderID,
+		NotificationID:     notificationID,
+		EventIDs:           eventIDs(event),
+		Destination:        destination,
+		EquipmentID:        event.EquipmentID,
+		FunctionalLocation: event.FunctionalLocation,
+		Plant:              event.Plant,
+		Description:        event.Description,
+		Priority:           event.Priority,
+		OrderType:          event.MaintenanceOrderType,
+		Status:             status,
+		PlannedStartTime:   event.PlannedStartTime,
+		PlannedEndTime:     event.PlannedEndTime,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	t.orders[orderID] = order
+
+	copied := *order
+	return &copied
+}
+
+// Get returns a copy of a tracked order
+func (t *OrderTracker) Get(orderID string) (*models.TrackedOrder, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	order, ok := t.orders[orderID]
+	if !ok {
+		return nil, false
+	}
+	copied := *order
+	return &copied, true
+}
+
+// FindByNotification returns a copy of the tracked order created for a notification
+func (t *OrderTracker) FindByNotification(notificationID string) (*models.TrackedOrder, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, order := range t.orders {
+		if order.NotificationID == notificationID {
+			copied := *order
+			return &copied, true
+		}
+	}
+	return nil, false
+}
+
+// FindByEvent returns a copy of the tracked order created for a Digital Twin event
+func (t *OrderTracker) FindByEvent(eventID string) (*models.TrackedOrder, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, order := range t.orders {
+		for _, id := range order.EventIDs {
+			if id == eventID {
+				copied := *order
+				return &copied, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// eventIDs returns the ID of an event and of the events aggregated into it
+func eventIDs(event *models.MaintenanceOrderEvent) []string {
+	var ids []string
+	if event.EventID != "" {
+		ids = append(ids, event.EventID)
+	}
+	for _, id := range event.MergedEventIDs {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// UpdateStatus stores the latest known SAP status of a tracked order
+func (t *OrderTracker) UpdateStatus(orderID, status string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if order, ok := t.orders[orderID]; ok && order.Status != status {
+		order.Status = status
+		order.UpdatedAt = time.Now()
+	}
+}
+
+// UpdatePriority stores a changed priority of a tracked order
+func (t *OrderTracker) UpdatePriority(orderID, priority string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if order, ok := t.orders[orderID]; ok && order.Priority != priority {
+		order.Priority = priority
+		order.UpdatedAt = time.Now()
+	}
+}
+
+// List returns the tracked orders matching the query, starting at offset, and whether more match
+func (t *OrderTracker) List(query *models.MaintenanceOrderQuery, offset, limit int) ([]models.TrackedOrder, bool) {
+	t.mu.RLock()
+	var matches []models.TrackedOrder
+	for _, order := range t.orders {
+		if trackedOrderMatches(order, query) {
+			matches = append(matches, *order)
+		}
+	}
+	t.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if query.SortOrder == "desc" {
+			return trackedOrderLess(&matches[j], &matches[i], query.SortBy)
+		}
+		return trackedOrderLess(&matches[i], &matches[j], query.SortBy)
+	})
+
+	if offset >= len(matches) {
+		return nil, false
+	}
+	end := offset + limit
+	if end >= len(matches) {
+		return matches[offset:], false
+	}
+	return matches[offset:end], true
+}
+
+// trackedOrderMatches applies the search filters to a tracked order
+func trackedOrderMatches(order *models.TrackedOrder, query *models.MaintenanceOrderQuery) bool {
+	if (query.EquipmentID != "" && order.EquipmentID != query.EquipmentID) ||
+		(query.FunctionalLocation != "" && order.FunctionalLocation != query.FunctionalLocation) ||
+		(query.Plant != "" && order.Plant != query.Plant) ||
+		(query.Status != "" && order.Status != query.Status) ||
+		(query.OrderType != "" && order.OrderType != query.OrderType) ||
+		(query.Priority != "" && order.Priority != query.Priority) {
+		return false
+	}
+	return inRange(order.PlannedStartTime, query.PlannedStartFrom, query.PlannedStartTo) &&
+		inRange(order.PlannedEndTime, query.PlannedEndFrom, query.PlannedEndTo)
+}
+
+// inRange reports whether t lies within the optional [from, to] bounds
+func inRange(t, from, to *time.Time) bool {
+	if from == nil && to == nil {
+		return true
+	}
+	if t == nil {
+		return false
+	}
+	return (from == nil || !t.Before(*from)) && (to == nil || !t.After(*to))
+}
+
+// trackedOrderLess orders tracked orders by the given sort key (default: order ID)
+func trackedOrderLess(a, b *models.TrackedOrder, sortBy string) bool {
+	switch sortBy {
+	case "plannedStart":
+		return timeBefore(a.PlannedStartTime, b.PlannedStartTime)
+	case "plannedEnd":
+		return timeBefore(a.PlannedEndTime, b.PlannedEndTime)
+	case "priority":
+		return a.Priority < b.Priority
+	case "status":
+		return a.Status < b.Status
+	default:
+		return a.OrderID < b.OrderID
+	}
+}
+
+// timeBefore orders optional times, placing missing times last
+func timeBefore(a, b *time.Time) bool {
+	if a == nil {
+		return false
+	}
+	if b == nil {
+		return true
+	}
+	return a.Before(*b)
+}
+
+// TrackedOrderToStatus converts a tracked order to the status model returned by the API
+func TrackedOrderToStatus(order *models.TrackedOrder) models.MaintenanceOrderStatus {
+	return models.MaintenanceOrderStatus{
+		OrderID:        order.OrderID,
+		Status:         order.Status,
+		Description:    order.Description,
+		EquipmentID:    order.EquipmentID,
+		Plant:          order.Plant,
+		NotificationID: order.NotificationID,
+		Destination:    order.Destination,
+	}
+}
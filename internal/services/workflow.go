@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+)
+
+// Workflow modes: the SAP objects an event creates
+const (
+	WorkflowFull             = "full"              // Notification, then an order referencing it
+	WorkflowNotificationOnly = "notification-only" // Notification; SAP creates the order from it
+	WorkflowOrderOnly        = "order-only"        // Order without notification
+)
+
+// StatusAwaitingOrder is returned for a notification whose order SAP has not created yet
+const StatusAwaitingOrder = "AWAITING_ORDER"
+
+const (
+	defaultOrderWait = 60 * time.Minute
+	defaultOrderPoll = 30 * time.Second
+)
+
+// awaitingOrders holds the notification-only events whose order SAP has not created yet, by
+// Digital Twin event ID, so they can be withdrawn while waiting
+type awaitingOrders struct {
+	mu     sync.Mutex
+	events map[string]*awaitingOrder
+}
+
+// awaitingOrder is a notification waiting for its order and the function that stops the wait
+type awaitingOrder struct {
+	notificationID string
+	eventIDs       []string
+	sapClient      *sap.Client
+	stop           context.CancelCauseFunc
+}
+
+// add registers a wait under its event IDs and returns the function that removes it again
+func (w *awaitingOrders) add(waiting *awaitingOrder) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.events == nil {
+		w.events = make(map[string]*awaitingOrder)
+	}
+	for _, eventID := range waiting.eventIDs {
+		w.events[eventID] = waiting
+	}
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for _, eventID := range waiting.eventIDs {
+			if w.events[eventID] == waiting {
+				delete(w.events, eventID)
+			}
+		}
+	}
+}
+
+// find returns the wait of an event ID
+func (w *awaitingOrders) find(eventID string) (*awaitingOrder, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	waiting, ok := w.events[eventID]
+	return waiting, ok
+}
+
+// workflowMode returns the mode of an event: the mode it was sent with, the mode of its plant,
+// or the mode of the destination. Unknown modes fall back to full.
+func (s *MaintenanceService) workflowMode(sapClient *sap.Client, event *models.MaintenanceOrderEvent) string {
+	mode := event.WorkflowMode
+	settings := sapClient.Workflow()
+	for _, plantWorkflow := range settings.Plants {
+		if mode == "" && sap.MatchesPlant(plantWorkflow.Plants, event.Plant) {
+			mode = plantWorkflow.Mode
+		}
+	}
+	if mode == "" {
+		mode = settings.Mode
+	}
+
+	switch mode = strings.ToLower(mode); mode {
+	case WorkflowNotificationOnly, WorkflowOrderOnly:
+		return mode
+	default:
+		return WorkflowFull
+	}
+}
+
+// awaitOrder reads a notification in the background until SAP has attached an order to it, then
+// verifies and tracks the order like one created by the adaptor. It gives up after the configured
+// wait or when the notification is closed without an order.
+func (s *MaintenanceService) awaitOrder(sapClient *sap.Client, notificationID string, event *models.MaintenanceOrderEvent) {
+	settings := sapClient.Workflow()
+	wait, poll := defaultOrderWait, defaultOrderPoll
+	if settings.OrderWaitMinutes > 0 {
+		wait = time.Duration(settings.OrderWaitMinutes) * time.Minute
+	}
+	if settings.PollSeconds > 0 {
+		poll = time.Duration(settings.PollSeconds) * time.Second
+	}
+	tracked := *event
+	logger := s.logger.WithField("notificationId", notificationID)
+
+	waitCtx, stop := context.WithCancelCause(context.Background())
+	var eventIDs []string
+	if event.EventID != "" {
+		eventIDs = append(eventIDs, event.EventID)
+	}
+	eventIDs = append(eventIDs, event.MergedEventIDs...)
+	remove := s.awaiting.add(&awaitingOrder{
+		notificationID: notificationID,
+		eventIDs:       eventIDs,
+		sapClient:      sapClient,
+		stop:           stop,
+	})
+
+	go func() {
+		defer remove()
+		ctx, cancel := context.WithTimeout(waitCtx, wait)
+		defer cancel()
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+
+		logger.WithField("wait", wait).Info("Waiting for SAP to create the order of the notification")
+		for {
+			select {
+			case <-ctx.Done():
+				if errors.Is(context.Cause(ctx), ErrOrderCancelled) {
+					logger.Info("Event was withdrawn, stopped waiting for the order of the notification")
+					return
+				}
+				logger.Warn("No order was attached to the notification in time, stopped waiting")
+				return
+			case <-ticker.C:
+				notification, err := sapClient.GetNotification(ctx, notificationID)
+				if err != nil {
+					logger.WithError(err).Warn("Failed to read notification while waiting for its order")
+					continue
+				}
+				if notification.MaintenanceOrder == "" {
+					status := sap.ConvertSAPNotificationToStatus(notification).Status
+					if status == "COMPLETED" || status == "REJECTED" {
+						logger.WithField("status", status).Info("Notification closed without an order, stopped waiting")
+						return
+					}
+					continue
+				}
+
+				if _, err := s.trackOrder(ctx, sapClient, &tracked, notification.MaintenanceOrder, notificationID); err != nil {
+					logger.WithError(err).Error("Failed to track the order SAP created for the notification")
+					return
+				}
+				logger.WithField("orderId", notification.MaintenanceOrder).Info("Order attached by SAP is tracked")
+				return
+			}
+		}
+	}()
+}
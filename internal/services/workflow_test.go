@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"sap-adaptor/internal/config"
+	"sap-adaptor/internal/models"
+	"sap-adaptor/internal/sap"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWorkflowModes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cfg := config.SAPConfig{
+		SimulatorMode:      true,
+		MasterDataCacheTTL: 60,
+		Workflow: config.WorkflowConfig{
+			Mode: "full",
+			Plants: []config.PlantWorkflow{
+				{Plants: []string{"1000"}, Mode: "order-only"},
+				{Plants: []string{"2000-2999"}, Mode: "notification-only"},
+			},
+		},
+	}
+	service := NewMaintenanceService(sap.NewRouter(cfg, nil, logger), logger)
+	client := service.destinations.Default()
+
+	tests := []struct {
+		event    models.MaintenanceOrderEvent
+		expected string
+	}{
+		{models.MaintenanceOrderEvent{Plant: "1000"}, WorkflowOrderOnly},
+		{models.MaintenanceOrderEvent{Plant: "2500"}, WorkflowNotificationOnly},
+		{models.MaintenanceOrderEvent{Plant: "3000"}, WorkflowFull},
+		{models.MaintenanceOrderEvent{Plant: "1000", WorkflowMode: "full"}, WorkflowFull},
+	}
+	for _, tt := range tests {
+		if mode := service.workflowMode(client, &tt.event); mode != tt.expected {
+			t.Errorf("Plant %s, event mode %q: expected %s, got %s", tt.event.Plant, tt.event.WorkflowMode, tt.expected, mode)
+		}
+	}
+
+	// Order-only creates an order without notification and tracks it
+	event := &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "1000", Description: "Replace seal"}
+	response, err := service.ProcessMaintenanceOrderEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.OrderID == "" || response.NotificationID != "" || response.WorkflowMode != WorkflowOrderOnly {
+		t.Errorf("Expected an order without notification, got %+v", response)
+	}
+	if _, ok := service.tracker.Get(response.OrderID); !ok {
+		t.Errorf("Expected order %s to be tracked", response.OrderID)
+	}
+
+	// Notification-only returns before SAP has created the order
+	event = &models.MaintenanceOrderEvent{EquipmentID: "10000045", Plant: "1000", Description: "Replace seal", WorkflowMode: WorkflowNotificationOnly}
+	response, err = service.ProcessMaintenanceOrderEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.OrderID != "" || response.NotificationID == "" || response.Status != StatusAwaitingOrder {
+		t.Errorf("Expected a notification awaiting its order, got %+v", response)
+	}
+}